@@ -2,51 +2,116 @@
 package app
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"maps"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	cfg "github.com/iomarmochtar/gitlab-token-updater/pkg/config"
 	gl "github.com/iomarmochtar/gitlab-token-updater/pkg/gitlab"
+	"github.com/iomarmochtar/gitlab-token-updater/pkg/journal"
+	"github.com/iomarmochtar/gitlab-token-updater/pkg/k8s"
 	"github.com/iomarmochtar/gitlab-token-updater/pkg/shell"
+	"github.com/iomarmochtar/gitlab-token-updater/pkg/vault"
+	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 )
 
 const (
 	injectEnvVarShellExec = "GL_NEW_TOKEN"
 	dryRunDommyToken      = "glpat-abc"
+	// the injected env vars of the token context in exec_cmd hook
+	injectEnvVarTokenName      = "GL_TOKEN_NAME"
+	injectEnvVarTokenID        = "GL_TOKEN_ID"
+	injectEnvVarTokenPath      = "GL_TOKEN_PATH"
+	injectEnvVarTokenType      = "GL_TOKEN_TYPE"
+	injectEnvVarTokenExpiresAt = "GL_TOKEN_EXPIRES_AT"
+	injectEnvVarHost           = "GL_HOST"
+	injectEnvVarDryRun         = "GL_DRY_RUN"
+	injectEnvVarHookAttempt    = "GL_HOOK_ATTEMPT"
 )
 
 var (
 	ErrDuringExecution = errors.New("some error(s) occured during execution")
+	ErrInterrupted     = errors.New("execution interrupted")
 )
 
 type accessTokenPair struct {
 	glAccessToken  gl.GitlabAccessToken
 	cfgAccessToken cfg.AccessToken
+	// missing the access token is not exists in Gitlab and it's going to be created
+	missing bool
+	// recreate the missing one is replacing the expired or revoked access token in glAccessToken
+	recreate bool
+	// expiresAt the known expiry of the rotated token, it's set in recovering the kept one
+	expiresAt *time.Time
+	// doneHooks the sequence numbers of the succeeded hooks, they are skipped in resuming
+	doneHooks map[int]bool
+}
+
+// targetType gitlab target type of the managed token type
+func targetType(mType string) gl.GitlabTargetType {
+	switch mType {
+	case cfg.ManagedTypePersonal:
+		return gl.GitlabTargetTypePersonal
+	case cfg.ManagedTypeGroup:
+		return gl.GitlabTargetTypeGroup
+	}
+	return gl.GitlabTargetTypeRepo
+}
+
+// managedType managed token type of the gitlab target type
+func managedType(tType gl.GitlabTargetType) string {
+	switch tType {
+	case gl.GitlabTargetTypePersonal:
+		return cfg.ManagedTypePersonal
+	case gl.GitlabTargetTypeGroup:
+		return cfg.ManagedTypeGroup
+	}
+	return cfg.ManagedTypeRepository
 }
 
 // GitlabTokenUpdater hold required properties and main execution of gitlab-token-updater
 type GitlabTokenUpdater struct {
-	config     *cfg.Config
-	sh         shell.Shell
-	glAPI      gl.GitlabAPI
+	ctx    context.Context
+	config *cfg.Config
+	sh     shell.Shell
+	glAPI  gl.GitlabAPI
+	// listAPI the API of listing the access tokens, in concurrent processing it's cancelled by the strict failure.
+	// The rotation and hooks are using glAPI, so the new token of the in-flight rotation is not lost
+	listAPI    gl.GitlabAPI
+	k8sInit    K8sInitFunc
+	vaultInit  VaultInitFunc
+	journal    *journal.Journal
 	now        *time.Time
 	forceRenew bool
 	dryRun     bool
 	strict     bool
 	errors     []error
+	report     *Report
+	selector   *Selector
+	// concurrency the number of managed tokens that are processed in parallel
+	concurrency int
+	// errMu and storeMu guarding the collected errors and the read-modify-write of recovery store in concurrent processing
+	errMu   *sync.Mutex
+	storeMu *sync.Mutex
+	// aborted the concurrent processing is stopped by the failure in strict mode
+	aborted *atomic.Bool
 }
 
 func (g GitlabTokenUpdater) listAccessTokens(mg cfg.ManagedToken) (results []accessTokenPair, err error) {
 	var tokens []gl.GitlabAccessToken
 	switch mg.Type {
 	case cfg.ManagedTypeRepository:
-		tokens, err = g.glAPI.ListRepoAccessToken(mg.Path)
+		tokens, err = g.listAPI.ListRepoAccessToken(mg.Path)
 	case cfg.ManagedTypeGroup:
-		tokens, err = g.glAPI.ListGroupAccessToken(mg.Path)
+		tokens, err = g.listAPI.ListGroupAccessToken(mg.Path)
 	case cfg.ManagedTypePersonal:
-		tokens, err = g.glAPI.ListPersonalAccessToken()
+		tokens, err = g.listAPI.ListPersonalAccessToken()
 	}
 	if err != nil {
 		return nil, err
@@ -54,9 +119,13 @@ func (g GitlabTokenUpdater) listAccessTokens(mg cfg.ManagedToken) (results []acc
 
 	for _, token := range mg.Tokens {
 		isFound := false
+		var expired *gl.GitlabAccessToken
 		for _, scToken := range tokens {
-			// ignore the revoked or inactive
+			// ignore the revoked or inactive, the latest one is kept as the reference for recreating it
 			if scToken.Revoked || !scToken.Active {
+				if scToken.Name == token.Name && (expired == nil || scToken.ID > expired.ID) {
+					expired = &scToken
+				}
 				tkID := scToken.ID
 				log.Debug().
 					Str("token", scToken.Path).
@@ -77,7 +146,20 @@ func (g GitlabTokenUpdater) listAccessTokens(mg cfg.ManagedToken) (results []acc
 			}
 		}
 
-		if !isFound {
+		if !isFound && expired != nil && token.RecreateOnExpired() {
+			results = append(results, accessTokenPair{
+				glAccessToken:  *expired,
+				cfgAccessToken: token,
+				missing:        true,
+				recreate:       true,
+			})
+		} else if !isFound && token.CreateIfMissing {
+			results = append(results, accessTokenPair{
+				glAccessToken:  gl.GitlabAccessToken{Name: token.Name, Type: targetType(mg.Type), Path: mg.Path},
+				cfgAccessToken: token,
+				missing:        true,
+			})
+		} else if !isFound {
 			err = fmt.Errorf("token %s in %s is not exists", token.Name, mg.Path)
 			if g.strict {
 				return nil, err
@@ -90,6 +172,27 @@ func (g GitlabTokenUpdater) listAccessTokens(mg cfg.ManagedToken) (results []acc
 	return results, nil
 }
 
+// renewInfo calculate the date of access token should be renewed and whether it's already reach the time
+func (g GitlabTokenUpdater) renewInfo(at accessTokenPair) (renewAt *time.Time, validToRenew bool) {
+	expiresAt := at.glAccessToken.ExpiresAt
+	if expiresAt == nil {
+		return nil, false
+	}
+
+	befDur, _ := at.cfgAccessToken.RenewBeforeDuration()
+	renewDate := expiresAt.Add(-befDur)
+	return &renewDate, g.now.After(renewDate)
+}
+
+// nextExpiry expiry date of the access token after it's rotated
+func (g GitlabTokenUpdater) nextExpiry(tkn accessTokenPair) time.Time {
+	if tkn.expiresAt != nil {
+		return *tkn.expiresAt
+	}
+	nextExpiration, _ := tkn.cfgAccessToken.ExpiryAfterRotateDuration()
+	return g.now.Add(nextExpiration)
+}
+
 func (g GitlabTokenUpdater) processRenew(tkn accessTokenPair) (string, error) {
 	if g.dryRun {
 		return dryRunDommyToken, nil
@@ -97,8 +200,7 @@ func (g GitlabTokenUpdater) processRenew(tkn accessTokenPair) (string, error) {
 
 	path := tkn.glAccessToken.Path
 	id := tkn.glAccessToken.ID
-	nextExpiration, _ := tkn.cfgAccessToken.ExpiryAfterRotateDuration()
-	nextExpiry := g.now.Add(nextExpiration)
+	nextExpiry := g.nextExpiry(tkn)
 	if tkn.glAccessToken.Type == gl.GitlabTargetTypePersonal {
 		return g.glAPI.RotatePersonalToken(id, nextExpiry)
 	} else if tkn.glAccessToken.Type == gl.GitlabTargetTypeRepo {
@@ -108,57 +210,266 @@ func (g GitlabTokenUpdater) processRenew(tkn accessTokenPair) (string, error) {
 	return g.glAPI.RotateGroupToken(path, id, nextExpiry)
 }
 
-func (g GitlabTokenUpdater) execHook(hk cfg.Hook, newToken string) (err error) {
-	switch hk.Type {
-	case cfg.HookTypeUseToken:
+// createAttrs scopes and access level of the created access token, the ones of the expired token are used in recreating it
+func (tkn accessTokenPair) createAttrs() (scopes []string, accessLevel int) {
+	scopes = tkn.cfgAccessToken.Scopes
+	accessLevel = tkn.cfgAccessToken.AccessLevelValue()
+	if len(tkn.glAccessToken.Scopes) > 0 {
+		scopes = tkn.glAccessToken.Scopes
+	}
+	if tkn.glAccessToken.AccessLevel != 0 {
+		accessLevel = tkn.glAccessToken.AccessLevel
+	}
+	return scopes, accessLevel
+}
+
+// processCreate create the missing access token with the configured scopes and access level
+func (g GitlabTokenUpdater) processCreate(tkn accessTokenPair) (string, error) {
+	if g.dryRun {
+		return dryRunDommyToken, nil
+	}
+
+	path := tkn.glAccessToken.Path
+	name := tkn.cfgAccessToken.Name
+	scopes, accessLevel := tkn.createAttrs()
+	nextExpiry := g.nextExpiry(tkn)
+	if tkn.glAccessToken.Type == gl.GitlabTargetTypePersonal {
+		return g.glAPI.CreatePersonalToken(name, scopes, nextExpiry)
+	} else if tkn.glAccessToken.Type == gl.GitlabTargetTypeRepo {
+		return g.glAPI.CreateRepoToken(path, name, scopes, accessLevel, nextExpiry)
+	}
+
+	return g.glAPI.CreateGroupToken(path, name, scopes, accessLevel, nextExpiry)
+}
+
+// updateVarAttrs the managed attributes of CICD variable in update_var hook
+func updateVarAttrs(args cfg.HookUpdateVar) gl.GitlabCICDVarAttrs {
+	attrs := gl.GitlabCICDVarAttrs{
+		Masked:    args.Masked,
+		Protected: args.Protected,
+		Raw:       args.Raw,
+	}
+	if args.VariableType != "" {
+		attrs.VariableType = &args.VariableType
+	}
+	if args.Description != "" {
+		attrs.Description = &args.Description
+	}
+	return attrs
+}
+
+// cicdVarAPI the get, update and create APIs of CICD variable by it's target type, the instance one is ignoring the path and environment scope
+func cicdVarAPI(glExecutor gl.GitlabAPI, varType string) (
+	getVar func(path, varName, envScope string) (*gl.GitlabCICDVar, error),
+	updateVar func(path, varName, envScope, value string, attrs gl.GitlabCICDVarAttrs) error,
+	createVar func(path, varName, value string, attrs gl.GitlabCICDVarAttrs) error,
+) {
+	switch varType {
+	case cfg.ManagedTypeRepository:
+		return glExecutor.GetRepoVar, glExecutor.UpdateRepoVar, glExecutor.CreateRepoVar
+	case cfg.UpdateVarTypeInstance:
+		return func(_, varName, _ string) (*gl.GitlabCICDVar, error) {
+				return glExecutor.GetInstanceVar(varName)
+			}, func(_, varName, _, value string, attrs gl.GitlabCICDVarAttrs) error {
+				return glExecutor.UpdateInstanceVar(varName, value, attrs)
+			}, func(_, varName, value string, attrs gl.GitlabCICDVarAttrs) error {
+				return glExecutor.CreateInstanceVar(varName, value, attrs)
+			}
+	}
+	return glExecutor.GetGroupVar, glExecutor.UpdateGroupVar, glExecutor.CreateGroupVar
+}
+
+// varEnvScopes the environment scopes of the updated CICD variable, an empty one means it's not filtered by environment scope
+func varEnvScopes(glExecutor gl.GitlabAPI, args cfg.HookUpdateVar) ([]string, error) {
+	if len(args.EnvironmentScopes) == 0 {
+		return []string{""}, nil
+	}
+	if !args.AllEnvScopes() {
+		return args.EnvironmentScopes, nil
+	}
+
+	listVars := glExecutor.ListGroupVars
+	if args.Type == cfg.ManagedTypeRepository {
+		listVars = glExecutor.ListRepoVars
+	}
+	vars, err := listVars(args.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	var envScopes []string
+	for _, v := range vars {
+		if v.Key == args.Name {
+			envScopes = append(envScopes, v.EnvironmentScope)
+		}
+	}
+	if len(envScopes) == 0 {
+		return nil, fmt.Errorf("variable %s is not found in any environment scope of %s", args.Name, args.Path)
+	}
+	return envScopes, nil
+}
+
+// updateVar update the CICD variable content in each of the environment scopes, the errors are joined so all of them are attempted
+func (g GitlabTokenUpdater) updateVar(logHook zerolog.Logger, glExecutor gl.GitlabAPI, args cfg.HookUpdateVar, newToken string) error {
+	envScopes, err := varEnvScopes(glExecutor, args)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, envScope := range envScopes {
+		if err := g.updateScopedVar(logHook, glExecutor, args, envScope, newToken); err != nil {
+			if envScope != "" {
+				err = fmt.Errorf("environment scope %s: %w", envScope, err)
+			}
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// updateScopedVar update the CICD variable content and enforcing it's managed attributes, the missing one is created if it's configured.
+// In dry run mode, only checking the existence of the variable and reporting the attributes that would be changed
+func (g GitlabTokenUpdater) updateScopedVar(logHook zerolog.Logger, glExecutor gl.GitlabAPI, args cfg.HookUpdateVar, envScope string, newToken string) error {
+	attrs := updateVarAttrs(args)
+	getVar, updateVar, createVar := cicdVarAPI(glExecutor, args.Type)
+
+	if !g.dryRun && !args.CreateIfMissing {
+		return updateVar(args.Path, args.Name, envScope, newToken, attrs)
+	}
+
+	logVar := logHook.With().Str("var_path", args.Path).Str("var_name", args.Name).Str("environment_scope", envScope).Logger()
+	cicdVar, err := getVar(args.Path, args.Name, envScope)
+	if errors.Is(err, gl.ErrNotFound) && args.CreateIfMissing {
+		if g.dryRun {
+			logVar.Warn().Msg("would create the missing variable")
+			return nil
+		}
+		logVar.Warn().Msg("creating the missing variable")
+		if envScope != "" {
+			attrs.EnvironmentScope = &envScope
+		}
+		return createVar(args.Path, args.Name, newToken, attrs)
+	} else if err != nil {
+		return err
+	}
+
+	if g.dryRun {
+		for _, drift := range attrs.Drift(*cicdVar) {
+			logVar.Warn().Str("drift", drift).Msg("would update the variable attribute")
+		}
+		return nil
+	}
+
+	return updateVar(args.Path, args.Name, envScope, newToken, attrs)
+}
+
+// hookTemplateData the data for rendering the hook value template of the renewed access token
+func (g GitlabTokenUpdater) hookTemplateData(at accessTokenPair, newToken string) cfg.HookTemplateData {
+	return cfg.NewHookTemplateData(
+		newToken,
+		at.cfgAccessToken.Name,
+		at.glAccessToken.Path,
+		at.glAccessToken.ID,
+		g.nextExpiry(at),
+		g.config.Host,
+	)
+}
+
+// execCMDEnvVar the env vars of the token context that are injected to the executable in exec_cmd hook
+func (g GitlabTokenUpdater) execCMDEnvVar(at accessTokenPair, newToken string, attempt int) map[string]string {
+	dryRun := "0"
+	if g.dryRun {
+		dryRun = "1"
+	}
+	return map[string]string{
+		injectEnvVarShellExec:      newToken,
+		injectEnvVarTokenName:      at.cfgAccessToken.Name,
+		injectEnvVarTokenID:        strconv.Itoa(at.glAccessToken.ID),
+		injectEnvVarTokenPath:      at.glAccessToken.Path,
+		injectEnvVarTokenType:      managedType(at.glAccessToken.Type),
+		injectEnvVarTokenExpiresAt: g.nextExpiry(at).Format(time.DateOnly),
+		injectEnvVarHost:           g.config.Host,
+		injectEnvVarDryRun:         dryRun,
+		injectEnvVarHookAttempt:    strconv.Itoa(attempt),
+	}
+}
+
+// hookContext the context of the hook execution, it's not cancelled by the interruption so the new token of the rotated access token is still distributed.
+// It's bounded by the timeout if it's set, the interruption is only checked between the access tokens
+func (g GitlabTokenUpdater) hookContext(timeout time.Duration) (context.Context, context.CancelFunc) {
+	ctx := context.WithoutCancel(g.ctx)
+	if timeout > 0 {
+		return context.WithTimeout(ctx, timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+func (g GitlabTokenUpdater) execHook(logHook zerolog.Logger, hk cfg.Hook, at accessTokenPair, newToken string, attempt int) (err error) {
+	if hk.Type == cfg.HookTypeUseToken {
 		if g.dryRun {
 			return nil
 		}
 		return g.glAPI.Auth(newToken)
+	}
+
+	// the written value is rendered from the template if it's set, the dummy token is rendered in dry run mode as well
+	rawToken := newToken
+	if newToken, err = hk.RenderValue(g.hookTemplateData(at, rawToken)); err != nil {
+		return err
+	}
+
+	switch hk.Type {
 	case cfg.HookTypeUpdateVar:
 		args := hk.UpdateVarArgs()
 		glExecutor := g.glAPI
 		if args.Gitlab != "" {
-			log.Info().Msgf("using external Gitlab instance (`%s`) in update_var hook", args.Gitlab)
+			logHook.Info().Msgf("using external Gitlab instance (`%s`) in update_var hook", args.Gitlab)
 			if glExecutor, err = g.glAPI.InitGitlab(args.Gitlab, args.GitlabToken); err != nil {
 				return err
 			}
 		}
 
-		if args.Type == cfg.ManagedTypeRepository {
-			if g.dryRun {
-				_, err := glExecutor.GetRepoVar(args.Path, args.Name)
-				return err
-			}
-
-			return glExecutor.UpdateRepoVar(
-				args.Path,
-				args.Name,
-				newToken,
-			)
-		}
-
-		// managed_type: group
-		if g.dryRun {
-			_, err := glExecutor.GetGroupVar(args.Path, args.Name)
-			return err
-		}
-
-		return glExecutor.UpdateGroupVar(
-			args.Path,
-			args.Name,
-			newToken,
-		)
+		return g.updateVar(logHook, glExecutor, args, newToken)
 	case cfg.HookTypeExecCMD:
 		args := hk.ExecCMDArgs()
-		if g.dryRun {
-			return g.sh.FileMustExists(args.Path)
+		if g.dryRun && !args.DryRun {
+			return g.sh.FileMustExists(args.Executable())
 		}
-		args.EnvVar[injectEnvVarShellExec] = newToken
 
-		results, err := g.sh.Exec(args.Path, args.EnvVar)
-		log.Debug().Msgf("script execution results %s", string(results))
+		envVar := g.execCMDEnvVar(at, newToken, attempt)
+		stdin := ""
+		if args.TokenStdin {
+			stdin = newToken
+			delete(envVar, injectEnvVarShellExec)
+		}
+		maps.Copy(args.EnvVar, envVar)
+
+		// the timeout is applied by the executor, so it's reported as timeout error
+		ctx, cancel := g.hookContext(0)
+		defer cancel()
+		result, err := g.sh.Run(ctx, shell.Command{
+			Path:        args.Path,
+			Args:        args.Args,
+			Script:      args.Script,
+			Interpreter: args.Interpreter,
+			WorkDir:     args.WorkDir,
+			Timeout:     args.Timeout,
+			Env:         args.EnvVar,
+			InheritEnv:  args.InheritEnv,
+			Stdin:       stdin,
+			Redact:      []string{rawToken, newToken},
+		})
+		if result != nil {
+			logHook.Debug().Str("stdout", string(result.Stdout)).Str("stderr", string(result.Stderr)).Msg("script execution results")
+		}
 		return err
+	case cfg.HookTypeK8sSecret:
+		return g.updateK8sSecret(logHook, hk.K8sSecretArgs(), newToken)
+	case cfg.HookTypeVaultKV:
+		return g.updateVaultKV(logHook, hk.VaultKVArgs(), newToken)
+	case cfg.HookTypeHTTP:
+		return g.execHTTP(logHook, hk, g.hookTemplateData(at, rawToken))
 	}
 
 	return nil
@@ -167,88 +478,249 @@ func (g GitlabTokenUpdater) execHook(hk cfg.Hook, newToken string) (err error) {
 // errAppender appending error if not in strict mode, otherwise just return the error as is
 func (g *GitlabTokenUpdater) errAppender(err error) error {
 	if err != nil && !g.strict {
+		g.errMu.Lock()
+		defer g.errMu.Unlock()
 		g.errors = append(g.errors, err)
 		return nil
 	}
 	return err
 }
 
-// Do the main sequences of app logic
-func (g *GitlabTokenUpdater) Do() error {
-	for _, mg := range g.config.Managed {
-		path := mg.Path
-		logPath := log.With().Str("path", path).Str("m_type", mg.Type).Logger()
-		logPath.Info().Msg("processing")
-
-		ats, err := g.listAccessTokens(mg)
-		if err != nil {
-			logPath.Error().Err(err).Msg("error while listing access token")
-			if err = g.errAppender(err); err != nil {
-				return err
+// execHooks executing all of the configured hooks of the renewed access token, each of them will be retried as configured
+func (g *GitlabTokenUpdater) execHooks(logTkn zerolog.Logger, at accessTokenPair, newToken string, tknReport *TokenReport) error {
+	for idx, hk := range at.cfgAccessToken.Hooks {
+		seq := idx + 1
+		hkReport := &HookReport{Type: hk.Type, Args: hk.StrArgs()}
+		logHook := logTkn.With().Str("hook_type", hk.Type).Str("args", hkReport.Args).Logger()
+		if at.doneHooks[seq] {
+			logHook.Info().Int("hook", seq).Msg("hook is already succeeded, skip it")
+			continue
+		}
+		tknReport.Hooks = append(tknReport.Hooks, hkReport)
+
+		var lastErr error
+		for i := 1; i <= int(hk.Retry+1); i++ {
+			logHookAttempt := logHook.With().Int("attempt", i).Logger()
+			hkReport.Attempts = i
+
+			logHookAttempt.Debug().Msg("executing hook")
+			err := g.execHook(logHookAttempt, hk, at, newToken, i)
+			if err == nil {
+				logHookAttempt.Info().Msg("hook successfully executed")
+				lastErr = nil
+				break
 			}
+
+			logHookAttempt.Error().Err(err).Msg("error in hook execution")
+			lastErr = err
+		}
+
+		hkReport.Success = lastErr == nil
+		g.recordHook(at, seq, hk, lastErr)
+		if lastErr != nil {
+			hkReport.Error = lastErr.Error()
+			tknReport.fail(lastErr)
+		}
+		if err := g.errAppender(lastErr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createToken create the missing (or recreate the expired) access token then executing it's hooks
+func (g *GitlabTokenUpdater) createToken(logTkn zerolog.Logger, at accessTokenPair, tknReport *TokenReport) error {
+	scopes, accessLevel := at.createAttrs()
+	logCreate := logTkn.Warn().Strs("scopes", scopes).Int("access_level", accessLevel)
+	status := TokenStatusCreated
+	switch {
+	case at.recreate && g.dryRun:
+		logCreate.Int("expired_id", at.glAccessToken.ID).Msg("would recreate the expired or revoked token")
+	case at.recreate:
+		logCreate.Int("expired_id", at.glAccessToken.ID).Msg("recreating the expired or revoked token")
+	case g.dryRun:
+		logCreate.Msg("would create the missing token")
+	default:
+		logCreate.Msg("creating the missing token")
+	}
+	if at.recreate {
+		status = TokenStatusRecreated
+		tknReport.ID = at.glAccessToken.ID
+		tknReport.OldExpiresAt = at.glAccessToken.ExpiresAt
+	}
+
+	newToken, err := g.processCreate(at)
+	if err != nil {
+		logTkn.Error().Err(err).Msg("error create token")
+		tknReport.fail(err)
+		return g.errAppender(err)
+	}
+	logTkn.Info().Msg("token successfully created")
+	nextExpiry := g.nextExpiry(at)
+	tknReport.Status = status
+	tknReport.NewExpiresAt = &nextExpiry
+	g.recordRotated(at)
+
+	if len(at.cfgAccessToken.Hooks) < 1 {
+		logTkn.Debug().Msg("no hook configured")
+		return nil
+	}
+	logTkn.Info().Msg("executing hooks")
+
+	return g.execHooksRecoverable(logTkn, at, newToken, tknReport)
+}
+
+// processToken renew the access token if it's reach the renew time (or forced) then executing it's hooks
+func (g *GitlabTokenUpdater) processToken(logPath zerolog.Logger, at accessTokenPair, tknReport *TokenReport) error {
+	logTkn := logPath.With().Str("token", at.cfgAccessToken.Name).Logger()
+	logTkn.Info().Msg("processing")
+
+	if at.missing {
+		return g.createToken(logTkn, at, tknReport)
+	}
+
+	expiresAt := at.glAccessToken.ExpiresAt
+	tknReport.ID = at.glAccessToken.ID
+	tknReport.OldExpiresAt = expiresAt
+	_, validToRenew := g.renewInfo(at)
+	if validToRenew {
+		logTkn.Warn().Msgf("reach renew time. expired: %v, renew before: %s", expiresAt, at.cfgAccessToken.RenewBefore)
+	}
+
+	if !(g.forceRenew || validToRenew) {
+		logTkn.Debug().Any("expires_at", expiresAt).Msg("not identified as need to renew")
+		return nil
+	}
+
+	return g.renewToken(logTkn, at, tknReport)
+}
+
+// renewToken rotate the access token then executing it's hooks
+func (g *GitlabTokenUpdater) renewToken(logTkn zerolog.Logger, at accessTokenPair, tknReport *TokenReport) error {
+	logTkn.Info().Msg("processing token renewal")
+	newToken, err := g.processRenew(at)
+	if err != nil {
+		logTkn.Error().Err(err).Msg("error renew token")
+		tknReport.fail(err)
+		return g.errAppender(err)
+	}
+	logTkn.Info().Msg("token successfully renewed")
+	nextExpiry := g.nextExpiry(at)
+	tknReport.Status = TokenStatusRenewed
+	tknReport.NewExpiresAt = &nextExpiry
+	g.recordRotated(at)
+
+	if len(at.cfgAccessToken.Hooks) < 1 {
+		logTkn.Debug().Msg("no hook configured")
+		return nil
+	}
+	logTkn.Info().Msg("executing hooks")
+
+	return g.execHooksRecoverable(logTkn, at, newToken, tknReport)
+}
+
+// processManaged process all of the selected access tokens in a managed token config
+func (g *GitlabTokenUpdater) processManaged(mg cfg.ManagedToken, mgReport *ManagedReport) error {
+	logPath := log.With().Str("path", mg.Path).Str("m_type", mg.Type).Logger()
+
+	selected, isSelected := g.selector.filter(mg)
+	if !isSelected {
+		logPath.Debug().Msg("not selected, skip it")
+		for _, tkn := range mg.Tokens {
+			mgReport.addToken(tkn.Name).Reason = skipReasonNotSelected
+		}
+		return nil
+	}
+	logPath.Info().Msg("processing")
+
+	ats, err := g.listAccessTokens(selected)
+	if err != nil && g.interrupted() {
+		// the listing is cancelled by the interruption
+		return ErrInterrupted
+	} else if err != nil {
+		logPath.Error().Err(err).Msg("error while listing access token")
+		mgReport.Error = err.Error()
+		return g.errAppender(err)
+	}
+	g.record(journal.Record{Step: journal.StepListed, Type: mg.Type, Path: mg.Path})
+
+	// the listed access tokens are in the same order as configured, the not exists one are excluded
+	atIdx, selIdx := 0, 0
+	for _, tkn := range mg.Tokens {
+		// the in-flight token and it's hooks are completed before stopping
+		if g.interrupted() {
+			return ErrInterrupted
+		}
+
+		tknReport := mgReport.addToken(tkn.Name)
+		if selIdx >= len(selected.Tokens) || selected.Tokens[selIdx].Name != tkn.Name {
+			tknReport.Reason = skipReasonNotSelected
 			continue
 		}
+		selIdx++
 
-		for _, at := range ats {
-			logTkn := logPath.With().Str("token", at.cfgAccessToken.Name).Logger()
-			logTkn.Info().Msg("processing")
-
-			befDur, _ := at.cfgAccessToken.RenewBeforeDuration()
-			addMe := g.now.Add(befDur)
-			expiresAt := at.glAccessToken.ExpiresAt
-			validToRenew := false
-			if expiresAt != nil && addMe.After(*expiresAt) {
-				logTkn.Warn().Msgf("reach renew time. expired: %v, renew before: %s", expiresAt, at.cfgAccessToken.RenewBefore)
-				validToRenew = true
-			}
+		if atIdx >= len(ats) || ats[atIdx].cfgAccessToken.Name != tkn.Name {
+			tknReport.Status = TokenStatusNotFound
+			continue
+		}
 
-			if !(g.forceRenew || validToRenew) {
-				logTkn.Debug().Any("expires_at", expiresAt).Msg("not identified as need to renew")
-				continue
-			}
+		err = g.processToken(logPath, ats[atIdx], tknReport)
+		if notifyErr := g.notify(logPath, mg, ats[atIdx].cfgAccessToken, tknReport); err == nil {
+			err = notifyErr
+		}
+		if err != nil {
+			return err
+		}
+		atIdx++
+	}
+	return nil
+}
 
-			logTkn.Info().Msg("processing token renewal")
-			newToken, err := g.processRenew(at)
-			if err != nil {
-				logTkn.Error().Err(err).Msg("error renew token")
-				if err = g.errAppender(err); err != nil {
-					return err
-				}
-				continue
-			}
-			logTkn.Info().Msg("token successfully renewed")
+// interrupted check whether the execution context is canceled or the concurrent processing is aborted
+func (g *GitlabTokenUpdater) interrupted() bool {
+	if g.ctx.Err() == nil && !g.aborted.Load() {
+		return false
+	}
+	log.Warn().Msg("execution interrupted, the rest of access tokens are not processed")
+	return true
+}
 
-			if len(at.cfgAccessToken.Hooks) < 1 {
-				logTkn.Debug().Msg("no hook configured")
-				continue
+// Do the main sequences of app logic
+func (g *GitlabTokenUpdater) Do() error {
+	g.warnOutstanding()
+	g.record(journal.Record{Step: journal.StepRunStarted})
+	if g.concurrency > 1 {
+		if err := g.processConcurrent(); err != nil {
+			return err
+		}
+	} else {
+		for _, mg := range g.config.Managed {
+			if g.interrupted() {
+				return ErrInterrupted
 			}
-			logTkn.Info().Msg("executing hooks")
-
-			for _, hk := range at.cfgAccessToken.Hooks {
-				logHook := logTkn.With().Str("hook_type", hk.Type).Str("args", hk.StrArgs()).Logger()
-				var lastErr error
-				for i := 1; i <= int(hk.Retry+1); i++ {
-					logHookAttempt := logHook.With().Int("attempt", i).Logger()
-
-					logHookAttempt.Debug().Msg("executing hook")
-					err = g.execHook(hk, newToken)
-					if err == nil {
-						logHookAttempt.Info().Msg("hook successfully executed")
-						lastErr = nil
-						break
-					}
-
-					logHookAttempt.Error().Err(err).Msg("error in hook execution")
-					lastErr = err
-				}
-				if err = g.errAppender(lastErr); err != nil {
-					return err
-				}
+
+			if err := g.processManaged(mg, g.report.addManaged(mg.Path, mg.Type)); err != nil {
+				return err
 			}
 		}
 	}
 
+	g.record(journal.Record{Step: journal.StepRunFinished})
 	log.Info().Msg("done")
+	return g.collectedErrors()
+}
+
+// Report return the results of the execution
+func (g *GitlabTokenUpdater) Report() *Report {
+	g.report.Errors = []string{}
+	for _, err := range g.errors {
+		g.report.Errors = append(g.report.Errors, err.Error())
+	}
+	return g.report
+}
+
+// collectedErrors print out the accumulated errors (non strict mode) and return ErrDuringExecution if there is any
+func (g *GitlabTokenUpdater) collectedErrors() error {
 	if len(g.errors) == 0 {
 		return nil
 	}
@@ -264,24 +736,58 @@ func (g *GitlabTokenUpdater) Do() error {
 // WithForceRenew set enble/disable force renew scenario
 func (g *GitlabTokenUpdater) WithForceRenew(e bool) *GitlabTokenUpdater {
 	g.forceRenew = e
+	g.report.Force = e
 	return g
 }
 
 // WithDryRun set enable/disable dry run mode
 func (g *GitlabTokenUpdater) WithDryRun(e bool) *GitlabTokenUpdater {
 	g.dryRun = e
+	g.report.DryRun = e
 	return g
 }
 
 // WithStrictMode set enable/disable strict mode
 func (g *GitlabTokenUpdater) WithStrictMode(e bool) *GitlabTokenUpdater {
 	g.strict = e
+	g.report.Strict = e
+	return g
+}
+
+// WithSelector set the selector of managed and access tokens to be processed, all of them are processed if it's nil
+func (g *GitlabTokenUpdater) WithSelector(s *Selector) *GitlabTokenUpdater {
+	g.selector = s
+	return g
+}
+
+// WithContext set the context, once it's canceled the execution is stopped after the in-flight token and it's hooks are done
+func (g *GitlabTokenUpdater) WithContext(ctx context.Context) *GitlabTokenUpdater {
+	g.ctx = ctx
+	return g
+}
+
+// WithConcurrency set the number of managed tokens that are processed in parallel, they are processed sequentially if it's less than 2
+func (g *GitlabTokenUpdater) WithConcurrency(n int) *GitlabTokenUpdater {
+	g.concurrency = n
+	return g
+}
+
+// WithK8sInit set the Kubernetes API initiator of k8s_secret hook, used in test
+func (g *GitlabTokenUpdater) WithK8sInit(k8sInit K8sInitFunc) *GitlabTokenUpdater {
+	g.k8sInit = k8sInit
+	return g
+}
+
+// WithVaultInit set the Vault API initiator of vault_kv hook, used in test
+func (g *GitlabTokenUpdater) WithVaultInit(vaultInit VaultInitFunc) *GitlabTokenUpdater {
+	g.vaultInit = vaultInit
 	return g
 }
 
 // WithCustomCurrentTime set custom current time, used in test
 func (g *GitlabTokenUpdater) WithCustomCurrentTime(tm *time.Time) *GitlabTokenUpdater {
 	g.now = tm
+	g.report.ExecutedAt = *tm
 	return g
 }
 
@@ -289,12 +795,20 @@ func (g *GitlabTokenUpdater) WithCustomCurrentTime(tm *time.Time) *GitlabTokenUp
 func NewGitlabTokenUpdater(config *cfg.Config, glAPI gl.GitlabAPI, sh shell.Shell) *GitlabTokenUpdater {
 	now := time.Now()
 	return &GitlabTokenUpdater{
+		ctx:        context.Background(),
 		config:     config,
 		glAPI:      glAPI,
+		listAPI:    glAPI,
+		k8sInit:    k8s.NewKubernetesAPI,
+		vaultInit:  vault.NewVaultAPI,
 		sh:         sh,
 		now:        &now,
 		dryRun:     false,
 		forceRenew: false,
 		errors:     []error{},
+		report:     &Report{ExecutedAt: now, Managed: []*ManagedReport{}},
+		errMu:      &sync.Mutex{},
+		storeMu:    &sync.Mutex{},
+		aborted:    &atomic.Bool{},
 	}
 }
@@ -0,0 +1,92 @@
+package app
+
+import (
+	"context"
+	"sync"
+
+	cfg "github.com/iomarmochtar/gitlab-token-updater/pkg/config"
+)
+
+// sequential the managed token that is depending on the executing token, it's processed before the concurrent ones.
+// The personal access tokens are belong to the executing user and only they can have use_token hook that is replacing the executing token
+func sequential(mg cfg.ManagedToken) bool {
+	return mg.Type == cfg.ManagedTypePersonal
+}
+
+// processConcurrent process the sequential managed tokens first then the rest of them by the worker pool,
+// the reports are kept in the configured order. In strict mode, the first failure is stopping the outstanding ones and cancelling the in-flight listings
+func (g *GitlabTokenUpdater) processConcurrent() (err error) {
+	reports := make([]*ManagedReport, len(g.config.Managed))
+	defer func() {
+		for _, mgReport := range reports {
+			if mgReport != nil {
+				g.report.Managed = append(g.report.Managed, mgReport)
+			}
+		}
+	}()
+
+	var concurrent []int
+	for idx, mg := range g.config.Managed {
+		if !sequential(mg) {
+			concurrent = append(concurrent, idx)
+			continue
+		}
+		if g.interrupted() {
+			return ErrInterrupted
+		}
+		reports[idx] = newManagedReport(mg.Path, mg.Type)
+		if err = g.processManaged(mg, reports[idx]); err != nil {
+			return err
+		}
+	}
+
+	// the in-flight listings are cancelled by the first failure, the in-flight rotations and their hooks are completed
+	ctx, cancel := context.WithCancel(g.ctx)
+	defer cancel()
+	listAPI, err := g.glAPI.WithContext(ctx)
+	if err != nil {
+		return err
+	}
+	g.listAPI = listAPI
+	defer func() {
+		g.listAPI = g.glAPI
+	}()
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	workers := make(chan struct{}, g.concurrency)
+	for _, idx := range concurrent {
+		workers <- struct{}{}
+		if g.interrupted() {
+			<-workers
+			break
+		}
+
+		wg.Add(1)
+		mg := g.config.Managed[idx]
+		reports[idx] = newManagedReport(mg.Path, mg.Type)
+		go func(mgReport *ManagedReport) {
+			defer func() {
+				<-workers
+				wg.Done()
+			}()
+			if err := g.processManaged(mg, mgReport); err != nil {
+				// the others are interrupted by the abort, so only the first failure is returned
+				once.Do(func() {
+					firstErr = err
+					g.aborted.Store(true)
+					cancel()
+				})
+			}
+		}(reports[idx])
+	}
+	wg.Wait()
+
+	if firstErr == nil && g.interrupted() {
+		return ErrInterrupted
+	}
+	return firstErr
+}
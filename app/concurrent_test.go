@@ -0,0 +1,161 @@
+package app_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/iomarmochtar/gitlab-token-updater/app"
+	cfg "github.com/iomarmochtar/gitlab-token-updater/pkg/config"
+	gl "github.com/iomarmochtar/gitlab-token-updater/pkg/gitlab"
+	t_helper "github.com/iomarmochtar/gitlab-token-updater/test"
+	gm "github.com/iomarmochtar/gitlab-token-updater/test/mocks/gitlab"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+// genConcurrentConfig the repository managed tokens of the given paths along with the personal one that has use_token hook
+func genConcurrentConfig(paths ...string) *cfg.Config {
+	config := t_helper.GenConfig(nil, nil, nil)
+	config.Managed = nil
+	for _, path := range paths {
+		config.Managed = append(config.Managed, cfg.ManagedToken{
+			Type:   cfg.ManagedTypeRepository,
+			Path:   path,
+			Tokens: t_helper.GenAccessTokens(nil, nil),
+		})
+	}
+	config.Managed = append(config.Managed, cfg.ManagedToken{
+		Type:   cfg.ManagedTypePersonal,
+		Tokens: []cfg.AccessToken{{Name: t_helper.SamplePersonalAccessToken.Name, RenewBefore: "1M", Hooks: []cfg.Hook{{Type: cfg.HookTypeUseToken}}}},
+	})
+	return config
+}
+
+func TestGitlabTokenUpdater_Do_Concurrency(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	config := genConcurrentConfig("/path/to/first", "/path/to/second")
+	assert.NoError(t, config.InitValues())
+
+	var (
+		mu     sync.Mutex
+		listed []string
+	)
+	started := map[string]chan struct{}{"/path/to/first": make(chan struct{}), "/path/to/second": make(chan struct{})}
+	g := gm.NewMockGitlabAPI(ctrl)
+	g.EXPECT().WithContext(gomock.Any()).Return(g, nil)
+	g.EXPECT().ListPersonalAccessToken().DoAndReturn(func() ([]gl.GitlabAccessToken, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		listed = append(listed, cfg.ManagedTypePersonal)
+		return []gl.GitlabAccessToken{t_helper.SamplePersonalAccessToken}, nil
+	})
+	g.EXPECT().ListRepoAccessToken(gomock.Any()).DoAndReturn(func(path string) ([]gl.GitlabAccessToken, error) {
+		mu.Lock()
+		listed = append(listed, path)
+		mu.Unlock()
+
+		// both of them are in-flight at the same time
+		close(started[path])
+		for _, ch := range started {
+			select {
+			case <-ch:
+			case <-time.After(5 * time.Second):
+				t.Errorf("%s is not processed concurrently", path)
+			}
+		}
+		token := t_helper.SampleRepoAccessToken
+		token.Path = path
+		return []gl.GitlabAccessToken{token}, nil
+	}).Times(2)
+
+	updater := app.NewGitlabTokenUpdater(config, g, nil).
+		WithCustomCurrentTime(t_helper.GenTime("2024-01-01")).
+		WithConcurrency(2)
+	assert.NoError(t, updater.Do())
+
+	// the personal one is processed first, but the reports are kept in the configured order
+	assert.Equal(t, cfg.ManagedTypePersonal, listed[0])
+	types := []string{}
+	for _, mgReport := range updater.Report().Managed {
+		types = append(types, mgReport.Type+":"+mgReport.Path)
+	}
+	assert.Equal(t, []string{"repository:/path/to/first", "repository:/path/to/second", "personal:@personal"}, types)
+}
+
+func TestGitlabTokenUpdater_Do_ConcurrencyStrict(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	config := genConcurrentConfig("/path/to/first", "/path/to/second", "/path/to/third")
+	assert.NoError(t, config.InitValues())
+
+	// the third one is never processed as the worker is released after the execution is aborted
+	listErr := errors.New("500 Internal Server Error")
+	var wg sync.WaitGroup
+	wg.Add(2)
+	g := gm.NewMockGitlabAPI(ctrl)
+	g.EXPECT().WithContext(gomock.Any()).Return(g, nil)
+	g.EXPECT().ListPersonalAccessToken().Return([]gl.GitlabAccessToken{t_helper.SamplePersonalAccessToken}, nil)
+	for _, path := range []string{"/path/to/first", "/path/to/second"} {
+		g.EXPECT().ListRepoAccessToken(path).DoAndReturn(func(_ string) ([]gl.GitlabAccessToken, error) {
+			wg.Done()
+			wg.Wait()
+			return nil, listErr
+		})
+	}
+
+	updater := app.NewGitlabTokenUpdater(config, g, nil).
+		WithCustomCurrentTime(t_helper.GenTime("2024-01-01")).
+		WithStrictMode(true).
+		WithConcurrency(2)
+	err := updater.Do()
+	assert.ErrorIs(t, err, listErr)
+	assert.NotErrorIs(t, err, app.ErrInterrupted)
+	assert.Len(t, updater.Report().Managed, 3)
+}
+
+func TestGitlabTokenUpdater_Do_ConcurrencyStrictCancel(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	config := genConcurrentConfig("/path/to/first", "/path/to/second")
+	config.Managed = config.Managed[:2]
+	assert.NoError(t, config.InitValues())
+
+	// the in-flight listing of the second one is cancelled by the failure of the first one, then nothing is rotated
+	listErr := errors.New("500 Internal Server Error")
+	var poolCtx context.Context
+	secondStarted := make(chan struct{})
+	g := gm.NewMockGitlabAPI(ctrl)
+	g.EXPECT().WithContext(gomock.Any()).DoAndReturn(func(ctx context.Context) (gl.GitlabAPI, error) {
+		poolCtx = ctx
+		return g, nil
+	})
+	g.EXPECT().ListRepoAccessToken("/path/to/first").DoAndReturn(func(_ string) ([]gl.GitlabAccessToken, error) {
+		<-secondStarted
+		return nil, listErr
+	})
+	g.EXPECT().ListRepoAccessToken("/path/to/second").DoAndReturn(func(path string) ([]gl.GitlabAccessToken, error) {
+		close(secondStarted)
+		select {
+		case <-poolCtx.Done():
+		case <-time.After(5 * time.Second):
+			t.Error("the in-flight listing is not cancelled")
+		}
+		token := t_helper.SampleRepoAccessToken
+		token.Path = path
+		return []gl.GitlabAccessToken{token}, nil
+	})
+	g.EXPECT().RotateRepoToken(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+	updater := app.NewGitlabTokenUpdater(config, g, nil).
+		WithCustomCurrentTime(t_helper.GenTime("2024-04-05")).
+		WithStrictMode(true).
+		WithConcurrency(2)
+	assert.ErrorIs(t, updater.Do(), listErr)
+}
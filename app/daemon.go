@@ -0,0 +1,275 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	DaemonStatusIdle    = "idle"
+	DaemonStatusRunning = "running"
+
+	DaemonHealthPath = "/healthz"
+
+	daemonReadHeaderTimeout = 5 * time.Second
+	daemonShutdownTimeout   = 10 * time.Second
+)
+
+var (
+	ErrDaemonScheduleRequired  = errors.New("either schedule or interval must be set")
+	ErrDaemonScheduleAmbiguous = errors.New("schedule and interval cannot be set at the same time")
+)
+
+// DaemonRunFunc a single execution cycle in daemon mode
+type DaemonRunFunc func(ctx context.Context) (*Report, error)
+
+// DaemonRun outcome of an execution cycle
+type DaemonRun struct {
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	Duration   string    `json:"duration"`
+	Success    bool      `json:"success"`
+	Error      string    `json:"error,omitempty"`
+	Renewed    int       `json:"renewed"`
+	Failed     int       `json:"failed"`
+}
+
+// DaemonHealth state of daemon that is served in health endpoint
+type DaemonHealth struct {
+	Status    string     `json:"status"`
+	NextRunAt *time.Time `json:"next_run_at"`
+	LastRun   *DaemonRun `json:"last_run"`
+}
+
+// Daemon execute the cycle based on the schedule, a cycle is never overlapped with another one
+type Daemon struct {
+	schedule   cron.Schedule
+	run        DaemonRunFunc
+	reload     func() error
+	listenAddr string
+	runOnStart bool
+	handlers   map[string]http.Handler
+	mu         sync.RWMutex
+	health     DaemonHealth
+}
+
+// ParseSchedule parse cron expression (including the descriptors such as @daily) or interval duration, only one of them can be set
+func ParseSchedule(schedule string, interval time.Duration) (cron.Schedule, error) {
+	switch {
+	case schedule != "" && interval != 0:
+		return nil, ErrDaemonScheduleAmbiguous
+	case schedule != "":
+		sched, err := cron.ParseStandard(schedule)
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule %s: %w", schedule, err)
+		}
+		return sched, nil
+	case interval > 0:
+		return cron.Every(interval), nil
+	}
+	return nil, ErrDaemonScheduleRequired
+}
+
+// countByStatus count the access tokens in the report by status
+func (r *Report) countByStatus(status string) (total int) {
+	for _, mr := range r.Managed {
+		for _, tr := range mr.Tokens {
+			if tr.Status == status {
+				total++
+			}
+		}
+	}
+	return total
+}
+
+// execute run a cycle then record the outcome
+func (d *Daemon) execute(ctx context.Context) {
+	started := time.Now()
+	d.setStatus(DaemonStatusRunning)
+	log.Info().Msg("starting execution cycle")
+
+	report, err := d.run(ctx)
+	finished := time.Now()
+	lastRun := &DaemonRun{
+		StartedAt:  started,
+		FinishedAt: finished,
+		Duration:   finished.Sub(started).String(),
+		Success:    err == nil,
+	}
+	if err != nil {
+		lastRun.Error = err.Error()
+		log.Error().Err(err).Msg("execution cycle failed")
+	} else {
+		log.Info().Str("duration", lastRun.Duration).Msg("execution cycle done")
+	}
+	if report != nil {
+		lastRun.Renewed = report.countByStatus(TokenStatusRenewed)
+		lastRun.Failed = report.countByStatus(TokenStatusFailed)
+	}
+
+	d.mu.Lock()
+	d.health.Status = DaemonStatusIdle
+	d.health.LastRun = lastRun
+	d.mu.Unlock()
+}
+
+func (d *Daemon) setStatus(status string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.health.Status = status
+}
+
+func (d *Daemon) setNextRun(next time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.health.NextRunAt = &next
+}
+
+// Health return the current state of daemon
+func (d *Daemon) Health() DaemonHealth {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	health := d.health
+	if health.LastRun != nil {
+		lastRun := *health.LastRun
+		health.LastRun = &lastRun
+	}
+	return health
+}
+
+// ServeHTTP health endpoint handler, it's responding the daemon state including the outcome of last cycle
+func (d *Daemon) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := writeJSON(w, d.Health()); err != nil {
+		log.Error().Err(err).Msg("error while writing health response")
+	}
+}
+
+// handleReload re-read the configuration as the SIGHUP received
+func (d *Daemon) handleReload() {
+	if d.reload == nil {
+		log.Info().Msg("reload requested, configuration is re-read in each cycle")
+		return
+	}
+
+	if err := d.reload(); err != nil {
+		log.Error().Err(err).Msg("error while reloading configuration, keep using the previous one")
+		return
+	}
+	log.Info().Msg("configuration reloaded")
+}
+
+// serveHealth start the health endpoint server, it's shutdown once the context is done
+func (d *Daemon) serveHealth(ctx context.Context, wg *sync.WaitGroup) error {
+	listener, err := net.Listen("tcp", d.listenAddr)
+	if err != nil {
+		return fmt.Errorf("error while listening health endpoint: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(DaemonHealthPath, d)
+	for path, handler := range d.handlers {
+		mux.Handle(path, handler)
+	}
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: daemonReadHeaderTimeout}
+	log.Info().Str("addr", listener.Addr().String()).Msg("serving health endpoint")
+
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		if errSrv := srv.Serve(listener); errSrv != nil && !errors.Is(errSrv, http.ErrServerClosed) {
+			log.Error().Err(errSrv).Msg("health endpoint stopped")
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), daemonShutdownTimeout)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+	return nil
+}
+
+// Start run the cycles based on the schedule until the context is done, the in-flight cycle is stopped gracefully.
+// Configuration reload is triggered from the given channel (SIGHUP)
+func (d *Daemon) Start(ctx context.Context, reload <-chan os.Signal) error {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	if d.listenAddr != "" {
+		srvCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		if err := d.serveHealth(srvCtx, &wg); err != nil {
+			return err
+		}
+	}
+
+	if d.runOnStart {
+		d.execute(ctx)
+	}
+
+	next := d.schedule.Next(time.Now())
+	for {
+		d.setNextRun(next)
+		log.Info().Time("next_run_at", next).Msg("waiting for next cycle")
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			log.Info().Msg("daemon stopped")
+			return nil
+		case <-reload:
+			timer.Stop()
+			d.handleReload()
+		case <-timer.C:
+			d.execute(ctx)
+			// calculated after the cycle is done, so the missed schedule during a long cycle is skipped
+			next = d.schedule.Next(time.Now())
+		}
+	}
+}
+
+// WithReload set the function for re-reading configuration as the reload requested
+func (d *Daemon) WithReload(reload func() error) *Daemon {
+	d.reload = reload
+	return d
+}
+
+// WithListenAddr set the address of health endpoint, it's disabled if empty
+func (d *Daemon) WithListenAddr(addr string) *Daemon {
+	d.listenAddr = addr
+	return d
+}
+
+// WithHandler add another endpoint that is served along with health endpoint, such as metrics
+func (d *Daemon) WithHandler(path string, handler http.Handler) *Daemon {
+	d.handlers[path] = handler
+	return d
+}
+
+// WithRunOnStart set enable/disable executing a cycle immediately once it's started
+func (d *Daemon) WithRunOnStart(e bool) *Daemon {
+	d.runOnStart = e
+	return d
+}
+
+// NewDaemon create Daemon with it's default values
+func NewDaemon(schedule cron.Schedule, run DaemonRunFunc) *Daemon {
+	return &Daemon{
+		schedule: schedule,
+		run:      run,
+		handlers: map[string]http.Handler{},
+		health:   DaemonHealth{Status: DaemonStatusIdle},
+	}
+}
@@ -0,0 +1,167 @@
+package app_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/iomarmochtar/gitlab-token-updater/app"
+	"github.com/stretchr/testify/assert"
+)
+
+// fixedIntervalSchedule schedule with sub second interval which is not supported by cron
+type fixedIntervalSchedule time.Duration
+
+func (s fixedIntervalSchedule) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(s))
+}
+
+func TestParseSchedule(t *testing.T) {
+	now := time.Date(2024, 4, 5, 10, 30, 0, 0, time.UTC)
+	testCases := map[string]struct {
+		schedule       string
+		interval       time.Duration
+		expectedNext   time.Time
+		expectedErrMsg string
+	}{
+		"ok: cron expression": {
+			schedule:     "0 3 * * *",
+			expectedNext: time.Date(2024, 4, 6, 3, 0, 0, 0, time.UTC),
+		},
+		"ok: cron descriptor": {
+			schedule:     "@hourly",
+			expectedNext: time.Date(2024, 4, 5, 11, 0, 0, 0, time.UTC),
+		},
+		"ok: interval": {
+			interval:     6 * time.Hour,
+			expectedNext: time.Date(2024, 4, 5, 16, 30, 0, 0, time.UTC),
+		},
+		"err: invalid cron expression": {
+			schedule:       "every day",
+			expectedErrMsg: "invalid schedule every day",
+		},
+		"err: both are set": {
+			schedule:       "@daily",
+			interval:       time.Hour,
+			expectedErrMsg: app.ErrDaemonScheduleAmbiguous.Error(),
+		},
+		"err: none of them is set": {
+			expectedErrMsg: app.ErrDaemonScheduleRequired.Error(),
+		},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			sched, err := app.ParseSchedule(tc.schedule, tc.interval)
+			if tc.expectedErrMsg != "" {
+				assert.ErrorContains(t, err, tc.expectedErrMsg)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedNext, sched.Next(now))
+		})
+	}
+}
+
+func TestDaemon_Start(t *testing.T) {
+	var runs, inFlight, overlapped int32
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	daemon := app.NewDaemon(fixedIntervalSchedule(5*time.Millisecond), func(_ context.Context) (*app.Report, error) {
+		if atomic.AddInt32(&inFlight, 1) > 1 {
+			atomic.AddInt32(&overlapped, 1)
+		}
+		defer atomic.AddInt32(&inFlight, -1)
+
+		// longer than the interval
+		time.Sleep(20 * time.Millisecond)
+		report := &app.Report{Managed: []*app.ManagedReport{{Tokens: []*app.TokenReport{
+			{Status: app.TokenStatusRenewed}, {Status: app.TokenStatusFailed}, {Status: app.TokenStatusSkipped},
+		}}}}
+		if atomic.AddInt32(&runs, 1) >= 3 {
+			cancel()
+		}
+		return report, errors.New("some error(s) occured during execution")
+	}).WithRunOnStart(true)
+
+	assert.NoError(t, daemon.Start(ctx, nil))
+	assert.Equal(t, int32(3), atomic.LoadInt32(&runs))
+	assert.Zero(t, atomic.LoadInt32(&overlapped))
+
+	health := daemon.Health()
+	assert.Equal(t, app.DaemonStatusIdle, health.Status)
+	assert.NotNil(t, health.NextRunAt)
+	assert.False(t, health.LastRun.Success)
+	assert.Equal(t, "some error(s) occured during execution", health.LastRun.Error)
+	assert.Equal(t, 1, health.LastRun.Renewed)
+	assert.Equal(t, 1, health.LastRun.Failed)
+}
+
+func TestDaemon_Start_Reload(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	reload := make(chan os.Signal, 1)
+
+	var reloaded int32
+	daemon := app.NewDaemon(fixedIntervalSchedule(time.Hour), func(_ context.Context) (*app.Report, error) {
+		return nil, nil
+	}).WithReload(func() error {
+		if atomic.AddInt32(&reloaded, 1) == 2 {
+			cancel()
+			return nil
+		}
+		return errors.New("broken config")
+	})
+
+	reload <- syscall.SIGHUP
+	go func() {
+		// the second one after the first is consumed
+		reload <- syscall.SIGHUP
+	}()
+	assert.NoError(t, daemon.Start(ctx, reload))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&reloaded))
+	assert.Nil(t, daemon.Health().LastRun)
+}
+
+func TestDaemon_ServeHTTP(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	daemon := app.NewDaemon(fixedIntervalSchedule(time.Hour), func(_ context.Context) (*app.Report, error) {
+		return &app.Report{}, nil
+	}).WithRunOnStart(true)
+	done := make(chan error)
+	go func() {
+		done <- daemon.Start(ctx, nil)
+	}()
+
+	assert.Eventually(t, func() bool {
+		return daemon.Health().NextRunAt != nil
+	}, time.Second, 5*time.Millisecond)
+
+	rec := httptest.NewRecorder()
+	daemon.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, app.DaemonHealthPath, nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var health app.DaemonHealth
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &health))
+	assert.Equal(t, app.DaemonStatusIdle, health.Status)
+	assert.True(t, health.LastRun.Success)
+
+	cancel()
+	assert.NoError(t, <-done)
+}
+
+func TestDaemon_Start_ListenError(t *testing.T) {
+	daemon := app.NewDaemon(fixedIntervalSchedule(time.Hour), nil).WithListenAddr("256.0.0.1:0")
+	assert.ErrorContains(t, daemon.Start(context.Background(), nil), "error while listening health endpoint")
+}
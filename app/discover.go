@@ -0,0 +1,225 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	cfg "github.com/iomarmochtar/gitlab-token-updater/pkg/config"
+	gl "github.com/iomarmochtar/gitlab-token-updater/pkg/gitlab"
+	"github.com/rs/zerolog/log"
+	yaml "gopkg.in/yaml.v3"
+)
+
+const (
+	discoverHeadComment = "generated by gitlab-token-updater discover, review it before use"
+	discoverYAMLIndent  = 2
+)
+
+var (
+	ErrDiscoverNoToken = errors.New("no active access token found")
+	// discoverVarNameRe the characters that are not allowed in CICD variable name
+	discoverVarNameRe = regexp.MustCompile(`[^A-Z0-9]+`)
+	// discoverVarHints the words in CICD variable name that indicating it holds a token
+	discoverVarHints = []string{"TOKEN", "PAT"}
+)
+
+// DiscoveredToken active access token found during discovery
+type DiscoveredToken struct {
+	Name string
+	// SuggestedVars CICD variables in the same path that the name is suggesting holding the token
+	SuggestedVars []string
+}
+
+// DiscoveredManaged group or repository that has active access token(s)
+type DiscoveredManaged struct {
+	Type   string
+	Path   string
+	Tokens []DiscoveredToken
+}
+
+// Discover walk through the group, it's subgroups and projects in finding the active access tokens
+func Discover(glAPI gl.GitlabAPI, group string, recursive bool) (results []DiscoveredManaged, err error) {
+	groups := []string{group}
+	if recursive {
+		subGroups, err := glAPI.ListSubGroups(group, true)
+		if err != nil {
+			return nil, fmt.Errorf("error while listing subgroups of %s: %w", group, err)
+		}
+		groups = append(groups, subGroups...)
+	}
+
+	projects, err := glAPI.ListGroupProjects(group, recursive)
+	if err != nil {
+		return nil, fmt.Errorf("error while listing projects of %s: %w", group, err)
+	}
+
+	for _, path := range groups {
+		if discovered := discoverPath(glAPI, cfg.ManagedTypeGroup, path); discovered != nil {
+			results = append(results, *discovered)
+		}
+	}
+
+	for _, path := range projects {
+		if discovered := discoverPath(glAPI, cfg.ManagedTypeRepository, path); discovered != nil {
+			results = append(results, *discovered)
+		}
+	}
+
+	if len(results) == 0 {
+		return nil, ErrDiscoverNoToken
+	}
+	return results, nil
+}
+
+// discoverPath list the active access tokens in a group or repository, nil returned if there is none
+func discoverPath(glAPI gl.GitlabAPI, mType, path string) *DiscoveredManaged {
+	logPath := log.With().Str("path", path).Str("m_type", mType).Logger()
+	logPath.Debug().Msg("discovering access tokens")
+
+	var tokens []gl.GitlabAccessToken
+	var vars []gl.GitlabCICDVar
+	var err, errVars error
+	if mType == cfg.ManagedTypeGroup {
+		tokens, err = glAPI.ListGroupAccessToken(path)
+	} else {
+		tokens, err = glAPI.ListRepoAccessToken(path)
+	}
+	if err != nil {
+		// the access token might be not permitted to list it, just skip it
+		logPath.Warn().Err(err).Msg("error while listing access token, skip it")
+		return nil
+	}
+
+	discovered := &DiscoveredManaged{Type: mType, Path: path}
+	for _, tkn := range tokens {
+		if tkn.Revoked || !tkn.Active || discovered.hasToken(tkn.Name) {
+			continue
+		}
+		discovered.Tokens = append(discovered.Tokens, DiscoveredToken{Name: tkn.Name})
+	}
+
+	if len(discovered.Tokens) == 0 {
+		return nil
+	}
+
+	if mType == cfg.ManagedTypeGroup {
+		vars, errVars = glAPI.ListGroupVars(path)
+	} else {
+		vars, errVars = glAPI.ListRepoVars(path)
+	}
+	if errVars != nil {
+		logPath.Debug().Err(errVars).Msg("cannot list CICD variables, no hook will be suggested")
+	}
+
+	for idx := range discovered.Tokens {
+		discovered.Tokens[idx].SuggestedVars = suggestVars(discovered.Tokens[idx].Name, vars)
+	}
+	return discovered
+}
+
+func (d DiscoveredManaged) hasToken(name string) bool {
+	for _, tkn := range d.Tokens {
+		if tkn.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// suggestVars find the CICD variables which the name is containing access token name and one of token hint words
+func suggestVars(tokenName string, vars []gl.GitlabCICDVar) (suggestions []string) {
+	normalized := strings.Trim(discoverVarNameRe.ReplaceAllString(strings.ToUpper(tokenName), "_"), "_")
+	if normalized == "" {
+		return nil
+	}
+
+	for _, v := range vars {
+		key := strings.ToUpper(v.Key)
+		if !strings.Contains(key, normalized) {
+			continue
+		}
+		for _, hint := range discoverVarHints {
+			if strings.Contains(key, hint) {
+				suggestions = append(suggestions, v.Key)
+				break
+			}
+		}
+	}
+	return suggestions
+}
+
+func yamlStr(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+}
+
+func yamlMap(kv ...*yaml.Node) *yaml.Node {
+	return &yaml.Node{Kind: yaml.MappingNode, Content: kv}
+}
+
+// suggestionComment generate the commented out update_var hooks
+func (d DiscoveredManaged) suggestionComment(vars []string) (string, error) {
+	hooks := &yaml.Node{Kind: yaml.SequenceNode}
+	for _, v := range vars {
+		hooks.Content = append(hooks.Content, yamlMap(
+			yamlStr("type"), yamlStr(cfg.HookTypeUpdateVar),
+			yamlStr("args"), yamlMap(
+				yamlStr("type"), yamlStr(d.Type),
+				yamlStr("path"), yamlStr(d.Path),
+				yamlStr("name"), yamlStr(v),
+			),
+		))
+	}
+
+	var sb strings.Builder
+	enc := yaml.NewEncoder(&sb)
+	enc.SetIndent(discoverYAMLIndent)
+	if err := enc.Encode(yamlMap(yamlStr("hooks"), hooks)); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(sb.String(), "\n"), nil
+}
+
+// RenderDiscovered write the discovered access tokens as configuration, set includeOnly for generating the content of include file
+func RenderDiscovered(w io.Writer, host string, discovered []DiscoveredManaged, includeOnly bool) error {
+	managedTokens := &yaml.Node{Kind: yaml.SequenceNode}
+	for _, d := range discovered {
+		accessTokens := &yaml.Node{Kind: yaml.SequenceNode}
+		for _, tkn := range d.Tokens {
+			nameKey := yamlStr("name")
+			if len(tkn.SuggestedVars) > 0 {
+				comment, err := d.suggestionComment(tkn.SuggestedVars)
+				if err != nil {
+					return err
+				}
+				nameKey.FootComment = comment
+			}
+			accessTokens.Content = append(accessTokens.Content, yamlMap(nameKey, yamlStr(tkn.Name)))
+		}
+
+		managedTokens.Content = append(managedTokens.Content, yamlMap(
+			yamlStr("type"), yamlStr(d.Type),
+			yamlStr("path"), yamlStr(d.Path),
+			yamlStr("access_tokens"), accessTokens,
+		))
+	}
+
+	doc := managedTokens
+	if !includeOnly {
+		doc = yamlMap(
+			yamlStr("host"), yamlStr(host),
+			yamlStr("token"), yamlStr(cfg.NewConfig().Token),
+			yamlStr("manage_tokens"), managedTokens,
+		)
+	}
+	doc.HeadComment = discoverHeadComment
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(discoverYAMLIndent)
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	return enc.Close()
+}
@@ -0,0 +1,171 @@
+package app_test
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/iomarmochtar/gitlab-token-updater/app"
+	cfg "github.com/iomarmochtar/gitlab-token-updater/pkg/config"
+	gl "github.com/iomarmochtar/gitlab-token-updater/pkg/gitlab"
+	gm "github.com/iomarmochtar/gitlab-token-updater/test/mocks/gitlab"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestDiscover(t *testing.T) {
+	testCases := map[string]struct {
+		recursive      bool
+		mockGitlab     func(g *gm.MockGitlabAPI)
+		expected       []app.DiscoveredManaged
+		expectedErrMsg string
+	}{
+		"ok: recursive with inaccessible subgroup and suggested variables": {
+			recursive: true,
+			mockGitlab: func(g *gm.MockGitlabAPI) {
+				g.EXPECT().ListSubGroups("parent", true).Return([]string{"parent/sub", "parent/forbidden"}, nil)
+				g.EXPECT().ListGroupProjects("parent", true).Return([]string{"parent/sub/repo"}, nil)
+				g.EXPECT().ListGroupAccessToken("parent").Return([]gl.GitlabAccessToken{
+					{Name: "deploy bot", Active: true},
+					{Name: "revoked", Active: false, Revoked: true},
+				}, nil)
+				g.EXPECT().ListGroupVars("parent").Return([]gl.GitlabCICDVar{
+					{Key: "DEPLOY_BOT_TOKEN"}, {Key: "DEPLOY_BOT_USER"}, {Key: "OTHER_TOKEN"},
+				}, nil)
+				g.EXPECT().ListGroupAccessToken("parent/sub").Return(nil, nil)
+				g.EXPECT().ListGroupAccessToken("parent/forbidden").Return(nil, fmt.Errorf("403 Forbidden"))
+				g.EXPECT().ListRepoAccessToken("parent/sub/repo").Return([]gl.GitlabAccessToken{
+					{Name: "mr-handler", Active: true},
+					{Name: "mr-handler", Active: true},
+				}, nil)
+				g.EXPECT().ListRepoVars("parent/sub/repo").Return(nil, fmt.Errorf("403 Forbidden"))
+			},
+			expected: []app.DiscoveredManaged{
+				{
+					Type:   cfg.ManagedTypeGroup,
+					Path:   "parent",
+					Tokens: []app.DiscoveredToken{{Name: "deploy bot", SuggestedVars: []string{"DEPLOY_BOT_TOKEN"}}},
+				},
+				{
+					Type:   cfg.ManagedTypeRepository,
+					Path:   "parent/sub/repo",
+					Tokens: []app.DiscoveredToken{{Name: "mr-handler"}},
+				},
+			},
+		},
+		"err: no active access token": {
+			mockGitlab: func(g *gm.MockGitlabAPI) {
+				g.EXPECT().ListGroupProjects("parent", false).Return([]string{"parent/repo"}, nil)
+				g.EXPECT().ListGroupAccessToken("parent").Return(nil, nil)
+				g.EXPECT().ListRepoAccessToken("parent/repo").Return([]gl.GitlabAccessToken{{Name: "expired", Active: false}}, nil)
+			},
+			expectedErrMsg: app.ErrDiscoverNoToken.Error(),
+		},
+		"err: listing subgroups": {
+			recursive: true,
+			mockGitlab: func(g *gm.MockGitlabAPI) {
+				g.EXPECT().ListSubGroups("parent", true).Return(nil, fmt.Errorf("404 Not Found"))
+			},
+			expectedErrMsg: "error while listing subgroups of parent: 404 Not Found",
+		},
+		"err: listing projects": {
+			mockGitlab: func(g *gm.MockGitlabAPI) {
+				g.EXPECT().ListGroupProjects("parent", false).Return(nil, fmt.Errorf("404 Not Found"))
+			},
+			expectedErrMsg: "error while listing projects of parent: 404 Not Found",
+		},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			g := gm.NewMockGitlabAPI(ctrl)
+			tc.mockGitlab(g)
+
+			results, err := app.Discover(g, "parent", tc.recursive)
+			if tc.expectedErrMsg != "" {
+				assert.EqualError(t, err, tc.expectedErrMsg)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, results)
+		})
+	}
+}
+
+func TestRenderDiscovered(t *testing.T) {
+	t.Setenv("GL_RENEWER_TOKEN", "glpat-renewer")
+	discovered := []app.DiscoveredManaged{
+		{
+			Type: cfg.ManagedTypeGroup,
+			Path: "parent",
+			Tokens: []app.DiscoveredToken{
+				{Name: "deploy bot", SuggestedVars: []string{"DEPLOY_BOT_TOKEN", "DEPLOY_BOT_PAT"}},
+				{Name: "name: with colon"},
+			},
+		},
+		{
+			Type:   cfg.ManagedTypeRepository,
+			Path:   "parent/repo",
+			Tokens: []app.DiscoveredToken{{Name: "mr-handler"}},
+		},
+	}
+
+	t.Run("config", func(t *testing.T) {
+		buf := new(bytes.Buffer)
+		assert.NoError(t, app.RenderDiscovered(buf, "https://gitlab.example.com/", discovered, false))
+		assert.Equal(t, `# generated by gitlab-token-updater discover, review it before use
+host: https://gitlab.example.com/
+token: ${GL_RENEWER_TOKEN}
+manage_tokens:
+  - type: group
+    path: parent
+    access_tokens:
+      - name: deploy bot
+        # hooks:
+        #   - type: update_var
+        #     args:
+        #       type: group
+        #       path: parent
+        #       name: DEPLOY_BOT_TOKEN
+        #   - type: update_var
+        #     args:
+        #       type: group
+        #       path: parent
+        #       name: DEPLOY_BOT_PAT
+      - name: 'name: with colon'
+  - type: repository
+    path: parent/repo
+    access_tokens:
+      - name: mr-handler
+`, buf.String())
+
+		// the generated configuration must be readable
+		configPath := filepath.Join(t.TempDir(), "config.yml")
+		assert.NoError(t, os.WriteFile(configPath, buf.Bytes(), 0o600))
+		config, err := cfg.ReadYAMLConfigFile(configPath)
+		assert.NoError(t, err)
+		assert.Len(t, config.Managed, 2)
+		assert.Equal(t, "name: with colon", config.Managed[0].Tokens[1].Name)
+		assert.Empty(t, config.Managed[0].Tokens[0].Hooks)
+	})
+
+	t.Run("include", func(t *testing.T) {
+		buf := new(bytes.Buffer)
+		assert.NoError(t, app.RenderDiscovered(buf, "https://gitlab.example.com/", discovered, true))
+
+		dir := t.TempDir()
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "discovered.yml"), buf.Bytes(), 0o600))
+		configPath := filepath.Join(dir, "config.yml")
+		assert.NoError(t, os.WriteFile(configPath, []byte("manage_tokens:\n  - include: discovered.yml\n"), 0o600))
+
+		config, err := cfg.ReadYAMLConfigFile(configPath)
+		assert.NoError(t, err)
+		assert.Len(t, config.Managed, 2)
+		assert.Equal(t, "parent/repo", config.Managed[1].Path)
+	})
+}
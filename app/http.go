@@ -0,0 +1,84 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+
+	cfg "github.com/iomarmochtar/gitlab-token-updater/pkg/config"
+	"github.com/iomarmochtar/gitlab-token-updater/pkg/webhook"
+	"github.com/rs/zerolog"
+)
+
+var (
+	ErrHTTPUnexpectedStatus = errors.New("unexpected response status")
+	ErrHTTPProbeFailed      = errors.New("probe request failed")
+)
+
+// expectedStatus whether the status code is in the expected one, any of 2xx if it's empty
+func expectedStatus(expected []int, code int) bool {
+	if len(expected) == 0 {
+		return code >= 200 && code <= 299
+	}
+	return slices.Contains(expected, code)
+}
+
+// execHTTP send the request of http hook with the rendered body. The bodies are never written in the logs and errors, only their length,
+// as the token might be encoded by the template functions (e.g. b64enc) or echoed back by the endpoint. In dry run mode, only the probe request is sent if it's configured
+func (g GitlabTokenUpdater) execHTTP(logHook zerolog.Logger, hk cfg.Hook, data cfg.HookTemplateData) error {
+	args := hk.HTTPArgs()
+	req := webhook.Request{
+		URL:           args.URL,
+		Method:        args.Method,
+		Headers:       args.Headers,
+		BearerToken:   args.BearerToken,
+		BasicUsername: args.BasicUsername,
+		BasicPassword: args.BasicPassword,
+		TLS:           webhook.TLS{CACert: args.CACert, ClientCert: args.ClientCert, ClientKey: args.ClientKey},
+		Timeout:       args.Timeout,
+	}
+	logReq := logHook.With().Str("method", args.Method).Str("url", args.URL).Logger()
+	timeout := args.Timeout
+	if timeout <= 0 {
+		timeout = webhook.DefaultTimeout
+	}
+	ctx, cancel := g.hookContext(timeout)
+	defer cancel()
+
+	if g.dryRun {
+		if args.DryRunMethod == "" {
+			logReq.Info().Msg("would send the request")
+			return nil
+		}
+		req.Method = args.DryRunMethod
+		resp, err := webhook.Send(ctx, req)
+		if err != nil {
+			return err
+		}
+		logReq.Debug().Str("probe_method", args.DryRunMethod).Int("status", resp.StatusCode).Msg("probe request response")
+		// the probe is not necessarily supported by the endpoint, only the auth and server errors are treated as failure
+		if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden || resp.StatusCode >= 500 {
+			return fmt.Errorf("%w with status %d", ErrHTTPProbeFailed, resp.StatusCode)
+		}
+		return nil
+	}
+
+	body, err := hk.RenderBody(data)
+	if err != nil {
+		return err
+	}
+	req.Body = body
+	logReq.Debug().Int("body_length", len(body)).Msg("sending request")
+
+	resp, err := webhook.Send(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	logReq.Debug().Int("status", resp.StatusCode).Int("body_length", len(resp.Body)).Msg("request response")
+	if !expectedStatus(args.ExpectedStatus, resp.StatusCode) {
+		return fmt.Errorf("%w %d with %d bytes of body", ErrHTTPUnexpectedStatus, resp.StatusCode, len(resp.Body))
+	}
+	return nil
+}
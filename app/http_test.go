@@ -0,0 +1,151 @@
+package app_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/iomarmochtar/gitlab-token-updater/app"
+	cfg "github.com/iomarmochtar/gitlab-token-updater/pkg/config"
+	gl "github.com/iomarmochtar/gitlab-token-updater/pkg/gitlab"
+	t_helper "github.com/iomarmochtar/gitlab-token-updater/test"
+	gm "github.com/iomarmochtar/gitlab-token-updater/test/mocks/gitlab"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+type receivedRequest struct {
+	method string
+	path   string
+	auth   string
+	header string
+	body   string
+}
+
+func TestGitlabTokenUpdater_Do_HTTP(t *testing.T) {
+	newToken := "glpat-newnew"
+
+	testCases := map[string]struct {
+		args             map[string]any
+		retry            uint8
+		envVars          map[string]string
+		dryRun           bool
+		responses        []int
+		responseBody     string
+		expectedRequests []receivedRequest
+		expectedAttempts int
+		expectedHookErr  string
+	}{
+		"ok: send the rendered body with the bearer token and headers": {
+			envVars: map[string]string{"SERVICE_TOKEN": "service-secret"},
+			args: map[string]any{
+				"method":       "put",
+				"headers":      map[any]any{"X-Source": "gitlab-token-updater"},
+				"body":         `{"name":"{{ .Name }}","token":"{{ .Token }}","expires_at":"{{ .ExpiresAt.Format "2006-01-02" }}"}`,
+				"bearer_token": "${SERVICE_TOKEN}",
+			},
+			responses: []int{http.StatusNoContent},
+			expectedRequests: []receivedRequest{{
+				method: http.MethodPut, path: "/credentials", auth: "Bearer service-secret", header: "gitlab-token-updater",
+				body: `{"name":"MR Handler","token":"glpat-newnew","expires_at":"2024-07-04"}`,
+			}},
+			expectedAttempts: 1,
+		},
+		"ok: retried until the expected status": {
+			args:      map[string]any{"body": "{{ .Token }}", "basic_username": "user", "basic_password": "pass", "expected_status": []any{201}},
+			retry:     2,
+			responses: []int{http.StatusOK, http.StatusCreated},
+			expectedRequests: []receivedRequest{
+				{method: http.MethodPost, path: "/credentials", auth: "Basic dXNlcjpwYXNz", body: newToken},
+				{method: http.MethodPost, path: "/credentials", auth: "Basic dXNlcjpwYXNz", body: newToken},
+			},
+			expectedAttempts: 2,
+		},
+		"fail: unexpected status without the echoed encoded token": {
+			args:         map[string]any{"body": "{{ .Token | b64enc }}"},
+			responses:    []int{http.StatusBadRequest},
+			responseBody: "invalid token Z2xwYXQtbmV3bmV3",
+			expectedRequests: []receivedRequest{
+				{method: http.MethodPost, path: "/credentials", body: "Z2xwYXQtbmV3bmV3"},
+			},
+			expectedAttempts: 1,
+			expectedHookErr:  "unexpected response status 400 with 30 bytes of body",
+		},
+		"ok: dry run probe request": {
+			dryRun:    true,
+			args:      map[string]any{"body": "{{ .Token }}", "dry_run_method": "head"},
+			responses: []int{http.StatusMethodNotAllowed},
+			expectedRequests: []receivedRequest{
+				{method: http.MethodHead, path: "/credentials"},
+			},
+			expectedAttempts: 1,
+		},
+		"fail: dry run probe request is unauthorized": {
+			dryRun:    true,
+			args:      map[string]any{"dry_run_method": "HEAD"},
+			responses: []int{http.StatusUnauthorized},
+			expectedRequests: []receivedRequest{
+				{method: http.MethodHead, path: "/credentials"},
+			},
+			expectedAttempts: 1,
+			expectedHookErr:  "probe request failed with status 401",
+		},
+		"ok: dry run without probe request": {
+			dryRun:           true,
+			args:             map[string]any{"body": "{{ .Token }}"},
+			expectedRequests: []receivedRequest{},
+			expectedAttempts: 1,
+		},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			for k, v := range tc.envVars {
+				t.Setenv(k, v)
+			}
+
+			requests := []receivedRequest{}
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				requests = append(requests, receivedRequest{
+					method: r.Method, path: r.URL.Path, auth: r.Header.Get("Authorization"), header: r.Header.Get("X-Source"), body: string(body),
+				})
+				w.WriteHeader(tc.responses[len(requests)-1])
+				_, _ = w.Write([]byte(tc.responseBody))
+			}))
+			defer srv.Close()
+
+			args := map[string]any{"url": srv.URL + "/credentials"}
+			for k, v := range tc.args {
+				args[k] = v
+			}
+			config := t_helper.GenConfig(nil, nil, nil)
+			config.Managed[0].Tokens[0].Hooks = []cfg.Hook{{Type: cfg.HookTypeHTTP, Retry: tc.retry, Args: args}}
+			assert.NoError(t, config.InitValues())
+
+			g := gm.NewMockGitlabAPI(ctrl)
+			g.EXPECT().ListRepoAccessToken(t_helper.SampleRepoPath).Return([]gl.GitlabAccessToken{t_helper.SampleRepoAccessToken}, nil)
+			if !tc.dryRun {
+				g.EXPECT().RotateRepoToken(t_helper.SampleRepoPath, 123, *t_helper.GenTime("2024-07-04")).Return(newToken, nil)
+			}
+
+			updater := app.NewGitlabTokenUpdater(config, g, nil).
+				WithCustomCurrentTime(t_helper.GenTime("2024-04-05")).
+				WithDryRun(tc.dryRun)
+			err := updater.Do()
+
+			assert.Equal(t, tc.expectedRequests, requests)
+			hkReport := updater.Report().Managed[0].Tokens[0].Hooks[0]
+			assert.Equal(t, tc.expectedAttempts, hkReport.Attempts)
+			assert.Equal(t, tc.expectedHookErr, hkReport.Error)
+			if tc.expectedHookErr != "" {
+				assert.ErrorIs(t, err, app.ErrDuringExecution)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
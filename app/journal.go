@@ -0,0 +1,97 @@
+package app
+
+import (
+	"time"
+
+	cfg "github.com/iomarmochtar/gitlab-token-updater/pkg/config"
+	"github.com/iomarmochtar/gitlab-token-updater/pkg/journal"
+	"github.com/rs/zerolog/log"
+)
+
+const runIDFormat = "20060102T150405.000Z"
+
+// journalOf the journal of the execution, nil if it's not configured
+func (g *GitlabTokenUpdater) journalOf() *journal.Journal {
+	jc := g.config.Journal
+	if jc == nil {
+		return nil
+	}
+	if g.journal != nil {
+		return g.journal
+	}
+
+	var backends []journal.Backend
+	if jc.File != "" {
+		backends = append(backends, journal.File{Path: jc.File})
+	}
+	if jc.GitlabVar != nil {
+		backends = append(backends, journal.Rewritten{Store: gitlabVarBackend{glAPI: g.glAPI, args: *jc.GitlabVar}})
+	}
+	g.journal = journal.New(time.Now().UTC().Format(runIDFormat), backends...)
+	return g.journal
+}
+
+// record append the records to the journal if it's configured, the failure is only logged so it's not interrupting the execution
+func (g *GitlabTokenUpdater) record(records ...journal.Record) {
+	jr := g.journalOf()
+	if jr == nil || g.dryRun {
+		return
+	}
+	if err := jr.Append(records...); err != nil {
+		log.Error().Err(err).Msg("error in writing the journal")
+	}
+}
+
+// tokenRecord the journal record of the access token
+func tokenRecord(step string, at accessTokenPair) journal.Record {
+	return journal.Record{
+		Step: step,
+		Type: managedType(at.glAccessToken.Type),
+		Path: at.glAccessToken.Path,
+		Name: at.cfgAccessToken.Name,
+	}
+}
+
+// recordRotated record the rotated access token along with it's hooks, so the outstanding ones are known in resuming
+func (g *GitlabTokenUpdater) recordRotated(at accessTokenPair) {
+	r := tokenRecord(journal.StepRotated, at)
+	expiresAt := g.nextExpiry(at)
+	r.ExpiresAt = &expiresAt
+	for _, hk := range at.cfgAccessToken.Hooks {
+		r.Hooks = append(r.Hooks, hk.Type)
+	}
+	g.record(r)
+}
+
+// recordHook record the result of the hook execution by it's sequence number
+func (g *GitlabTokenUpdater) recordHook(at accessTokenPair, seq int, hk cfg.Hook, err error) {
+	r := tokenRecord(journal.StepHookSucceeded, at)
+	r.Hook = seq
+	r.HookType = hk.Type
+	if err != nil {
+		r.Step = journal.StepHookFailed
+		r.Error = err.Error()
+	}
+	g.record(r)
+}
+
+// warnOutstanding log the access tokens that have outstanding hooks in the journal, they are not retried by the normal execution
+func (g *GitlabTokenUpdater) warnOutstanding() {
+	jr := g.journalOf()
+	if jr == nil {
+		return
+	}
+	records, err := jr.Read()
+	if err != nil {
+		log.Error().Err(err).Msg("error in reading the journal")
+		return
+	}
+	for _, p := range journal.Outstanding(records) {
+		log.Warn().
+			Str("path", p.Rotated.Path).
+			Str("m_type", p.Rotated.Type).
+			Str("token", p.Rotated.Name).
+			Ints("hooks", p.Outstanding()).
+			Msg("outstanding hooks of the previous execution, complete them by resume command")
+	}
+}
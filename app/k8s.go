@@ -0,0 +1,92 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	cfg "github.com/iomarmochtar/gitlab-token-updater/pkg/config"
+	"github.com/iomarmochtar/gitlab-token-updater/pkg/k8s"
+	"github.com/rs/zerolog"
+)
+
+// ErrK8sNotPermitted the current Kubernetes user is not allowed to do the action that is required by k8s_secret hook
+var ErrK8sNotPermitted = errors.New("not permitted by kubernetes")
+
+// K8sInitFunc initiate the Kubernetes API by it's client config
+type K8sInitFunc func(k8s.ClientConfig) (k8s.KubernetesAPI, error)
+
+// k8sMustPermitted check the permission of the verb in the resource, error if it's not allowed
+func k8sMustPermitted(k8sAPI k8s.KubernetesAPI, verb, resource, namespace, name string) error {
+	allowed, err := k8sAPI.CanI(verb, resource, namespace, name)
+	if err != nil {
+		return err
+	} else if !allowed {
+		return fmt.Errorf("%w: %s %s %s in namespace %s", ErrK8sNotPermitted, verb, resource, name, namespace)
+	}
+	return nil
+}
+
+// checkK8sSecret the dry run of k8s_secret hook, ensuring the secret exists and the required permissions are granted
+func checkK8sSecret(k8sAPI k8s.KubernetesAPI, args cfg.HookK8sSecret, logSecret zerolog.Logger) error {
+	data, err := k8sAPI.GetSecret(args.Namespace, args.Name)
+	if errors.Is(err, k8s.ErrNotFound) && args.CreateIfMissing {
+		logSecret.Warn().Msg("would create the missing secret")
+		if err = k8sMustPermitted(k8sAPI, "create", "secrets", args.Namespace, ""); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return fmt.Errorf("secret %s in namespace %s: %w", args.Name, args.Namespace, err)
+	} else {
+		if _, exists := data[args.Key]; !exists {
+			logSecret.Warn().Msg("would add the missing key to the secret")
+		}
+		if err = k8sMustPermitted(k8sAPI, "patch", "secrets", args.Namespace, args.Name); err != nil {
+			return err
+		}
+	}
+
+	for _, deployment := range args.RolloutRestart {
+		if err = k8sMustPermitted(k8sAPI, "patch", "deployments", args.Namespace, deployment); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateK8sSecret update the key of Kubernetes secret then rollout restart the deployments if they are set.
+// In dry run mode, only checking the existence of the secret and the permissions
+func (g GitlabTokenUpdater) updateK8sSecret(logHook zerolog.Logger, args cfg.HookK8sSecret, value string) error {
+	k8sAPI, err := g.k8sInit(k8s.ClientConfig{
+		Kubeconfig: args.Kubeconfig,
+		Context:    args.Context,
+		InCluster:  args.InCluster,
+	})
+	if err != nil {
+		return err
+	}
+
+	logSecret := logHook.With().Str("namespace", args.Namespace).Str("secret", args.Name).Str("key", args.Key).Logger()
+	if g.dryRun {
+		return checkK8sSecret(k8sAPI, args, logSecret)
+	}
+
+	data := map[string][]byte{args.Key: []byte(value)}
+	err = k8sAPI.PatchSecret(args.Namespace, args.Name, data)
+	if errors.Is(err, k8s.ErrNotFound) && args.CreateIfMissing {
+		logSecret.Warn().Msg("creating the missing secret")
+		err = k8sAPI.CreateSecret(args.Namespace, args.Name, data)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, deployment := range args.RolloutRestart {
+		logSecret.Info().Str("deployment", deployment).Msg("rollout restart deployment")
+		// not the run start time, the same annotation of the next restart in the run is a no-op that keeps the pods with the revoked token
+		if err = k8sAPI.RestartDeployment(args.Namespace, deployment, time.Now()); err != nil {
+			return fmt.Errorf("rollout restart deployment %s: %w", deployment, err)
+		}
+	}
+	return nil
+}
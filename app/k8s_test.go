@@ -0,0 +1,180 @@
+package app_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/iomarmochtar/gitlab-token-updater/app"
+	cfg "github.com/iomarmochtar/gitlab-token-updater/pkg/config"
+	gl "github.com/iomarmochtar/gitlab-token-updater/pkg/gitlab"
+	"github.com/iomarmochtar/gitlab-token-updater/pkg/k8s"
+	t_helper "github.com/iomarmochtar/gitlab-token-updater/test"
+	gm "github.com/iomarmochtar/gitlab-token-updater/test/mocks/gitlab"
+	km "github.com/iomarmochtar/gitlab-token-updater/test/mocks/k8s"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestGitlabTokenUpdater_Do_K8sSecret(t *testing.T) {
+	newToken := "glpat-newnew"
+	now := t_helper.GenTime("2024-04-05")
+
+	testCases := map[string]struct {
+		args           map[string]any
+		dryRun         bool
+		mockK8s        func(k *km.MockKubernetesAPI)
+		expectedClient k8s.ClientConfig
+		expectedErrMsg string
+	}{
+		"ok: patch the secret key then rollout restart the deployments": {
+			args: map[string]any{
+				"kubeconfig": "/path/to/kubeconfig", "context": "prod",
+				"namespace": "app", "name": "gitlab", "key": "token",
+				"rollout_restart": []any{"web", "worker"},
+			},
+			mockK8s: func(k *km.MockKubernetesAPI) {
+				gomock.InOrder(
+					k.EXPECT().PatchSecret("app", "gitlab", map[string][]byte{"token": []byte(newToken)}).Return(nil),
+					k.EXPECT().RestartDeployment("app", "web", gomock.Any()).Return(nil),
+					k.EXPECT().RestartDeployment("app", "worker", gomock.Any()).Return(nil),
+				)
+			},
+			expectedClient: k8s.ClientConfig{Kubeconfig: "/path/to/kubeconfig", Context: "prod"},
+		},
+		"ok: create the missing secret with the rendered template": {
+			args: map[string]any{
+				"in_cluster": true, "namespace": "app", "name": "gitlab", "key": ".netrc",
+				"create_if_missing": true, "template": "machine {{ .Hostname }} password {{ .Token }}",
+			},
+			mockK8s: func(k *km.MockKubernetesAPI) {
+				k.EXPECT().PatchSecret("app", "gitlab", gomock.Any()).Return(k8s.ErrNotFound)
+				k.EXPECT().CreateSecret("app", "gitlab", map[string][]byte{".netrc": []byte("machine gitlab.com password " + newToken)}).Return(nil)
+			},
+			expectedClient: k8s.ClientConfig{InCluster: true},
+		},
+		"fail: the missing secret is not created": {
+			args: map[string]any{"namespace": "app", "name": "gitlab", "key": "token", "rollout_restart": "web"},
+			mockK8s: func(k *km.MockKubernetesAPI) {
+				k.EXPECT().PatchSecret("app", "gitlab", gomock.Any()).Return(k8s.ErrNotFound)
+			},
+			expectedErrMsg: "some error(s) occured during execution",
+		},
+		"fail: rollout restart": {
+			args: map[string]any{"namespace": "app", "name": "gitlab", "key": "token", "rollout_restart": "web"},
+			mockK8s: func(k *km.MockKubernetesAPI) {
+				k.EXPECT().PatchSecret("app", "gitlab", gomock.Any()).Return(nil)
+				k.EXPECT().RestartDeployment("app", "web", gomock.Any()).Return(errors.New("forbidden"))
+			},
+			expectedErrMsg: "some error(s) occured during execution",
+		},
+		"ok: dry run verify the secret and permissions": {
+			dryRun: true,
+			args:   map[string]any{"namespace": "app", "name": "gitlab", "key": "token", "rollout_restart": "web"},
+			mockK8s: func(k *km.MockKubernetesAPI) {
+				k.EXPECT().GetSecret("app", "gitlab").Return(map[string][]byte{"token": []byte("old")}, nil)
+				k.EXPECT().CanI("patch", "secrets", "app", "gitlab").Return(true, nil)
+				k.EXPECT().CanI("patch", "deployments", "app", "web").Return(true, nil)
+			},
+		},
+		"ok: dry run verify the create permission of the missing secret": {
+			dryRun: true,
+			args:   map[string]any{"namespace": "app", "name": "gitlab", "key": "token", "create_if_missing": true},
+			mockK8s: func(k *km.MockKubernetesAPI) {
+				k.EXPECT().GetSecret("app", "gitlab").Return(nil, k8s.ErrNotFound)
+				k.EXPECT().CanI("create", "secrets", "app", "").Return(true, nil)
+			},
+		},
+		"fail: dry run without update permission": {
+			dryRun: true,
+			args:   map[string]any{"namespace": "app", "name": "gitlab", "key": "token"},
+			mockK8s: func(k *km.MockKubernetesAPI) {
+				k.EXPECT().GetSecret("app", "gitlab").Return(map[string][]byte{}, nil)
+				k.EXPECT().CanI("patch", "secrets", "app", "gitlab").Return(false, nil)
+			},
+			expectedErrMsg: "some error(s) occured during execution",
+		},
+		"fail: dry run with the missing secret": {
+			dryRun: true,
+			args:   map[string]any{"namespace": "app", "name": "gitlab", "key": "token"},
+			mockK8s: func(k *km.MockKubernetesAPI) {
+				k.EXPECT().GetSecret("app", "gitlab").Return(nil, k8s.ErrNotFound)
+			},
+			expectedErrMsg: "some error(s) occured during execution",
+		},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			config := t_helper.GenConfig(nil, nil, nil)
+			config.Managed[0].Tokens[0].Hooks = []cfg.Hook{{Type: cfg.HookTypeK8sSecret, Args: tc.args}}
+			assert.NoError(t, config.InitValues())
+
+			g := gm.NewMockGitlabAPI(ctrl)
+			g.EXPECT().ListRepoAccessToken(t_helper.SampleRepoPath).Return([]gl.GitlabAccessToken{t_helper.SampleRepoAccessToken}, nil)
+			if !tc.dryRun {
+				g.EXPECT().RotateRepoToken(t_helper.SampleRepoPath, 123, *t_helper.GenTime("2024-07-04")).Return(newToken, nil)
+			}
+
+			k := km.NewMockKubernetesAPI(ctrl)
+			tc.mockK8s(k)
+			k8sInit := func(clientCfg k8s.ClientConfig) (k8s.KubernetesAPI, error) {
+				assert.Equal(t, tc.expectedClient, clientCfg)
+				return k, nil
+			}
+
+			err := app.NewGitlabTokenUpdater(config, g, nil).
+				WithCustomCurrentTime(now).
+				WithDryRun(tc.dryRun).
+				WithK8sInit(k8sInit).
+				Do()
+			if tc.expectedErrMsg != "" {
+				assert.EqualError(t, err, tc.expectedErrMsg)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestGitlabTokenUpdater_Do_K8sSecretRestartTwice(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// both of the rotated tokens are restarting the same deployment in the same run
+	hooks := []cfg.Hook{{Type: cfg.HookTypeK8sSecret, Args: map[string]any{"namespace": "app", "name": "gitlab", "key": "token", "rollout_restart": "web"}}}
+	config := t_helper.GenConfig(nil, nil, nil)
+	config.Managed = []cfg.ManagedToken{
+		{Type: cfg.ManagedTypeRepository, Path: "/path/to/first", Tokens: []cfg.AccessToken{{Name: "MR Handler", RenewBefore: "1M", Hooks: hooks}}},
+		{Type: cfg.ManagedTypeRepository, Path: "/path/to/second", Tokens: []cfg.AccessToken{{Name: "MR Handler", RenewBefore: "1M", Hooks: hooks}}},
+	}
+	assert.NoError(t, config.InitValues())
+
+	g := gm.NewMockGitlabAPI(ctrl)
+	for _, path := range []string{"/path/to/first", "/path/to/second"} {
+		token := t_helper.SampleRepoAccessToken
+		token.Path = path
+		g.EXPECT().ListRepoAccessToken(path).Return([]gl.GitlabAccessToken{token}, nil)
+		g.EXPECT().RotateRepoToken(path, 123, gomock.Any()).Return("glpat-newnew", nil)
+	}
+
+	var restartedAt []time.Time
+	k := km.NewMockKubernetesAPI(ctrl)
+	k.EXPECT().PatchSecret("app", "gitlab", gomock.Any()).Return(nil).Times(2)
+	k.EXPECT().RestartDeployment("app", "web", gomock.Any()).DoAndReturn(func(_, _ string, at time.Time) error {
+		restartedAt = append(restartedAt, at)
+		return nil
+	}).Times(2)
+
+	err := app.NewGitlabTokenUpdater(config, g, nil).
+		WithCustomCurrentTime(t_helper.GenTime("2024-04-05")).
+		WithK8sInit(func(k8s.ClientConfig) (k8s.KubernetesAPI, error) { return k, nil }).
+		Do()
+	assert.NoError(t, err)
+	if assert.Len(t, restartedAt, 2) {
+		assert.NotEqual(t, restartedAt[0].Format(time.RFC3339Nano), restartedAt[1].Format(time.RFC3339Nano))
+	}
+}
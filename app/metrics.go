@@ -0,0 +1,234 @@
+package app
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	MetricsPath = "/metrics"
+
+	metricsNamespace   = "gltu"
+	metricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+	metricsFileMode    = 0o644
+
+	rotationResultSuccess = "success"
+	rotationResultFailure = "failure"
+)
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+type metricsTokenKey struct {
+	path  string
+	mType string
+	name  string
+}
+
+type metricsRotationKey struct {
+	metricsTokenKey
+	result string
+}
+
+// Metrics collect the execution results as prometheus metrics, the counters are accumulated across the executions
+type Metrics struct {
+	mu              sync.Mutex
+	expires         map[metricsTokenKey]time.Time
+	rotations       map[metricsRotationKey]int
+	hookExecutions  map[string]int
+	hookFailures    map[string]int
+	runs            int
+	lastRunDuration time.Duration
+	lastRunAt       time.Time
+	lastRunSuccess  bool
+}
+
+// Observe record the execution report, the expiry of tokens are replaced with the latest one
+func (m *Metrics) Observe(r *Report, duration time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.runs++
+	m.lastRunDuration = duration
+	m.lastRunAt = r.ExecutedAt
+	m.lastRunSuccess = err == nil
+	m.expires = map[metricsTokenKey]time.Time{}
+
+	for _, mr := range r.Managed {
+		for _, tr := range mr.Tokens {
+			key := metricsTokenKey{path: mr.Path, mType: mr.Type, name: tr.Name}
+			switch {
+			case tr.NewExpiresAt != nil:
+				m.expires[key] = *tr.NewExpiresAt
+				if tr.Status != TokenStatusCreated {
+					m.rotations[metricsRotationKey{key, rotationResultSuccess}]++
+				}
+			case tr.OldExpiresAt != nil:
+				m.expires[key] = *tr.OldExpiresAt
+				if tr.Status == TokenStatusFailed {
+					m.rotations[metricsRotationKey{key, rotationResultFailure}]++
+				}
+			}
+
+			for _, hk := range tr.Hooks {
+				m.hookExecutions[hk.Type]++
+				if !hk.Success {
+					m.hookFailures[hk.Type]++
+				}
+			}
+		}
+	}
+}
+
+func labels(kv ...string) string {
+	pairs := make([]string, 0, len(kv)/2)
+	for i := 0; i+1 < len(kv); i += 2 {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, kv[i], labelValueReplacer.Replace(kv[i+1])))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// metricsWriter write the metrics in prometheus text exposition format, the first error is kept
+type metricsWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+func (mw *metricsWriter) printf(format string, a ...any) {
+	if mw.err != nil {
+		return
+	}
+	n, err := fmt.Fprintf(mw.w, format, a...)
+	mw.n += int64(n)
+	mw.err = err
+}
+
+func (mw *metricsWriter) header(name, mType, help string) {
+	mw.printf("# HELP %s_%s %s\n# TYPE %s_%s %s\n", metricsNamespace, name, help, metricsNamespace, name, mType)
+}
+
+func (mw *metricsWriter) sample(name, labels string, value float64) {
+	mw.printf("%s_%s%s %s\n", metricsNamespace, name, labels, strconv.FormatFloat(value, 'f', -1, 64))
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// sortedKeys return the keys of map that are sorted by it's labels, so the metrics output is stable
+func sortedKeys[K comparable, V any](m map[K]V, label func(K) string) []K {
+	keys := make([]K, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return label(keys[i]) < label(keys[j]) })
+	return keys
+}
+
+func (k metricsTokenKey) labels() string {
+	return labels("path", k.path, "type", k.mType, "name", k.name)
+}
+
+func (k metricsRotationKey) labels() string {
+	return labels("path", k.path, "type", k.mType, "name", k.name, "result", k.result)
+}
+
+// WriteTo write the metrics in prometheus text exposition format
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	mw := &metricsWriter{w: w}
+
+	mw.header("token_expires_timestamp", "gauge", "Expiry of the access token in unix timestamp.")
+	for _, k := range sortedKeys(m.expires, metricsTokenKey.labels) {
+		mw.sample("token_expires_timestamp", k.labels(), float64(m.expires[k].Unix()))
+	}
+
+	mw.header("rotations_total", "counter", "Total of the access token rotations by result.")
+	for _, k := range sortedKeys(m.rotations, metricsRotationKey.labels) {
+		mw.sample("rotations_total", k.labels(), float64(m.rotations[k]))
+	}
+
+	hookLabels := func(hookType string) string { return labels("hook_type", hookType) }
+	mw.header("hook_executions_total", "counter", "Total of the hook executions, the retries are not counted.")
+	for _, k := range sortedKeys(m.hookExecutions, hookLabels) {
+		mw.sample("hook_executions_total", hookLabels(k), float64(m.hookExecutions[k]))
+	}
+
+	mw.header("hook_failures_total", "counter", "Total of the failed hook executions after all of the retries.")
+	for _, k := range sortedKeys(m.hookFailures, hookLabels) {
+		mw.sample("hook_failures_total", hookLabels(k), float64(m.hookFailures[k]))
+	}
+
+	mw.header("runs_total", "counter", "Total of the executions.")
+	mw.sample("runs_total", "", float64(m.runs))
+
+	if m.runs > 0 {
+		mw.header("last_run_duration_seconds", "gauge", "Duration of the last execution.")
+		mw.sample("last_run_duration_seconds", "", m.lastRunDuration.Seconds())
+		mw.header("last_run_timestamp", "gauge", "Time of the last execution in unix timestamp.")
+		mw.sample("last_run_timestamp", "", float64(m.lastRunAt.Unix()))
+		mw.header("last_run_success", "gauge", "Whether the last execution is succeed without any error.")
+		mw.sample("last_run_success", "", boolValue(m.lastRunSuccess))
+	}
+	return mw.n, mw.err
+}
+
+// ServeHTTP metrics endpoint handler
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", metricsContentType)
+	if _, err := m.WriteTo(w); err != nil {
+		log.Error().Err(err).Msg("error while writing metrics")
+	}
+}
+
+// WriteTextfile write the metrics into file for node exporter textfile collector,
+// it's written into temporary file first then renamed so the collector never reads a partial file
+func (m *Metrics) WriteTextfile(path string) (err error) {
+	path = filepath.Clean(path)
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("error while create metrics file: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = os.Remove(f.Name())
+		}
+	}()
+
+	if _, err = m.WriteTo(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	//nolint:gosec // it must be readable by node exporter
+	if err = os.Chmod(f.Name(), metricsFileMode); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// NewMetrics create Metrics with it's default values
+func NewMetrics() *Metrics {
+	return &Metrics{
+		expires:        map[metricsTokenKey]time.Time{},
+		rotations:      map[metricsRotationKey]int{},
+		hookExecutions: map[string]int{},
+		hookFailures:   map[string]int{},
+	}
+}
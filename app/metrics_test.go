@@ -0,0 +1,138 @@
+package app_test
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/iomarmochtar/gitlab-token-updater/app"
+	cfg "github.com/iomarmochtar/gitlab-token-updater/pkg/config"
+	t_helper "github.com/iomarmochtar/gitlab-token-updater/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func sampleMetricsReport() *app.Report {
+	return &app.Report{
+		ExecutedAt: *t_helper.GenTime("2024-04-05"),
+		Managed: []*app.ManagedReport{
+			{
+				Path: t_helper.SampleRepoPath,
+				Type: cfg.ManagedTypeRepository,
+				Tokens: []*app.TokenReport{
+					{
+						Name:         "renewed",
+						Status:       app.TokenStatusFailed,
+						OldExpiresAt: t_helper.GenTime("2024-05-01"),
+						NewExpiresAt: t_helper.GenTime("2024-07-04"),
+						Hooks: []*app.HookReport{
+							{Type: cfg.HookTypeUpdateVar, Attempts: 1, Success: true},
+							{Type: cfg.HookTypeExecCMD, Attempts: 3, Success: false},
+						},
+					},
+					{Name: "failed", Status: app.TokenStatusFailed, OldExpiresAt: t_helper.GenTime("2024-04-10")},
+					{Name: `skipped "quoted"`, Status: app.TokenStatusSkipped, OldExpiresAt: t_helper.GenTime("2025-01-01")},
+					{Name: "not exists", Status: app.TokenStatusNotFound},
+				},
+			},
+			{Path: t_helper.SampleGroupPath, Type: cfg.ManagedTypeGroup, Error: "error in listing"},
+		},
+	}
+}
+
+func TestMetrics_WriteTo(t *testing.T) {
+	t.Run("without any execution", func(t *testing.T) {
+		buf := new(bytes.Buffer)
+		_, err := app.NewMetrics().WriteTo(buf)
+		assert.NoError(t, err)
+		assert.Contains(t, buf.String(), "gltu_runs_total 0\n")
+		assert.NotContains(t, buf.String(), "gltu_last_run_success")
+	})
+
+	t.Run("counters are accumulated", func(t *testing.T) {
+		metrics := app.NewMetrics()
+		metrics.Observe(sampleMetricsReport(), 2*time.Second, nil)
+		metrics.Observe(sampleMetricsReport(), 1500*time.Millisecond, errors.New("some error(s) occured during execution"))
+
+		buf := new(bytes.Buffer)
+		n, err := metrics.WriteTo(buf)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(buf.Len()), n)
+		assert.Equal(t, `# HELP gltu_token_expires_timestamp Expiry of the access token in unix timestamp.
+# TYPE gltu_token_expires_timestamp gauge
+gltu_token_expires_timestamp{path="/path/to/repo",type="repository",name="failed"} 1712707200
+gltu_token_expires_timestamp{path="/path/to/repo",type="repository",name="renewed"} 1720051200
+gltu_token_expires_timestamp{path="/path/to/repo",type="repository",name="skipped \"quoted\""} 1735689600
+# HELP gltu_rotations_total Total of the access token rotations by result.
+# TYPE gltu_rotations_total counter
+gltu_rotations_total{path="/path/to/repo",type="repository",name="failed",result="failure"} 2
+gltu_rotations_total{path="/path/to/repo",type="repository",name="renewed",result="success"} 2
+# HELP gltu_hook_executions_total Total of the hook executions, the retries are not counted.
+# TYPE gltu_hook_executions_total counter
+gltu_hook_executions_total{hook_type="exec_cmd"} 2
+gltu_hook_executions_total{hook_type="update_var"} 2
+# HELP gltu_hook_failures_total Total of the failed hook executions after all of the retries.
+# TYPE gltu_hook_failures_total counter
+gltu_hook_failures_total{hook_type="exec_cmd"} 2
+# HELP gltu_runs_total Total of the executions.
+# TYPE gltu_runs_total counter
+gltu_runs_total 2
+# HELP gltu_last_run_duration_seconds Duration of the last execution.
+# TYPE gltu_last_run_duration_seconds gauge
+gltu_last_run_duration_seconds 1.5
+# HELP gltu_last_run_timestamp Time of the last execution in unix timestamp.
+# TYPE gltu_last_run_timestamp gauge
+gltu_last_run_timestamp 1712275200
+# HELP gltu_last_run_success Whether the last execution is succeed without any error.
+# TYPE gltu_last_run_success gauge
+gltu_last_run_success 0
+`, buf.String())
+	})
+
+	t.Run("expiry of removed token is dropped", func(t *testing.T) {
+		metrics := app.NewMetrics()
+		metrics.Observe(sampleMetricsReport(), time.Second, nil)
+		metrics.Observe(&app.Report{}, time.Second, nil)
+
+		buf := new(bytes.Buffer)
+		_, err := metrics.WriteTo(buf)
+		assert.NoError(t, err)
+		assert.NotContains(t, buf.String(), "gltu_token_expires_timestamp{")
+		assert.Contains(t, buf.String(), `gltu_rotations_total{path="/path/to/repo",type="repository",name="renewed",result="success"} 1`)
+	})
+}
+
+func TestMetrics_ServeHTTP(t *testing.T) {
+	metrics := app.NewMetrics()
+	metrics.Observe(sampleMetricsReport(), time.Second, nil)
+
+	rec := httptest.NewRecorder()
+	metrics.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, app.MetricsPath, nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Body.String(), "gltu_last_run_success 1\n")
+}
+
+func TestMetrics_WriteTextfile(t *testing.T) {
+	metrics := app.NewMetrics()
+	metrics.Observe(sampleMetricsReport(), time.Second, nil)
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "gltu.prom")
+	assert.NoError(t, metrics.WriteTextfile(path))
+
+	content, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), "gltu_runs_total 1\n")
+
+	// no leftover of temporary file
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	assert.ErrorContains(t, metrics.WriteTextfile(filepath.Join(dir, "not_exists", "gltu.prom")), "error while create metrics file")
+}
@@ -0,0 +1,109 @@
+package app
+
+import (
+	"fmt"
+	"slices"
+
+	cfg "github.com/iomarmochtar/gitlab-token-updater/pkg/config"
+	"github.com/iomarmochtar/gitlab-token-updater/pkg/notify"
+	"github.com/rs/zerolog"
+)
+
+// notifyEvent the notified event of the processed access token, empty if it's not notified
+func notifyEvent(tknReport *TokenReport) string {
+	switch tknReport.Status {
+	case TokenStatusRenewed, TokenStatusCreated, TokenStatusRecreated:
+		return cfg.NotifyOnRotated
+	case TokenStatusFailed:
+		return cfg.NotifyOnFailed
+	}
+	return ""
+}
+
+// notifyErrors the errors of the failed hooks, or the access token error if none of the hooks are failed
+func notifyErrors(tknReport *TokenReport) (errs []string) {
+	for _, hkReport := range tknReport.Hooks {
+		if hkReport.Error != "" {
+			errs = append(errs, fmt.Sprintf("hook %s: %s", hkReport.Type, hkReport.Error))
+		}
+	}
+	if len(errs) == 0 && tknReport.Error != "" {
+		errs = append(errs, tknReport.Error)
+	}
+	return errs
+}
+
+// newNotifier the notifier of the notify config
+func newNotifier(nt cfg.Notify) notify.Notifier {
+	switch nt.Type {
+	case cfg.NotifyTypeSlack:
+		return notify.Slack{WebhookURL: nt.WebhookURL()}
+	case cfg.NotifyTypeTeams:
+		return notify.Teams{WebhookURL: nt.WebhookURL()}
+	}
+	args := nt.EmailArgs()
+	return notify.Email{
+		Host:     args.Host,
+		Port:     args.Port,
+		TLS:      args.TLS,
+		Username: args.Username,
+		Password: args.Password,
+		From:     args.From,
+		To:       args.To,
+	}
+}
+
+// notify send the notifications of the access token and the global one, the message is rendered from the report so it never contains the token.
+// The failure of non fatal notification is only logged, otherwise the access token is marked as failed
+func (g *GitlabTokenUpdater) notify(logPath zerolog.Logger, mg cfg.ManagedToken, tkn cfg.AccessToken, tknReport *TokenReport) error {
+	event := notifyEvent(tknReport)
+	if event == "" {
+		return nil
+	}
+
+	data := cfg.NotifyTemplateData{
+		Event:     event,
+		Name:      tkn.Name,
+		Path:      mg.Path,
+		Type:      mg.Type,
+		Status:    tknReport.Status,
+		ExpiresAt: tknReport.NewExpiresAt,
+		Errors:    notifyErrors(tknReport),
+		Host:      g.config.Host,
+	}
+	for _, nt := range append(slices.Clone(tkn.Notify), g.config.Notify...) {
+		if !nt.Notified(event) {
+			continue
+		}
+
+		logNotify := logPath.With().Str("token", tkn.Name).Str("notify_type", nt.Type).Str("event", event).Logger()
+		if g.dryRun {
+			logNotify.Info().Msg("would send notification")
+			continue
+		}
+
+		subject, message, err := nt.Render(data)
+		if err == nil {
+			// the notification of the interrupted execution is still sent, it's when the operators need it the most
+			ctx, cancel := g.hookContext(notify.DefaultTimeout)
+			err = newNotifier(nt).Send(ctx, notify.Message{Subject: subject, Text: message})
+			cancel()
+		}
+		if err == nil {
+			logNotify.Info().Msg("notification sent")
+			continue
+		}
+
+		err = fmt.Errorf("error in sending %s notification: %w", nt.Type, err)
+		if nt.NonFatal {
+			logNotify.Warn().Err(err).Msg("non fatal notification failure")
+			continue
+		}
+		logNotify.Error().Err(err).Msg("notification failure")
+		tknReport.fail(err)
+		if err = g.errAppender(err); err != nil {
+			return err
+		}
+	}
+	return nil
+}
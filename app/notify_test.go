@@ -0,0 +1,152 @@
+package app_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/iomarmochtar/gitlab-token-updater/app"
+	cfg "github.com/iomarmochtar/gitlab-token-updater/pkg/config"
+	gl "github.com/iomarmochtar/gitlab-token-updater/pkg/gitlab"
+	t_helper "github.com/iomarmochtar/gitlab-token-updater/test"
+	gm "github.com/iomarmochtar/gitlab-token-updater/test/mocks/gitlab"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestGitlabTokenUpdater_Do_Notify(t *testing.T) {
+	newToken := "glpat-newnew"
+
+	testCases := map[string]struct {
+		tokenNotify      []cfg.Notify
+		globalNotify     []cfg.Notify
+		dryRun           bool
+		rotateErr        error
+		status           int
+		expectedTexts    []string
+		expectedTknError string
+		expectedErr      bool
+	}{
+		"ok: rotated token is notified with the custom message": {
+			tokenNotify: []cfg.Notify{{
+				Type:    cfg.NotifyTypeSlack,
+				Message: `{{ .Name }} in {{ .Path }} expires at {{ .ExpiresAt.Format "2006-01-02" }}`,
+			}},
+			status:        http.StatusOK,
+			expectedTexts: []string{"*[gitlab-token-updater] access token MR Handler is rotated*\nMR Handler in /path/to/repo expires at 2024-07-04"},
+		},
+		"ok: global notify for the failed event only is skipped": {
+			tokenNotify:   []cfg.Notify{{Type: cfg.NotifyTypeSlack, Message: "{{ .Event }}"}},
+			globalNotify:  []cfg.Notify{{Type: cfg.NotifyTypeSlack, On: []string{cfg.NotifyOnFailed}, Message: "{{ .Event }}"}},
+			status:        http.StatusOK,
+			expectedTexts: []string{"*[gitlab-token-updater] access token MR Handler is rotated*\nrotated"},
+		},
+		"ok: failed rotation is notified with the errors": {
+			globalNotify:     []cfg.Notify{{Type: cfg.NotifyTypeSlack, Subject: "{{ .Status }}"}},
+			rotateErr:        errors.New("rotation error"),
+			status:           http.StatusOK,
+			expectedTexts:    []string{"*failed*\naccess token MR Handler (repository: /path/to/repo) is failed\n- rotation error"},
+			expectedTknError: "rotation error",
+			expectedErr:      true,
+		},
+		"ok: non fatal notify failure": {
+			tokenNotify:   []cfg.Notify{{Type: cfg.NotifyTypeSlack, Message: "{{ .Event }}", NonFatal: true}},
+			status:        http.StatusInternalServerError,
+			expectedTexts: []string{"*[gitlab-token-updater] access token MR Handler is rotated*\nrotated"},
+		},
+		"fail: notify failure mark the token as failed": {
+			tokenNotify:      []cfg.Notify{{Type: cfg.NotifyTypeSlack, Message: "{{ .Event }}"}},
+			status:           http.StatusInternalServerError,
+			expectedTexts:    []string{"*[gitlab-token-updater] access token MR Handler is rotated*\nrotated"},
+			expectedTknError: "error in sending slack notification: incoming webhook responded with status 500: down",
+			expectedErr:      true,
+		},
+		"ok: dry run is not sending the notification": {
+			tokenNotify:   []cfg.Notify{{Type: cfg.NotifyTypeSlack}},
+			dryRun:        true,
+			expectedTexts: []string{},
+		},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			texts := []string{}
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				payload := map[string]string{}
+				require.NoError(t, json.Unmarshal(body, &payload))
+				assert.NotContains(t, payload["text"], newToken)
+				texts = append(texts, payload["text"])
+				w.WriteHeader(tc.status)
+				_, _ = w.Write([]byte("down"))
+			}))
+			defer srv.Close()
+
+			withURL := func(notifies []cfg.Notify) []cfg.Notify {
+				for idx := range notifies {
+					notifies[idx].Args = map[string]any{"webhook_url": srv.URL}
+				}
+				return notifies
+			}
+			config := t_helper.GenConfig(nil, nil, nil)
+			config.Managed[0].Tokens[0].Hooks = nil
+			config.Managed[0].Tokens[0].Notify = withURL(tc.tokenNotify)
+			config.Notify = withURL(tc.globalNotify)
+			assert.NoError(t, config.InitValues())
+
+			g := gm.NewMockGitlabAPI(ctrl)
+			g.EXPECT().ListRepoAccessToken(t_helper.SampleRepoPath).Return([]gl.GitlabAccessToken{t_helper.SampleRepoAccessToken}, nil)
+			if !tc.dryRun {
+				g.EXPECT().RotateRepoToken(t_helper.SampleRepoPath, 123, *t_helper.GenTime("2024-07-04")).Return(newToken, tc.rotateErr)
+			}
+
+			updater := app.NewGitlabTokenUpdater(config, g, nil).
+				WithCustomCurrentTime(t_helper.GenTime("2024-04-05")).
+				WithDryRun(tc.dryRun)
+			err := updater.Do()
+
+			assert.Equal(t, tc.expectedTexts, texts)
+			assert.Equal(t, tc.expectedTknError, updater.Report().Managed[0].Tokens[0].Error)
+			if tc.expectedErr {
+				assert.ErrorIs(t, err, app.ErrDuringExecution)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestGitlabTokenUpdater_Do_NotifyEmail(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	srv := t_helper.NewSMTPServer(t)
+	config := t_helper.GenConfig(nil, nil, nil)
+	config.Managed[0].Tokens[0].Hooks = nil
+	config.Notify = []cfg.Notify{{
+		Type: cfg.NotifyTypeEmail,
+		Args: map[string]any{"host": srv.Host, "port": srv.Port, "tls": cfg.NotifyEmailTLSNone, "from": "bot@example.com", "to": "team@example.com"},
+	}}
+	assert.NoError(t, config.InitValues())
+
+	g := gm.NewMockGitlabAPI(ctrl)
+	g.EXPECT().ListRepoAccessToken(t_helper.SampleRepoPath).Return([]gl.GitlabAccessToken{t_helper.SampleRepoAccessToken}, nil)
+	g.EXPECT().RotateRepoToken(t_helper.SampleRepoPath, 123, *t_helper.GenTime("2024-07-04")).Return("glpat-newnew", nil)
+
+	updater := app.NewGitlabTokenUpdater(config, g, nil).WithCustomCurrentTime(t_helper.GenTime("2024-04-05"))
+	assert.NoError(t, updater.Do())
+
+	messages := srv.Messages()
+	require.Len(t, messages, 1)
+	assert.Equal(t, []string{"team@example.com"}, messages[0].To)
+	assert.Contains(t, messages[0].Data, "Subject: [gitlab-token-updater] access token MR Handler is rotated\r\n")
+	assert.Contains(t, messages[0].Data, "access token MR Handler (repository: /path/to/repo) is rotated, the new expiry is 2024-07-04")
+	assert.NotContains(t, messages[0].Data, "glpat-newnew")
+}
@@ -0,0 +1,276 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+
+	cfg "github.com/iomarmochtar/gitlab-token-updater/pkg/config"
+	"github.com/iomarmochtar/gitlab-token-updater/pkg/journal"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	PlanVersion        = 1
+	PlanActionRotate   = "rotate"
+	PlanActionCreate   = "create"
+	PlanActionRecreate = "recreate"
+)
+
+var (
+	ErrPlanInvalidVersion = fmt.Errorf("unsupported plan version, the supported one is %d", PlanVersion)
+	ErrPlanDrifted        = errors.New("live state is drifted since planning")
+)
+
+// PlannedHook the hook that is going to be executed along with it's resolved arguments, the secret ones are masked
+type PlannedHook struct {
+	Type string         `json:"type"`
+	Args map[string]any `json:"args"`
+}
+
+// PlannedToken the access token that is going to be rotated, created or recreated
+type PlannedToken struct {
+	Path   string `json:"path"`
+	Type   string `json:"type"`
+	Name   string `json:"name"`
+	Action string `json:"action"`
+	// ID and ExpiresAt the live state in planning, they are empty for the missing one
+	ID           int           `json:"id,omitempty"`
+	ExpiresAt    *time.Time    `json:"expires_at,omitempty"`
+	NewExpiresAt time.Time     `json:"new_expires_at"`
+	Hooks        []PlannedHook `json:"hooks"`
+}
+
+// Plan the reviewable plan of the execution, only the planned access tokens are processed in applying it
+type Plan struct {
+	Version   int            `json:"version"`
+	CreatedAt time.Time      `json:"created_at"`
+	Host      string         `json:"host"`
+	Force     bool           `json:"force"`
+	Tokens    []PlannedToken `json:"tokens"`
+}
+
+// planTarget the live state of the planned access tokens in a managed token
+type planTarget struct {
+	mg  cfg.ManagedToken
+	ats []accessTokenPair
+}
+
+// plannedHooks the hooks of the access token with their resolved arguments
+func plannedHooks(tkn cfg.AccessToken) []PlannedHook {
+	hooks := make([]PlannedHook, 0, len(tkn.Hooks))
+	for _, hk := range tkn.Hooks {
+		hooks = append(hooks, PlannedHook{Type: hk.Type, Args: hk.ResolvedArgs()})
+	}
+	return hooks
+}
+
+// liveAction the action of the access token regardless of it's renew time
+func liveAction(at accessTokenPair) string {
+	switch {
+	case at.recreate:
+		return PlanActionRecreate
+	case at.missing:
+		return PlanActionCreate
+	}
+	return PlanActionRotate
+}
+
+// plannedAction the action of the access token as evaluated by the main execution, empty if nothing is going to be done
+func (g *GitlabTokenUpdater) plannedAction(at accessTokenPair) string {
+	if at.missing {
+		return liveAction(at)
+	}
+	if _, validToRenew := g.renewInfo(at); g.forceRenew || validToRenew {
+		return PlanActionRotate
+	}
+	return ""
+}
+
+// Plan evaluate the selected access tokens as the main execution does, no any write execution will be made
+func (g *GitlabTokenUpdater) Plan() (*Plan, error) {
+	plan := &Plan{Version: PlanVersion, CreatedAt: *g.now, Host: g.config.Host, Force: g.forceRenew, Tokens: []PlannedToken{}}
+	err := g.listSelected(func(mg cfg.ManagedToken, ats []accessTokenPair) {
+		for _, at := range ats {
+			action := g.plannedAction(at)
+			if action == "" {
+				continue
+			}
+
+			pt := PlannedToken{
+				Path:         mg.Path,
+				Type:         mg.Type,
+				Name:         at.cfgAccessToken.Name,
+				Action:       action,
+				NewExpiresAt: g.nextExpiry(at),
+				Hooks:        plannedHooks(at.cfgAccessToken),
+			}
+			if action != PlanActionCreate {
+				pt.ID = at.glAccessToken.ID
+				pt.ExpiresAt = at.glAccessToken.ExpiresAt
+			}
+			log.Info().Str("path", mg.Path).Str("m_type", mg.Type).Str("token", pt.Name).Str("action", action).Msg("planned")
+			plan.Tokens = append(plan.Tokens, pt)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return plan, g.collectedErrors()
+}
+
+// drift the difference between the planned access token and it's live state, empty if there is none
+func drift(pt PlannedToken, at *accessTokenPair) string {
+	if at == nil {
+		return "is not exists"
+	}
+	if action := liveAction(*at); action != pt.Action {
+		return fmt.Sprintf("is going to be %s instead of %s", action, pt.Action)
+	}
+	if pt.Action == PlanActionCreate {
+		return ""
+	}
+
+	expiresAt := at.glAccessToken.ExpiresAt
+	switch {
+	case pt.ID != at.glAccessToken.ID:
+		return fmt.Sprintf("id is changed from %d to %d", pt.ID, at.glAccessToken.ID)
+	case (pt.ExpiresAt == nil) != (expiresAt == nil) || (expiresAt != nil && !pt.ExpiresAt.Equal(*expiresAt)):
+		return fmt.Sprintf("expiry is changed from %s to %s", fmtStatusDate(pt.ExpiresAt), fmtStatusDate(expiresAt))
+	}
+
+	// the resolved arguments are compared in JSON as the planned one is decoded from it
+	planned, _ := json.Marshal(pt.Hooks)
+	live, _ := json.Marshal(plannedHooks(at.cfgAccessToken))
+	if !bytes.Equal(planned, live) {
+		return "hooks are changed"
+	}
+	return ""
+}
+
+// planTargets the live state of the planned access tokens, all of the drifted ones are returned as error
+func (g *GitlabTokenUpdater) planTargets(plan *Plan) (targets []planTarget, err error) {
+	var drifts []error
+	managed := make([]bool, len(plan.Tokens))
+	for _, mg := range g.config.Managed {
+		var planned []PlannedToken
+		selected := mg
+		selected.Tokens = nil
+		for idx, pt := range plan.Tokens {
+			if pt.Type != mg.Type || pt.Path != mg.Path {
+				continue
+			}
+			if tknIdx := slices.IndexFunc(mg.Tokens, func(tkn cfg.AccessToken) bool { return tkn.Name == pt.Name }); tknIdx >= 0 {
+				managed[idx] = true
+				planned = append(planned, pt)
+				selected.Tokens = append(selected.Tokens, mg.Tokens[tknIdx])
+			}
+		}
+		if len(planned) == 0 {
+			continue
+		}
+
+		ats, err := g.listAccessTokens(selected)
+		if err != nil {
+			return nil, err
+		}
+
+		target := planTarget{mg: mg}
+		for _, pt := range planned {
+			var at *accessTokenPair
+			if atIdx := slices.IndexFunc(ats, func(at accessTokenPair) bool { return at.cfgAccessToken.Name == pt.Name }); atIdx >= 0 {
+				at = &ats[atIdx]
+			}
+			if reason := drift(pt, at); reason != "" {
+				drifts = append(drifts, fmt.Errorf("%w: token %s in %s %s", ErrPlanDrifted, pt.Name, pt.Path, reason))
+				continue
+			}
+
+			// the new expiry is as planned regardless of the applying time
+			at.expiresAt = &pt.NewExpiresAt
+			target.ats = append(target.ats, *at)
+		}
+		targets = append(targets, target)
+	}
+
+	for idx, pt := range plan.Tokens {
+		if !managed[idx] {
+			drifts = append(drifts, fmt.Errorf("%w: token %s in %s is not managed in the config", ErrPlanDrifted, pt.Name, pt.Path))
+		}
+	}
+	return targets, errors.Join(drifts...)
+}
+
+// applyToken create or rotate the planned access token then executing it's hooks
+func (g *GitlabTokenUpdater) applyToken(logPath zerolog.Logger, at accessTokenPair, tknReport *TokenReport) error {
+	logTkn := logPath.With().Str("token", at.cfgAccessToken.Name).Logger()
+	logTkn.Info().Msg("applying")
+
+	if at.missing {
+		return g.createToken(logTkn, at, tknReport)
+	}
+
+	tknReport.ID = at.glAccessToken.ID
+	tknReport.OldExpiresAt = at.glAccessToken.ExpiresAt
+	return g.renewToken(logTkn, at, tknReport)
+}
+
+// Apply execute only the planned access tokens, nothing is executed if any of them is drifted since planning
+func (g *GitlabTokenUpdater) Apply(plan *Plan) error {
+	if plan.Version != PlanVersion {
+		return fmt.Errorf("%w: %d", ErrPlanInvalidVersion, plan.Version)
+	}
+	if plan.Host != g.config.Host {
+		return fmt.Errorf("%w: host is changed from %s to %s", ErrPlanDrifted, plan.Host, g.config.Host)
+	}
+
+	targets, err := g.planTargets(plan)
+	if err != nil {
+		return err
+	}
+
+	g.record(journal.Record{Step: journal.StepRunStarted})
+	for _, target := range targets {
+		mg := target.mg
+		logPath := log.With().Str("path", mg.Path).Str("m_type", mg.Type).Logger()
+		mgReport := g.report.addManaged(mg.Path, mg.Type)
+		for _, at := range target.ats {
+			if g.interrupted() {
+				return ErrInterrupted
+			}
+
+			tknReport := mgReport.addToken(at.cfgAccessToken.Name)
+			err = g.applyToken(logPath, at, tknReport)
+			if notifyErr := g.notify(logPath, mg, at.cfgAccessToken, tknReport); err == nil {
+				err = notifyErr
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	g.record(journal.Record{Step: journal.StepRunFinished})
+	log.Info().Msg("done")
+	return g.collectedErrors()
+}
+
+// WritePlan write the plan in JSON format
+func WritePlan(w io.Writer, plan *Plan) error {
+	return writeJSON(w, plan)
+}
+
+// ReadPlan read the plan in JSON format
+func ReadPlan(r io.Reader) (*Plan, error) {
+	var plan Plan
+	if err := json.NewDecoder(r).Decode(&plan); err != nil {
+		return nil, fmt.Errorf("invalid plan: %w", err)
+	}
+	return &plan, nil
+}
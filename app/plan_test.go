@@ -0,0 +1,174 @@
+package app_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/iomarmochtar/gitlab-token-updater/app"
+	cfg "github.com/iomarmochtar/gitlab-token-updater/pkg/config"
+	gl "github.com/iomarmochtar/gitlab-token-updater/pkg/gitlab"
+	t_helper "github.com/iomarmochtar/gitlab-token-updater/test"
+	gm "github.com/iomarmochtar/gitlab-token-updater/test/mocks/gitlab"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestGitlabTokenUpdater_Plan(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	config := t_helper.GenConfig(nil, []cfg.AccessToken{
+		{Name: "CI Reader", RenewBefore: "1M"},
+		{Name: "Deployer", CreateIfMissing: true, Scopes: []string{"api"}},
+	}, nil)
+	assert.NoError(t, config.InitValues())
+
+	ciReader := t_helper.SampleRepoAccessToken
+	ciReader.Name, ciReader.ID, ciReader.ExpiresAt = "CI Reader", 456, t_helper.GenTime("2024-12-01")
+	g := gm.NewMockGitlabAPI(ctrl)
+	g.EXPECT().ListRepoAccessToken(t_helper.SampleRepoPath).Return([]gl.GitlabAccessToken{t_helper.SampleRepoAccessToken, ciReader}, nil)
+
+	plan, err := app.NewGitlabTokenUpdater(config, g, nil).WithCustomCurrentTime(t_helper.GenTime("2024-04-05")).Plan()
+	require.NoError(t, err)
+	assert.Equal(t, &app.Plan{
+		Version:   app.PlanVersion,
+		CreatedAt: *t_helper.GenTime("2024-04-05"),
+		Host:      config.Host,
+		Tokens: []app.PlannedToken{
+			{
+				Path:         t_helper.SampleRepoPath,
+				Type:         cfg.ManagedTypeRepository,
+				Name:         t_helper.SampleAccessTokeName,
+				Action:       app.PlanActionRotate,
+				ID:           123,
+				ExpiresAt:    t_helper.GenTime("2024-05-01"),
+				NewExpiresAt: *t_helper.GenTime("2024-07-04"),
+				Hooks: []app.PlannedHook{
+					{Type: cfg.HookTypeUpdateVar, Args: map[string]any{"name": t_helper.SampleCICDVar, "path": t_helper.SampleRepoPath, "type": cfg.ManagedTypeRepository}},
+				},
+			},
+			{
+				Path:         t_helper.SampleRepoPath,
+				Type:         cfg.ManagedTypeRepository,
+				Name:         "Deployer",
+				Action:       app.PlanActionCreate,
+				NewExpiresAt: *t_helper.GenTime("2024-07-04"),
+				Hooks:        []app.PlannedHook{},
+			},
+		},
+	}, plan)
+}
+
+func TestGitlabTokenUpdater_Apply(t *testing.T) {
+	newToken := "glpat-newnew"
+
+	testCases := map[string]struct {
+		modifyPlan     func(p *app.Plan)
+		modifyConfig   func(c *cfg.Config)
+		listed         func(tkn *gl.GitlabAccessToken)
+		expectedErr    error
+		expectedErrMsg string
+	}{
+		"ok: planned token is rotated with the planned expiry": {},
+		"err: id is changed": {
+			listed:         func(tkn *gl.GitlabAccessToken) { tkn.ID = 124 },
+			expectedErr:    app.ErrPlanDrifted,
+			expectedErrMsg: "token MR Handler in /path/to/repo id is changed from 123 to 124",
+		},
+		"err: expiry is changed": {
+			listed:         func(tkn *gl.GitlabAccessToken) { tkn.ExpiresAt = t_helper.GenTime("2024-07-01") },
+			expectedErr:    app.ErrPlanDrifted,
+			expectedErrMsg: "token MR Handler in /path/to/repo expiry is changed from 2024-05-01 to 2024-07-01",
+		},
+		"err: token is revoked": {
+			listed:         func(tkn *gl.GitlabAccessToken) { tkn.Revoked = true },
+			expectedErr:    app.ErrPlanDrifted,
+			expectedErrMsg: "token MR Handler in /path/to/repo is not exists",
+		},
+		"err: hooks are changed": {
+			modifyConfig: func(c *cfg.Config) {
+				c.Managed[0].Tokens[0].Hooks[0] = t_helper.SampleHookUpdateVarGroup
+			},
+			listed:         func(_ *gl.GitlabAccessToken) {},
+			expectedErr:    app.ErrPlanDrifted,
+			expectedErrMsg: "token MR Handler in /path/to/repo hooks are changed",
+		},
+		"err: token is not managed anymore": {
+			modifyPlan:     func(p *app.Plan) { p.Tokens[0].Path = "/path/to/another" },
+			expectedErr:    app.ErrPlanDrifted,
+			expectedErrMsg: "token MR Handler in /path/to/another is not managed in the config",
+		},
+		"err: host is changed": {
+			modifyPlan:     func(p *app.Plan) { p.Host = "https://gitlab.example.com/" },
+			expectedErr:    app.ErrPlanDrifted,
+			expectedErrMsg: "host is changed from https://gitlab.example.com/ to https://gitlab.com/",
+		},
+		"err: unsupported version": {
+			modifyPlan:  func(p *app.Plan) { p.Version = 2 },
+			expectedErr: app.ErrPlanInvalidVersion,
+		},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			// the plan is read from it's written file
+			var buf bytes.Buffer
+			require.NoError(t, app.WritePlan(&buf, &app.Plan{
+				Version: app.PlanVersion,
+				Host:    "https://gitlab.com/",
+				Tokens: []app.PlannedToken{{
+					Path:         t_helper.SampleRepoPath,
+					Type:         cfg.ManagedTypeRepository,
+					Name:         t_helper.SampleAccessTokeName,
+					Action:       app.PlanActionRotate,
+					ID:           123,
+					ExpiresAt:    t_helper.GenTime("2024-05-01"),
+					NewExpiresAt: *t_helper.GenTime("2024-07-04"),
+					Hooks:        []app.PlannedHook{{Type: cfg.HookTypeUpdateVar, Args: t_helper.SampleHookUpdateVarRepo.ResolvedArgs()}},
+				}},
+			}))
+			plan, err := app.ReadPlan(&buf)
+			require.NoError(t, err)
+			if tc.modifyPlan != nil {
+				tc.modifyPlan(plan)
+			}
+
+			config := t_helper.GenConfig(nil, nil, nil)
+			if tc.modifyConfig != nil {
+				tc.modifyConfig(config)
+			}
+			assert.NoError(t, config.InitValues())
+
+			g := gm.NewMockGitlabAPI(ctrl)
+			listed := t_helper.SampleRepoAccessToken
+			switch {
+			case tc.listed != nil:
+				tc.listed(&listed)
+				g.EXPECT().ListRepoAccessToken(t_helper.SampleRepoPath).Return([]gl.GitlabAccessToken{listed}, nil)
+			case tc.expectedErr == nil:
+				g.EXPECT().ListRepoAccessToken(t_helper.SampleRepoPath).Return([]gl.GitlabAccessToken{listed}, nil)
+				g.EXPECT().RotateRepoToken(t_helper.SampleRepoPath, 123, *t_helper.GenTime("2024-07-04")).Return(newToken, nil)
+				g.EXPECT().UpdateRepoVar(t_helper.SampleRepoPath, t_helper.SampleCICDVar, "", newToken, gl.GitlabCICDVarAttrs{}).Return(nil)
+			}
+
+			// applied later than the planning, but the new expiry is still as planned
+			updater := app.NewGitlabTokenUpdater(config, g, nil).WithCustomCurrentTime(t_helper.GenTime("2024-04-10"))
+			err = updater.Apply(plan)
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+				assert.ErrorContains(t, err, tc.expectedErrMsg)
+				assert.Empty(t, updater.Report().Managed)
+				return
+			}
+
+			require.NoError(t, err)
+			tknReport := updater.Report().Managed[0].Tokens[0]
+			assert.Equal(t, app.TokenStatusRenewed, tknReport.Status)
+			assert.Equal(t, t_helper.GenTime("2024-07-04"), tknReport.NewExpiresAt)
+		})
+	}
+}
@@ -0,0 +1,222 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+
+	"filippo.io/age"
+	cfg "github.com/iomarmochtar/gitlab-token-updater/pkg/config"
+	gl "github.com/iomarmochtar/gitlab-token-updater/pkg/gitlab"
+	"github.com/iomarmochtar/gitlab-token-updater/pkg/recovery"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	TokenStatusRecovered = "recovered"
+)
+
+var (
+	ErrRecoveryNotConfigured = errors.New("recovery is not configured")
+	ErrRecoverNoEntry        = errors.New("no recovery entry is found for the selected access tokens")
+)
+
+// gitlabVarBackend the recovery and journal backend of the dedicated CICD variable, it's created as protected and raw one if it's not exists
+type gitlabVarBackend struct {
+	glAPI gl.GitlabAPI
+	args  cfg.GitlabVarBackend
+}
+
+func (b gitlabVarBackend) Name() string {
+	return "gitlab_var"
+}
+
+func (b gitlabVarBackend) Read() ([]byte, error) {
+	getVar, _, _ := cicdVarAPI(b.glAPI, b.args.Type)
+	cicdVar, err := getVar(b.args.Path, b.args.Name, "")
+	if errors.Is(err, gl.ErrNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return []byte(cicdVar.Value), nil
+}
+
+func (b gitlabVarBackend) Write(content []byte) error {
+	enabled := true
+	attrs := gl.GitlabCICDVarAttrs{Protected: &enabled, Raw: &enabled}
+	_, updateVar, createVar := cicdVarAPI(b.glAPI, b.args.Type)
+	err := updateVar(b.args.Path, b.args.Name, "", string(content), attrs)
+	if errors.Is(err, gl.ErrNotFound) {
+		return createVar(b.args.Path, b.args.Name, string(content), attrs)
+	}
+	return err
+}
+
+// newRecoveryStore the recovery store of the configured backends, the file one is preferred in reading the entry
+func (g *GitlabTokenUpdater) newRecoveryStore() (*recovery.Store, error) {
+	rc := g.config.Recovery
+	var backends []recovery.Backend
+	if rc.File != "" {
+		backends = append(backends, recovery.File{Path: rc.File})
+	}
+	if rc.GitlabVar != nil {
+		backends = append(backends, gitlabVarBackend{glAPI: g.glAPI, args: *rc.GitlabVar})
+	}
+	return recovery.NewStore(rc.Recipients, backends...)
+}
+
+// recoveryEntry the recovery entry of the rotated access token
+func (g *GitlabTokenUpdater) recoveryEntry(at accessTokenPair) recovery.Entry {
+	return recovery.Entry{
+		Type:      managedType(at.glAccessToken.Type),
+		Path:      at.glAccessToken.Path,
+		Name:      at.cfgAccessToken.Name,
+		ID:        at.glAccessToken.ID,
+		ExpiresAt: g.nextExpiry(at),
+		RotatedAt: *g.now,
+	}
+}
+
+// clearRecovery remove the entry of the access token that all of it's hooks are succeeded, the failure is only logged as the token is already distributed
+func (g *GitlabTokenUpdater) clearRecovery(logTkn zerolog.Logger, store *recovery.Store, key string) {
+	g.storeMu.Lock()
+	defer g.storeMu.Unlock()
+	if err := store.Delete(key); err != nil {
+		logTkn.Warn().Err(err).Msg("error in clearing the recovery entry")
+		return
+	}
+	logTkn.Debug().Msg("recovery entry is cleared")
+}
+
+// execHooksRecoverable keep the new token in recovery store then executing the hooks, the entry is cleared once all of the hooks are succeeded.
+// The hooks are still executed if the token can't be kept, since the old one is already invalidated
+func (g *GitlabTokenUpdater) execHooksRecoverable(logTkn zerolog.Logger, at accessTokenPair, newToken string, tknReport *TokenReport) error {
+	if g.config.Recovery == nil || g.dryRun {
+		return g.execHooks(logTkn, at, newToken, tknReport)
+	}
+
+	entry := g.recoveryEntry(at)
+	store, recoveryErr := g.newRecoveryStore()
+	if recoveryErr == nil {
+		g.storeMu.Lock()
+		recoveryErr = store.Put(entry, newToken)
+		g.storeMu.Unlock()
+	}
+	if recoveryErr != nil {
+		logTkn.Error().Err(recoveryErr).Msg("error in keeping the new token in recovery store, continue executing the hooks")
+		recoveryErr = fmt.Errorf("error in keeping token %s of %s in recovery store: %w", entry.Name, entry.Path, recoveryErr)
+	} else {
+		logTkn.Debug().Msg("new token is kept in recovery store")
+	}
+
+	if err := g.execHooks(logTkn, at, newToken, tknReport); err != nil {
+		if recoveryErr == nil {
+			logTkn.Warn().Msg("the new token is kept in recovery store, replay the hooks by recover command")
+		}
+		return errors.Join(err, recoveryErr)
+	}
+
+	switch {
+	case tknReport.Status == TokenStatusFailed && recoveryErr == nil:
+		logTkn.Warn().Msg("the new token is kept in recovery store, replay the hooks by recover command")
+	case tknReport.Status != TokenStatusFailed && store != nil:
+		// the entry might be kept partially in some of the backends
+		g.clearRecovery(logTkn, store, entry.Key())
+	}
+	return g.errAppender(recoveryErr)
+}
+
+// findRecoveryEntry the recovery entry of the access token in the managed token, the personal one is matched regardless of it's path
+func findRecoveryEntry(entries []recovery.Entry, mg cfg.ManagedToken, name string) *recovery.Entry {
+	for _, entry := range entries {
+		if entry.Type == mg.Type && entry.Name == name && (entry.Path == mg.Path || mg.Type == cfg.ManagedTypePersonal) {
+			return &entry
+		}
+	}
+	return nil
+}
+
+// recoverToken decrypt the kept token then replay the hooks of the access token except the done ones, in dry run mode the dummy token is used instead
+func (g *GitlabTokenUpdater) recoverToken(store *recovery.Store, identities []age.Identity, entry recovery.Entry, tkn cfg.AccessToken, doneHooks map[int]bool, status string, tknReport *TokenReport) error {
+	logTkn := log.With().Str("path", entry.Path).Str("m_type", entry.Type).Str("token", entry.Name).Logger()
+	tknReport.ID = entry.ID
+	tknReport.NewExpiresAt = &entry.ExpiresAt
+
+	newToken, err := entry.Decrypt(identities...)
+	if err != nil {
+		logTkn.Error().Err(err).Msg("error in decrypting the kept token")
+		tknReport.fail(err)
+		return g.errAppender(err)
+	}
+	if g.dryRun {
+		newToken = dryRunDommyToken
+	}
+
+	logTkn.Info().Time("rotated_at", entry.RotatedAt).Msg("replaying the hooks by the kept token")
+	tknReport.Status = status
+	at := accessTokenPair{
+		glAccessToken:  gl.GitlabAccessToken{ID: entry.ID, Name: entry.Name, Path: entry.Path, Type: targetType(entry.Type)},
+		cfgAccessToken: tkn,
+		expiresAt:      &entry.ExpiresAt,
+		doneHooks:      doneHooks,
+	}
+	if err = g.execHooks(logTkn, at, newToken, tknReport); err != nil {
+		return err
+	}
+
+	if tknReport.Status != TokenStatusFailed && !g.dryRun {
+		g.clearRecovery(logTkn, store, entry.Key())
+	}
+	return nil
+}
+
+// Recover replay the hooks of the selected access tokens by the kept token in recovery store, the entry is cleared once all of the hooks are succeeded
+func (g *GitlabTokenUpdater) Recover(identities []age.Identity) error {
+	if g.config.Recovery == nil {
+		return ErrRecoveryNotConfigured
+	}
+
+	store, err := g.newRecoveryStore()
+	if err != nil {
+		return err
+	}
+	entries, err := store.List()
+	if err != nil {
+		return err
+	}
+
+	recovered := 0
+	for _, mg := range g.config.Managed {
+		selected, isSelected := g.selector.filter(mg)
+		if !isSelected {
+			continue
+		}
+
+		var mgReport *ManagedReport
+		for _, tkn := range selected.Tokens {
+			if g.interrupted() {
+				return ErrInterrupted
+			}
+
+			entry := findRecoveryEntry(entries, mg, tkn.Name)
+			if entry == nil {
+				continue
+			}
+			recovered++
+
+			if mgReport == nil {
+				mgReport = g.report.addManaged(mg.Path, mg.Type)
+			}
+			if err = g.recoverToken(store, identities, *entry, tkn, nil, TokenStatusRecovered, mgReport.addToken(tkn.Name)); err != nil {
+				return err
+			}
+		}
+	}
+
+	if recovered == 0 {
+		return ErrRecoverNoEntry
+	}
+	log.Info().Msg("done")
+	return g.collectedErrors()
+}
@@ -0,0 +1,219 @@
+package app_test
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"filippo.io/age"
+	"github.com/iomarmochtar/gitlab-token-updater/app"
+	cfg "github.com/iomarmochtar/gitlab-token-updater/pkg/config"
+	gl "github.com/iomarmochtar/gitlab-token-updater/pkg/gitlab"
+	"github.com/iomarmochtar/gitlab-token-updater/pkg/recovery"
+	t_helper "github.com/iomarmochtar/gitlab-token-updater/test"
+	gm "github.com/iomarmochtar/gitlab-token-updater/test/mocks/gitlab"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+var sampleRecoveryKey = recovery.Key(cfg.ManagedTypeRepository, t_helper.SampleRepoPath, t_helper.SampleAccessTokeName)
+
+func TestGitlabTokenUpdater_Do_Recovery(t *testing.T) {
+	newToken := "glpat-newnew"
+	opsPath, recoveryVar := "/path/to/ops", "GL_TOKEN_RECOVERY"
+	recoveryAttrs := gl.GitlabCICDVarAttrs{Protected: t_helper.Ptr(true), Raw: t_helper.Ptr(true)}
+
+	testCases := map[string]struct {
+		gitlabVar      bool
+		mockGitlab     func(g *gm.MockGitlabAPI)
+		expectedKept   bool
+		expectedStatus string
+		expectedErrs   []string
+	}{
+		"ok: entry is cleared once the hooks are succeeded": {
+			mockGitlab: func(g *gm.MockGitlabAPI) {
+				g.EXPECT().UpdateRepoVar(t_helper.SampleRepoPath, t_helper.SampleCICDVar, "", newToken, gl.GitlabCICDVarAttrs{}).Return(nil)
+			},
+			expectedStatus: app.TokenStatusRenewed,
+			expectedErrs:   []string{},
+		},
+		"ok: entry is kept when the hooks are failed": {
+			mockGitlab: func(g *gm.MockGitlabAPI) {
+				g.EXPECT().UpdateRepoVar(t_helper.SampleRepoPath, t_helper.SampleCICDVar, "", newToken, gl.GitlabCICDVarAttrs{}).Return(errors.New("403 Forbidden"))
+			},
+			expectedKept:   true,
+			expectedStatus: app.TokenStatusFailed,
+			expectedErrs:   []string{"403 Forbidden"},
+		},
+		"ok: entry is kept in the created CICD variable as well": {
+			gitlabVar: true,
+			mockGitlab: func(g *gm.MockGitlabAPI) {
+				var kept string
+				g.EXPECT().GetGroupVar(opsPath, recoveryVar, "").Return(nil, gl.ErrNotFound)
+				g.EXPECT().UpdateGroupVar(opsPath, recoveryVar, "", gomock.Any(), recoveryAttrs).Return(gl.ErrNotFound)
+				g.EXPECT().CreateGroupVar(opsPath, recoveryVar, gomock.Any(), recoveryAttrs).
+					DoAndReturn(func(_, _, value string, _ gl.GitlabCICDVarAttrs) error {
+						kept = value
+						return nil
+					})
+				g.EXPECT().UpdateRepoVar(t_helper.SampleRepoPath, t_helper.SampleCICDVar, "", newToken, gl.GitlabCICDVarAttrs{}).Return(nil)
+				g.EXPECT().GetGroupVar(opsPath, recoveryVar, "").DoAndReturn(func(_, _, _ string) (*gl.GitlabCICDVar, error) {
+					assert.Contains(t, kept, sampleRecoveryKey)
+					assert.NotContains(t, kept, newToken)
+					return &gl.GitlabCICDVar{Key: recoveryVar, Value: kept}, nil
+				})
+				g.EXPECT().UpdateGroupVar(opsPath, recoveryVar, "", "{}", recoveryAttrs).Return(nil)
+			},
+			expectedStatus: app.TokenStatusRenewed,
+			expectedErrs:   []string{},
+		},
+		"err: hooks are executed even the token can't be kept in all of the backends": {
+			gitlabVar: true,
+			mockGitlab: func(g *gm.MockGitlabAPI) {
+				g.EXPECT().GetGroupVar(opsPath, recoveryVar, "").Return(nil, errors.New("401 Unauthorized")).Times(2)
+				g.EXPECT().UpdateRepoVar(t_helper.SampleRepoPath, t_helper.SampleCICDVar, "", newToken, gl.GitlabCICDVarAttrs{}).Return(nil)
+			},
+			expectedStatus: app.TokenStatusRenewed,
+			expectedErrs: []string{
+				"error in keeping token MR Handler of /path/to/repo in recovery store: recovery backend gitlab_var: 401 Unauthorized",
+			},
+		},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			identity, err := age.GenerateX25519Identity()
+			require.NoError(t, err)
+			recoveryFile := filepath.Join(t.TempDir(), "recovery.json")
+			config := t_helper.GenConfig(nil, nil, nil)
+			config.Recovery = &cfg.Recovery{Recipients: []string{identity.Recipient().String()}, File: recoveryFile}
+			if tc.gitlabVar {
+				config.Recovery.GitlabVar = &cfg.GitlabVarBackend{Type: cfg.ManagedTypeGroup, Path: opsPath, Name: recoveryVar}
+			}
+			assert.NoError(t, config.InitValues())
+
+			g := gm.NewMockGitlabAPI(ctrl)
+			g.EXPECT().ListRepoAccessToken(t_helper.SampleRepoPath).Return([]gl.GitlabAccessToken{t_helper.SampleRepoAccessToken}, nil)
+			g.EXPECT().RotateRepoToken(t_helper.SampleRepoPath, 123, *t_helper.GenTime("2024-07-04")).Return(newToken, nil)
+			tc.mockGitlab(g)
+
+			updater := app.NewGitlabTokenUpdater(config, g, nil).WithCustomCurrentTime(t_helper.GenTime("2024-04-05"))
+			err = updater.Do()
+			report := updater.Report()
+			assert.Equal(t, tc.expectedStatus, report.Managed[0].Tokens[0].Status)
+			assert.Equal(t, tc.expectedErrs, report.Errors)
+			if len(tc.expectedErrs) > 0 {
+				assert.ErrorIs(t, err, app.ErrDuringExecution)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			store, err := recovery.NewStore(config.Recovery.Recipients, recovery.File{Path: recoveryFile})
+			require.NoError(t, err)
+			entry, err := store.Get(sampleRecoveryKey)
+			if !tc.expectedKept {
+				assert.ErrorIs(t, err, recovery.ErrNotFound)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, *t_helper.GenTime("2024-07-04"), entry.ExpiresAt)
+			kept, err := entry.Decrypt(identity)
+			require.NoError(t, err)
+			assert.Equal(t, newToken, kept)
+		})
+	}
+}
+
+func TestGitlabTokenUpdater_Recover(t *testing.T) {
+	keptToken := "glpat-kept"
+
+	testCases := map[string]struct {
+		noRecovery      bool
+		noEntry         bool
+		dryRun          bool
+		hookErr         error
+		expectedStatus  string
+		expectedCleared bool
+		expectedErr     error
+	}{
+		"ok: hooks are replayed then the entry is cleared": {
+			expectedStatus:  app.TokenStatusRecovered,
+			expectedCleared: true,
+		},
+		"ok: dry run is using the dummy token and keeping the entry": {
+			dryRun:         true,
+			expectedStatus: app.TokenStatusRecovered,
+		},
+		"err: entry is kept when the hooks are failed": {
+			hookErr:        errors.New("403 Forbidden"),
+			expectedStatus: app.TokenStatusFailed,
+			expectedErr:    app.ErrDuringExecution,
+		},
+		"err: no entry": {
+			noEntry:     true,
+			expectedErr: app.ErrRecoverNoEntry,
+		},
+		"err: recovery is not configured": {
+			noRecovery:  true,
+			expectedErr: app.ErrRecoveryNotConfigured,
+		},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			identity, err := age.GenerateX25519Identity()
+			require.NoError(t, err)
+			recoveryFile := filepath.Join(t.TempDir(), "recovery.json")
+			store, err := recovery.NewStore([]string{identity.Recipient().String()}, recovery.File{Path: recoveryFile})
+			require.NoError(t, err)
+			if !tc.noEntry {
+				require.NoError(t, store.Put(recovery.Entry{
+					Type: cfg.ManagedTypeRepository, Path: t_helper.SampleRepoPath, Name: t_helper.SampleAccessTokeName, ID: 123,
+					ExpiresAt: *t_helper.GenTime("2024-07-04"), RotatedAt: time.Now(),
+				}, keptToken))
+			}
+
+			config := t_helper.GenConfig(nil, nil, nil)
+			if !tc.noRecovery {
+				config.Recovery = &cfg.Recovery{Recipients: []string{identity.Recipient().String()}, File: recoveryFile}
+			}
+			assert.NoError(t, config.InitValues())
+
+			g := gm.NewMockGitlabAPI(ctrl)
+			if tc.expectedStatus != "" && !tc.dryRun {
+				g.EXPECT().UpdateRepoVar(t_helper.SampleRepoPath, t_helper.SampleCICDVar, "", keptToken, gl.GitlabCICDVarAttrs{}).Return(tc.hookErr)
+			}
+			if tc.dryRun {
+				g.EXPECT().GetRepoVar(t_helper.SampleRepoPath, t_helper.SampleCICDVar, "").Return(&gl.GitlabCICDVar{Key: t_helper.SampleCICDVar}, nil)
+			}
+
+			updater := app.NewGitlabTokenUpdater(config, g, nil).WithDryRun(tc.dryRun)
+			err = updater.Recover([]age.Identity{identity})
+			assert.ErrorIs(t, err, tc.expectedErr)
+			if tc.expectedErr == nil {
+				assert.NoError(t, err)
+			}
+			if tc.expectedStatus == "" {
+				return
+			}
+
+			tknReport := updater.Report().Managed[0].Tokens[0]
+			assert.Equal(t, tc.expectedStatus, tknReport.Status)
+			assert.Equal(t, t_helper.GenTime("2024-07-04"), tknReport.NewExpiresAt)
+			_, err = store.Get(sampleRecoveryKey)
+			if tc.expectedCleared {
+				assert.ErrorIs(t, err, recovery.ErrNotFound)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,207 @@
+package app
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	ReportFormatJSON  = "json"
+	ReportFormatJUnit = "junit"
+
+	TokenStatusSkipped   = "skipped"
+	TokenStatusRenewed   = "renewed"
+	TokenStatusFailed    = "failed"
+	TokenStatusNotFound  = "not_found"
+	TokenStatusCreated   = "created"
+	TokenStatusRecreated = "recreated"
+)
+
+var (
+	ReportFormatList = []string{
+		ReportFormatJSON,
+		ReportFormatJUnit,
+	}
+	ErrReportInvalidFormat = fmt.Errorf("invalid report format, the valid one are %s", strings.Join(ReportFormatList, ","))
+)
+
+// Report results of an execution, it must never contains any of token value
+type Report struct {
+	ExecutedAt time.Time        `json:"executed_at"`
+	DryRun     bool             `json:"dry_run"`
+	Force      bool             `json:"force"`
+	Strict     bool             `json:"strict"`
+	Managed    []*ManagedReport `json:"managed"`
+	Errors     []string         `json:"errors"`
+}
+
+// ManagedReport execution results of a managed token config
+type ManagedReport struct {
+	Path   string         `json:"path"`
+	Type   string         `json:"type"`
+	Error  string         `json:"error,omitempty"`
+	Tokens []*TokenReport `json:"tokens"`
+}
+
+// TokenReport execution results of an access token
+type TokenReport struct {
+	Name         string        `json:"name"`
+	ID           int           `json:"id,omitempty"`
+	Status       string        `json:"status"`
+	Reason       string        `json:"reason,omitempty"`
+	OldExpiresAt *time.Time    `json:"old_expires_at"`
+	NewExpiresAt *time.Time    `json:"new_expires_at"`
+	Error        string        `json:"error,omitempty"`
+	Hooks        []*HookReport `json:"hooks"`
+}
+
+// HookReport execution results of a hook
+type HookReport struct {
+	Type     string `json:"type"`
+	Args     string `json:"args"`
+	Attempts int    `json:"attempts"`
+	Success  bool   `json:"success"`
+	Error    string `json:"error,omitempty"`
+}
+
+func newManagedReport(path, mType string) *ManagedReport {
+	return &ManagedReport{Path: path, Type: mType, Tokens: []*TokenReport{}}
+}
+
+func (r *Report) addManaged(path, mType string) *ManagedReport {
+	mr := newManagedReport(path, mType)
+	r.Managed = append(r.Managed, mr)
+	return mr
+}
+
+func (m *ManagedReport) addToken(name string) *TokenReport {
+	tr := &TokenReport{Name: name, Status: TokenStatusSkipped, Hooks: []*HookReport{}}
+	m.Tokens = append(m.Tokens, tr)
+	return tr
+}
+
+func (t *TokenReport) fail(err error) {
+	t.Status = TokenStatusFailed
+	t.Error = err.Error()
+}
+
+// WriteReport write out the execution report in the given format
+func WriteReport(w io.Writer, format string, r *Report) error {
+	switch format {
+	case ReportFormatJSON:
+		return writeJSON(w, r)
+	case ReportFormatJUnit:
+		return r.writeJUnit(w)
+	}
+	return ErrReportInvalidFormat
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Errors    int             `xml:"errors,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Timestamp string          `xml:"timestamp,attr"`
+	Cases     []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+}
+
+func (t TokenReport) junitTestCase(className string) junitTestCase {
+	tc := junitTestCase{Name: t.Name, ClassName: className}
+	switch t.Status {
+	case TokenStatusFailed:
+		tc.Failure = &junitMessage{Message: t.Error}
+	case TokenStatusSkipped:
+		reason := t.Reason
+		if reason == "" {
+			reason = "not identified as need to renew"
+		}
+		tc.Skipped = &junitMessage{Message: reason}
+	case TokenStatusNotFound:
+		tc.Skipped = &junitMessage{Message: "token is not exists"}
+	}
+
+	var out []string
+	if t.NewExpiresAt != nil {
+		out = append(out, fmt.Sprintf("expires at: %s -> %s", fmtStatusDate(t.OldExpiresAt), fmtStatusDate(t.NewExpiresAt)))
+	}
+	for _, hk := range t.Hooks {
+		hkOut := fmt.Sprintf("hook %s (%s): attempts %d, success %t", hk.Type, hk.Args, hk.Attempts, hk.Success)
+		if hk.Error != "" {
+			hkOut = fmt.Sprintf("%s, error: %s", hkOut, hk.Error)
+		}
+		out = append(out, hkOut)
+	}
+	tc.SystemOut = strings.Join(out, "\n")
+	return tc
+}
+
+func (r *Report) writeJUnit(w io.Writer) error {
+	suites := junitTestSuites{Name: "gitlab-token-updater"}
+	for _, mr := range r.Managed {
+		className := fmt.Sprintf("%s.%s", mr.Type, mr.Path)
+		suite := junitTestSuite{Name: mr.Path, Timestamp: r.ExecutedAt.Format(time.RFC3339)}
+		if mr.Error != "" {
+			suite.Errors++
+			suite.Cases = append(suite.Cases, junitTestCase{
+				Name:      "list access tokens",
+				ClassName: className,
+				Error:     &junitMessage{Message: mr.Error},
+			})
+		}
+
+		for _, tr := range mr.Tokens {
+			switch tr.Status {
+			case TokenStatusFailed:
+				suite.Failures++
+			case TokenStatusSkipped, TokenStatusNotFound:
+				suite.Skipped++
+			}
+			suite.Cases = append(suite.Cases, tr.junitTestCase(className))
+		}
+		suite.Tests = len(suite.Cases)
+
+		suites.Tests += suite.Tests
+		suites.Failures += suite.Failures
+		suites.Errors += suite.Errors
+		suites.Skipped += suite.Skipped
+		suites.Suites = append(suites.Suites, suite)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(suites); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
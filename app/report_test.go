@@ -0,0 +1,196 @@
+package app_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/iomarmochtar/gitlab-token-updater/app"
+	cfg "github.com/iomarmochtar/gitlab-token-updater/pkg/config"
+	gl "github.com/iomarmochtar/gitlab-token-updater/pkg/gitlab"
+	t_helper "github.com/iomarmochtar/gitlab-token-updater/test"
+	gm "github.com/iomarmochtar/gitlab-token-updater/test/mocks/gitlab"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestGitlabTokenUpdater_Report(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	newToken := "glpat-newnew"
+
+	// 1st: renewed with a hook that succeed in the 2nd attempt, 2nd: error in renewal,
+	// 3rd: error in listing, 4th: not identified as need to renew and another one is not exists
+	config := t_helper.GenConfig(t_helper.GenManageTokens(t_helper.GenManageTokens(t_helper.GenManageTokens(nil, nil, nil), nil, nil), nil, nil), nil, nil)
+	config.Managed[0].Path = "/first"
+	config.Managed[0].Tokens[0].Hooks[0].Retry = 1
+	config.Managed[1].Path = "/second"
+	config.Managed[2].Path = "/third"
+	config.Managed[3].Path = "/fourth"
+	config.Managed[3].Tokens = append(config.Managed[3].Tokens, cfg.AccessToken{Name: "not exists"})
+	assert.NoError(t, config.InitValues())
+
+	g := gm.NewMockGitlabAPI(ctrl)
+	accessTokens := []gl.GitlabAccessToken{t_helper.SampleRepoAccessToken}
+	notRenewedTokens := []gl.GitlabAccessToken{t_helper.SampleRepoAccessToken}
+	notRenewedTokens[0].ExpiresAt = t_helper.GenTime("2025-01-01")
+	gomock.InOrder(
+		g.EXPECT().ListRepoAccessToken("/first").Return(accessTokens, nil),
+		g.EXPECT().RotateRepoToken(t_helper.SampleRepoPath, 123, *t_helper.GenTime("2024-07-04")).Return(newToken, nil),
+		g.EXPECT().UpdateRepoVar(t_helper.SampleRepoPath, t_helper.SampleCICDVar, "", newToken, gl.GitlabCICDVarAttrs{}).Return(fmt.Errorf("error occured")),
+		g.EXPECT().UpdateRepoVar(t_helper.SampleRepoPath, t_helper.SampleCICDVar, "", newToken, gl.GitlabCICDVarAttrs{}).Return(nil),
+		g.EXPECT().ListRepoAccessToken("/second").Return(accessTokens, nil),
+		g.EXPECT().RotateRepoToken(t_helper.SampleRepoPath, 123, *t_helper.GenTime("2024-07-04")).Return("", fmt.Errorf("error during renew")),
+		g.EXPECT().ListRepoAccessToken("/third").Return(nil, fmt.Errorf("error in listing access token")),
+		g.EXPECT().ListRepoAccessToken("/fourth").Return(notRenewedTokens, nil),
+	)
+
+	updater := app.NewGitlabTokenUpdater(config, g, nil).WithCustomCurrentTime(t_helper.GenTime("2024-04-05"))
+	assert.EqualError(t, updater.Do(), "some error(s) occured during execution")
+
+	hookArgs := "type:repository,path:/path/to/repo,name:SOME_VAR"
+	assert.Equal(t, &app.Report{
+		ExecutedAt: *t_helper.GenTime("2024-04-05"),
+		Managed: []*app.ManagedReport{
+			{
+				Path: "/first",
+				Type: cfg.ManagedTypeRepository,
+				Tokens: []*app.TokenReport{
+					{
+						Name:         t_helper.SampleAccessTokeName,
+						ID:           123,
+						Status:       app.TokenStatusRenewed,
+						OldExpiresAt: t_helper.GenTime("2024-05-01"),
+						NewExpiresAt: t_helper.GenTime("2024-07-04"),
+						Hooks: []*app.HookReport{
+							{Type: cfg.HookTypeUpdateVar, Args: hookArgs, Attempts: 2, Success: true},
+						},
+					},
+				},
+			},
+			{
+				Path: "/second",
+				Type: cfg.ManagedTypeRepository,
+				Tokens: []*app.TokenReport{
+					{
+						Name:         t_helper.SampleAccessTokeName,
+						ID:           123,
+						Status:       app.TokenStatusFailed,
+						OldExpiresAt: t_helper.GenTime("2024-05-01"),
+						Error:        "error during renew",
+						Hooks:        []*app.HookReport{},
+					},
+				},
+			},
+			{
+				Path:   "/third",
+				Type:   cfg.ManagedTypeRepository,
+				Error:  "error in listing access token",
+				Tokens: []*app.TokenReport{},
+			},
+			{
+				Path: "/fourth",
+				Type: cfg.ManagedTypeRepository,
+				Tokens: []*app.TokenReport{
+					{
+						Name:         t_helper.SampleAccessTokeName,
+						ID:           123,
+						Status:       app.TokenStatusSkipped,
+						OldExpiresAt: t_helper.GenTime("2025-01-01"),
+						Hooks:        []*app.HookReport{},
+					},
+					{
+						Name:   "not exists",
+						Status: app.TokenStatusNotFound,
+						Hooks:  []*app.HookReport{},
+					},
+				},
+			},
+		},
+		Errors: []string{"error during renew", "error in listing access token"},
+	}, updater.Report())
+}
+
+func TestWriteReport(t *testing.T) {
+	report := &app.Report{
+		ExecutedAt: *t_helper.GenTime("2024-04-05"),
+		Force:      true,
+		Managed: []*app.ManagedReport{
+			{
+				Path: t_helper.SampleRepoPath,
+				Type: cfg.ManagedTypeRepository,
+				Tokens: []*app.TokenReport{
+					{
+						Name:         "renewed",
+						ID:           123,
+						Status:       app.TokenStatusRenewed,
+						OldExpiresAt: t_helper.GenTime("2024-05-01"),
+						NewExpiresAt: t_helper.GenTime("2024-07-04"),
+						Hooks: []*app.HookReport{
+							{Type: cfg.HookTypeExecCMD, Args: "path:./script.sh", Attempts: 1, Success: true},
+						},
+					},
+					{
+						Name:         "failed",
+						ID:           124,
+						Status:       app.TokenStatusFailed,
+						OldExpiresAt: t_helper.GenTime("2024-05-01"),
+						NewExpiresAt: t_helper.GenTime("2024-07-04"),
+						Error:        "script error",
+						Hooks: []*app.HookReport{
+							{Type: cfg.HookTypeExecCMD, Args: "path:./script.sh", Attempts: 2, Error: "script error"},
+						},
+					},
+					{Name: "skipped", ID: 125, Status: app.TokenStatusSkipped, Hooks: []*app.HookReport{}},
+				},
+			},
+			{
+				Path:   t_helper.SampleGroupPath,
+				Type:   cfg.ManagedTypeGroup,
+				Error:  "error in listing",
+				Tokens: []*app.TokenReport{},
+			},
+		},
+		Errors: []string{"script error", "error in listing"},
+	}
+
+	t.Run("json", func(t *testing.T) {
+		buf := new(bytes.Buffer)
+		assert.NoError(t, app.WriteReport(buf, app.ReportFormatJSON, report))
+
+		var obj app.Report
+		assert.NoError(t, json.Unmarshal(buf.Bytes(), &obj))
+		assert.Equal(t, report, &obj)
+	})
+
+	t.Run("junit", func(t *testing.T) {
+		buf := new(bytes.Buffer)
+		assert.NoError(t, app.WriteReport(buf, app.ReportFormatJUnit, report))
+		assert.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites name="gitlab-token-updater" tests="4" failures="1" errors="1" skipped="1">
+  <testsuite name="/path/to/repo" tests="3" failures="1" errors="0" skipped="1" timestamp="2024-04-05T00:00:00Z">
+    <testcase name="renewed" classname="repository./path/to/repo">
+      <system-out>expires at: 2024-05-01 -&gt; 2024-07-04&#xA;hook exec_cmd (path:./script.sh): attempts 1, success true</system-out>
+    </testcase>
+    <testcase name="failed" classname="repository./path/to/repo">
+      <failure message="script error"></failure>
+      <system-out>expires at: 2024-05-01 -&gt; 2024-07-04&#xA;hook exec_cmd (path:./script.sh): attempts 2, success false, error: script error</system-out>
+    </testcase>
+    <testcase name="skipped" classname="repository./path/to/repo">
+      <skipped message="not identified as need to renew"></skipped>
+    </testcase>
+  </testsuite>
+  <testsuite name="/path/to/group" tests="1" failures="0" errors="1" skipped="0" timestamp="2024-04-05T00:00:00Z">
+    <testcase name="list access tokens" classname="group./path/to/group">
+      <error message="error in listing"></error>
+    </testcase>
+  </testsuite>
+</testsuites>
+`, buf.String())
+	})
+
+	t.Run("err: unknown format", func(t *testing.T) {
+		assert.ErrorIs(t, app.WriteReport(new(bytes.Buffer), "xml", report), app.ErrReportInvalidFormat)
+	})
+}
@@ -0,0 +1,125 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"filippo.io/age"
+	cfg "github.com/iomarmochtar/gitlab-token-updater/pkg/config"
+	"github.com/iomarmochtar/gitlab-token-updater/pkg/journal"
+	"github.com/iomarmochtar/gitlab-token-updater/pkg/recovery"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	TokenStatusResumed = "resumed"
+)
+
+var (
+	ErrJournalNotConfigured   = errors.New("journal is not configured")
+	ErrResumeRequiresRecovery = errors.New("recovery is required for resuming, the new token is taken from the recovery store")
+	ErrResumeNoOutstanding    = errors.New("no outstanding hooks are found for the selected access tokens")
+	ErrResumeHooksChanged     = errors.New("the hooks are changed since the token is rotated")
+)
+
+// findPending the pending access token in the managed token, the personal one is matched regardless of it's path
+func findPending(pendings []journal.Pending, mg cfg.ManagedToken, name string) *journal.Pending {
+	for _, p := range pendings {
+		r := p.Rotated
+		if r.Type == mg.Type && r.Name == name && (r.Path == mg.Path || mg.Type == cfg.ManagedTypePersonal) {
+			return &p
+		}
+	}
+	return nil
+}
+
+// resumeToken execute the outstanding hooks of the access token by the kept token in recovery store
+func (g *GitlabTokenUpdater) resumeToken(store *recovery.Store, identities []age.Identity, entries []recovery.Entry, p journal.Pending, mg cfg.ManagedToken, tkn cfg.AccessToken, tknReport *TokenReport) error {
+	logTkn := log.With().Str("path", mg.Path).Str("m_type", mg.Type).Str("token", tkn.Name).Logger()
+	hookTypes := make([]string, 0, len(tkn.Hooks))
+	for _, hk := range tkn.Hooks {
+		hookTypes = append(hookTypes, hk.Type)
+	}
+
+	var err error
+	entry := findRecoveryEntry(entries, mg, tkn.Name)
+	switch {
+	case !slices.Equal(hookTypes, p.Rotated.Hooks):
+		err = fmt.Errorf("%w: %s in %s", ErrResumeHooksChanged, tkn.Name, mg.Path)
+	case entry == nil:
+		err = fmt.Errorf("%w: %s in %s", recovery.ErrNotFound, tkn.Name, mg.Path)
+	}
+	if err != nil {
+		logTkn.Error().Err(err).Msg("unable to resume the outstanding hooks")
+		tknReport.fail(err)
+		return g.errAppender(err)
+	}
+
+	logTkn.Info().Ints("hooks", p.Outstanding()).Str("run_id", p.Rotated.RunID).Msg("resuming the outstanding hooks")
+	return g.recoverToken(store, identities, *entry, tkn, p.Succeeded, TokenStatusResumed, tknReport)
+}
+
+// Resume execute only the outstanding hooks of the selected access tokens that are rotated in the interrupted or failed execution,
+// the new token is taken from the recovery store
+func (g *GitlabTokenUpdater) Resume(identities []age.Identity) error {
+	jr := g.journalOf()
+	if jr == nil {
+		return ErrJournalNotConfigured
+	}
+	if g.config.Recovery == nil {
+		return ErrResumeRequiresRecovery
+	}
+
+	records, err := jr.Read()
+	if err != nil {
+		return err
+	}
+	pendings := journal.Outstanding(records)
+	if len(pendings) == 0 {
+		return ErrResumeNoOutstanding
+	}
+
+	store, err := g.newRecoveryStore()
+	if err != nil {
+		return err
+	}
+	entries, err := store.List()
+	if err != nil {
+		return err
+	}
+
+	resumed := 0
+	for _, mg := range g.config.Managed {
+		selected, isSelected := g.selector.filter(mg)
+		if !isSelected {
+			continue
+		}
+
+		var mgReport *ManagedReport
+		for _, tkn := range selected.Tokens {
+			if g.interrupted() {
+				return ErrInterrupted
+			}
+
+			p := findPending(pendings, mg, tkn.Name)
+			if p == nil {
+				continue
+			}
+			resumed++
+
+			if mgReport == nil {
+				mgReport = g.report.addManaged(mg.Path, mg.Type)
+			}
+			if err = g.resumeToken(store, identities, entries, *p, mg, tkn, mgReport.addToken(tkn.Name)); err != nil {
+				return err
+			}
+		}
+	}
+
+	if resumed == 0 {
+		return ErrResumeNoOutstanding
+	}
+	log.Info().Msg("done")
+	return g.collectedErrors()
+}
@@ -0,0 +1,183 @@
+package app_test
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"filippo.io/age"
+	"github.com/iomarmochtar/gitlab-token-updater/app"
+	cfg "github.com/iomarmochtar/gitlab-token-updater/pkg/config"
+	gl "github.com/iomarmochtar/gitlab-token-updater/pkg/gitlab"
+	"github.com/iomarmochtar/gitlab-token-updater/pkg/journal"
+	"github.com/iomarmochtar/gitlab-token-updater/pkg/recovery"
+	t_helper "github.com/iomarmochtar/gitlab-token-updater/test"
+	gm "github.com/iomarmochtar/gitlab-token-updater/test/mocks/gitlab"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestGitlabTokenUpdater_Do_Journal(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	newToken := "glpat-newnew"
+	journalFile := filepath.Join(t.TempDir(), "journal.jsonl")
+	config := t_helper.GenConfig(nil, nil, []cfg.Hook{t_helper.SampleHookUpdateVarGroup})
+	config.Journal = &cfg.Journal{File: journalFile}
+	assert.NoError(t, config.InitValues())
+
+	g := gm.NewMockGitlabAPI(ctrl)
+	g.EXPECT().ListRepoAccessToken(t_helper.SampleRepoPath).Return([]gl.GitlabAccessToken{t_helper.SampleRepoAccessToken}, nil)
+	g.EXPECT().RotateRepoToken(t_helper.SampleRepoPath, 123, *t_helper.GenTime("2024-07-04")).Return(newToken, nil)
+	g.EXPECT().UpdateRepoVar(t_helper.SampleRepoPath, t_helper.SampleCICDVar, "", newToken, gl.GitlabCICDVarAttrs{}).Return(nil)
+	g.EXPECT().UpdateGroupVar(t_helper.SampleGroupPath, t_helper.SampleCICDVar, "", newToken, gl.GitlabCICDVarAttrs{}).Return(errors.New("403 Forbidden"))
+
+	updater := app.NewGitlabTokenUpdater(config, g, nil).WithCustomCurrentTime(t_helper.GenTime("2024-04-05"))
+	assert.ErrorIs(t, updater.Do(), app.ErrDuringExecution)
+
+	records, err := journal.File{Path: journalFile}.Read()
+	require.NoError(t, err)
+	steps := []string{}
+	for _, r := range records {
+		steps = append(steps, r.Step)
+		assert.NotContains(t, r.Error, newToken)
+	}
+	assert.Equal(t, []string{
+		journal.StepRunStarted, journal.StepListed, journal.StepRotated,
+		journal.StepHookSucceeded, journal.StepHookFailed, journal.StepRunFinished,
+	}, steps)
+	assert.Equal(t, []string{cfg.HookTypeUpdateVar, cfg.HookTypeUpdateVar}, records[2].Hooks)
+	assert.Equal(t, t_helper.GenTime("2024-07-04"), records[2].ExpiresAt)
+
+	pendings := journal.Outstanding(records)
+	require.Len(t, pendings, 1)
+	assert.Equal(t, []int{2}, pendings[0].Outstanding())
+	assert.Equal(t, "403 Forbidden", pendings[0].LastError)
+}
+
+func TestGitlabTokenUpdater_Resume(t *testing.T) {
+	keptToken := "glpat-kept"
+	rotatedHooks := []string{cfg.HookTypeUpdateVar, cfg.HookTypeUpdateVar}
+
+	testCases := map[string]struct {
+		noJournal       bool
+		noRecovery      bool
+		noEntry         bool
+		succeeded       []int
+		hooks           []string
+		hookErr         error
+		expectedStatus  string
+		expectedCleared bool
+		expectedErr     error
+	}{
+		"ok: only the outstanding hooks are executed": {
+			succeeded:       []int{1},
+			hooks:           rotatedHooks,
+			expectedStatus:  app.TokenStatusResumed,
+			expectedCleared: true,
+		},
+		"err: outstanding hooks are failed again": {
+			succeeded:      []int{1},
+			hooks:          rotatedHooks,
+			hookErr:        errors.New("403 Forbidden"),
+			expectedStatus: app.TokenStatusFailed,
+			expectedErr:    app.ErrDuringExecution,
+		},
+		"err: hooks are changed since the rotation": {
+			hooks:          []string{cfg.HookTypeUpdateVar},
+			expectedStatus: app.TokenStatusFailed,
+			expectedErr:    app.ErrDuringExecution,
+		},
+		"err: token is not kept in recovery store": {
+			noEntry:        true,
+			hooks:          rotatedHooks,
+			expectedStatus: app.TokenStatusFailed,
+			expectedErr:    app.ErrDuringExecution,
+		},
+		"err: nothing is outstanding": {
+			succeeded:   []int{1, 2},
+			hooks:       rotatedHooks,
+			expectedErr: app.ErrResumeNoOutstanding,
+		},
+		"err: journal is not configured": {
+			noJournal:   true,
+			expectedErr: app.ErrJournalNotConfigured,
+		},
+		"err: recovery is not configured": {
+			noRecovery:  true,
+			expectedErr: app.ErrResumeRequiresRecovery,
+		},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			identity, err := age.GenerateX25519Identity()
+			require.NoError(t, err)
+			dir := t.TempDir()
+			recoveryFile, journalFile := filepath.Join(dir, "recovery.json"), filepath.Join(dir, "journal.jsonl")
+			store, err := recovery.NewStore([]string{identity.Recipient().String()}, recovery.File{Path: recoveryFile})
+			require.NoError(t, err)
+			if !tc.noEntry {
+				require.NoError(t, store.Put(recovery.Entry{
+					Type: cfg.ManagedTypeRepository, Path: t_helper.SampleRepoPath, Name: t_helper.SampleAccessTokeName, ID: 123,
+					ExpiresAt: *t_helper.GenTime("2024-07-04"), RotatedAt: time.Now(),
+				}, keptToken))
+			}
+
+			records := []journal.Record{{
+				Step: journal.StepRotated, Type: cfg.ManagedTypeRepository, Path: t_helper.SampleRepoPath,
+				Name: t_helper.SampleAccessTokeName, Hooks: tc.hooks,
+			}}
+			for _, seq := range tc.succeeded {
+				records = append(records, journal.Record{
+					Step: journal.StepHookSucceeded, Type: cfg.ManagedTypeRepository, Path: t_helper.SampleRepoPath,
+					Name: t_helper.SampleAccessTokeName, Hook: seq,
+				})
+			}
+			require.NoError(t, journal.New("previous", journal.File{Path: journalFile}).Append(records...))
+
+			config := t_helper.GenConfig(nil, nil, []cfg.Hook{t_helper.SampleHookUpdateVarGroup})
+			if !tc.noJournal {
+				config.Journal = &cfg.Journal{File: journalFile}
+			}
+			if !tc.noRecovery {
+				config.Recovery = &cfg.Recovery{Recipients: []string{identity.Recipient().String()}, File: recoveryFile}
+			}
+			assert.NoError(t, config.InitValues())
+
+			g := gm.NewMockGitlabAPI(ctrl)
+			if tc.expectedStatus == app.TokenStatusResumed || tc.hookErr != nil {
+				g.EXPECT().UpdateGroupVar(t_helper.SampleGroupPath, t_helper.SampleCICDVar, "", keptToken, gl.GitlabCICDVarAttrs{}).Return(tc.hookErr)
+			}
+
+			updater := app.NewGitlabTokenUpdater(config, g, nil)
+			err = updater.Resume([]age.Identity{identity})
+			assert.ErrorIs(t, err, tc.expectedErr)
+			if tc.expectedErr == nil {
+				assert.NoError(t, err)
+			}
+			if tc.expectedStatus == "" {
+				return
+			}
+
+			tknReport := updater.Report().Managed[0].Tokens[0]
+			assert.Equal(t, tc.expectedStatus, tknReport.Status)
+			_, err = store.Get(sampleRecoveryKey)
+			if tc.expectedCleared {
+				assert.ErrorIs(t, err, recovery.ErrNotFound)
+			} else if !tc.noEntry {
+				assert.NoError(t, err)
+			}
+
+			journalRecords, err := journal.File{Path: journalFile}.Read()
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedCleared, len(journal.Outstanding(journalRecords)) == 0)
+		})
+	}
+}
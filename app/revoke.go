@@ -0,0 +1,122 @@
+package app
+
+import (
+	"errors"
+
+	cfg "github.com/iomarmochtar/gitlab-token-updater/pkg/config"
+	gl "github.com/iomarmochtar/gitlab-token-updater/pkg/gitlab"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	TokenStatusRevoked = "revoked"
+)
+
+var (
+	ErrRevokeNoTarget = errors.New("no access token is selected to be revoked")
+)
+
+// RevokeTarget selected access token that is going to be revoked
+type RevokeTarget struct {
+	TokenStatus
+	at accessTokenPair
+}
+
+// RevokeTargets list the selected access tokens that are going to be revoked
+func (g *GitlabTokenUpdater) RevokeTargets() (targets []RevokeTarget, err error) {
+	err = g.listSelected(func(mg cfg.ManagedToken, ats []accessTokenPair) {
+		for _, at := range ats {
+			if !at.missing {
+				targets = append(targets, RevokeTarget{TokenStatus: g.tokenStatus(mg, at), at: at})
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(targets) == 0 {
+		return nil, errors.Join(ErrRevokeNoTarget, g.collectedErrors())
+	}
+	return targets, nil
+}
+
+// RevokeTargetStatuses expiry state of the revoke targets, used in confirming the revocation
+func RevokeTargetStatuses(targets []RevokeTarget) []TokenStatus {
+	statuses := make([]TokenStatus, 0, len(targets))
+	for _, target := range targets {
+		statuses = append(statuses, target.TokenStatus)
+	}
+	return statuses
+}
+
+// processRevoke revoke the access token immediately
+func (g GitlabTokenUpdater) processRevoke(tkn accessTokenPair) error {
+	if g.dryRun {
+		return nil
+	}
+
+	path := tkn.glAccessToken.Path
+	id := tkn.glAccessToken.ID
+	if tkn.glAccessToken.Type == gl.GitlabTargetTypePersonal {
+		return g.glAPI.RevokePersonalToken(id)
+	} else if tkn.glAccessToken.Type == gl.GitlabTargetTypeRepo {
+		return g.glAPI.RevokeRepoToken(path, id)
+	}
+
+	return g.glAPI.RevokeGroupToken(path, id)
+}
+
+// revokeToken revoke the access token, if rotate is set then it's rotated instead (the old one is revoked by Gitlab) and the hooks are executed
+func (g *GitlabTokenUpdater) revokeToken(target RevokeTarget, rotate bool, tknReport *TokenReport) error {
+	logTkn := log.With().Str("path", target.Path).Str("m_type", target.Type).Str("token", target.Name).Logger()
+	tknReport.ID = target.ID
+	tknReport.OldExpiresAt = target.ExpiresAt
+
+	if rotate {
+		logTkn.Warn().Msg("rotating token, the current one is revoked")
+		newToken, err := g.processRenew(target.at)
+		if err != nil {
+			logTkn.Error().Err(err).Msg("error rotate token")
+			tknReport.fail(err)
+			return g.errAppender(err)
+		}
+		logTkn.Info().Msg("token successfully rotated")
+		nextExpiry := g.nextExpiry(target.at)
+		tknReport.Status = TokenStatusRenewed
+		tknReport.NewExpiresAt = &nextExpiry
+		g.recordRotated(target.at)
+
+		return g.execHooksRecoverable(logTkn, target.at, newToken, tknReport)
+	}
+
+	logTkn.Warn().Msg("revoking token")
+	if err := g.processRevoke(target.at); err != nil {
+		logTkn.Error().Err(err).Msg("error revoke token")
+		tknReport.fail(err)
+		return g.errAppender(err)
+	}
+	logTkn.Info().Msg("token successfully revoked")
+	tknReport.Status = TokenStatusRevoked
+	return nil
+}
+
+// Revoke revoke the targeted access tokens immediately, set rotate for creating the replacement and executing the hooks
+func (g *GitlabTokenUpdater) Revoke(targets []RevokeTarget, rotate bool) error {
+	var mgReport *ManagedReport
+	for _, target := range targets {
+		if g.interrupted() {
+			return ErrInterrupted
+		}
+
+		if mgReport == nil || mgReport.Path != target.Path || mgReport.Type != target.Type {
+			mgReport = g.report.addManaged(target.Path, target.Type)
+		}
+		if err := g.revokeToken(target, rotate, mgReport.addToken(target.Name)); err != nil {
+			return err
+		}
+	}
+
+	log.Info().Msg("done")
+	return g.collectedErrors()
+}
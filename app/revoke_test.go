@@ -0,0 +1,136 @@
+package app_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/iomarmochtar/gitlab-token-updater/app"
+	cfg "github.com/iomarmochtar/gitlab-token-updater/pkg/config"
+	gl "github.com/iomarmochtar/gitlab-token-updater/pkg/gitlab"
+	t_helper "github.com/iomarmochtar/gitlab-token-updater/test"
+	gm "github.com/iomarmochtar/gitlab-token-updater/test/mocks/gitlab"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestGitlabTokenUpdater_Revoke(t *testing.T) {
+	newToken := "glpat-newnew"
+	groupToken := t_helper.SampleGroupAccessToken
+	groupToken.ID = 321
+
+	// repository and group managed tokens, each of them has the sample access token with update_var hook
+	genConfig := func() *cfg.Config {
+		config := t_helper.GenConfig(t_helper.GenManageTokens(nil, nil, nil), nil, nil)
+		config.Managed[1].Type = cfg.ManagedTypeGroup
+		config.Managed[1].Path = t_helper.SampleGroupPath
+		assert.NoError(t, config.InitValues())
+		return config
+	}
+
+	testCases := map[string]struct {
+		rotate           bool
+		dryRun           bool
+		strict           bool
+		selectPaths      []string
+		mockGitlab       func(g *gm.MockGitlabAPI)
+		expectedStatuses []string
+		expectedErrMsg   string
+	}{
+		"ok: revoke all of selected": {
+			mockGitlab: func(g *gm.MockGitlabAPI) {
+				g.EXPECT().ListRepoAccessToken(t_helper.SampleRepoPath).Return([]gl.GitlabAccessToken{t_helper.SampleRepoAccessToken}, nil)
+				g.EXPECT().ListGroupAccessToken(t_helper.SampleGroupPath).Return([]gl.GitlabAccessToken{groupToken}, nil)
+				g.EXPECT().RevokeRepoToken(t_helper.SampleRepoPath, 123).Return(nil)
+				g.EXPECT().RevokeGroupToken(t_helper.SampleGroupPath, 321).Return(nil)
+			},
+			expectedStatuses: []string{app.TokenStatusRevoked, app.TokenStatusRevoked},
+		},
+		"ok: rotate then execute the hooks": {
+			rotate:      true,
+			selectPaths: []string{t_helper.SampleGroupPath},
+			mockGitlab: func(g *gm.MockGitlabAPI) {
+				g.EXPECT().ListGroupAccessToken(t_helper.SampleGroupPath).Return([]gl.GitlabAccessToken{groupToken}, nil)
+				g.EXPECT().RotateGroupToken(t_helper.SampleGroupPath, 321, *t_helper.GenTime("2024-07-04")).Return(newToken, nil)
+				g.EXPECT().UpdateRepoVar(t_helper.SampleRepoPath, t_helper.SampleCICDVar, "", newToken, gl.GitlabCICDVarAttrs{}).Return(nil)
+			},
+			expectedStatuses: []string{app.TokenStatusRenewed},
+		},
+		"ok: dry run": {
+			dryRun:      true,
+			selectPaths: []string{t_helper.SampleRepoPath},
+			mockGitlab: func(g *gm.MockGitlabAPI) {
+				g.EXPECT().ListRepoAccessToken(t_helper.SampleRepoPath).Return([]gl.GitlabAccessToken{t_helper.SampleRepoAccessToken}, nil)
+			},
+			expectedStatuses: []string{app.TokenStatusRevoked},
+		},
+		"err: revoke error is collected in non strict mode": {
+			mockGitlab: func(g *gm.MockGitlabAPI) {
+				g.EXPECT().ListRepoAccessToken(t_helper.SampleRepoPath).Return([]gl.GitlabAccessToken{t_helper.SampleRepoAccessToken}, nil)
+				g.EXPECT().ListGroupAccessToken(t_helper.SampleGroupPath).Return([]gl.GitlabAccessToken{groupToken}, nil)
+				g.EXPECT().RevokeRepoToken(t_helper.SampleRepoPath, 123).Return(fmt.Errorf("403 Forbidden"))
+				g.EXPECT().RevokeGroupToken(t_helper.SampleGroupPath, 321).Return(nil)
+			},
+			expectedStatuses: []string{app.TokenStatusFailed, app.TokenStatusRevoked},
+			expectedErrMsg:   app.ErrDuringExecution.Error(),
+		},
+		"err: revoke error stop the execution in strict mode": {
+			strict: true,
+			mockGitlab: func(g *gm.MockGitlabAPI) {
+				g.EXPECT().ListRepoAccessToken(t_helper.SampleRepoPath).Return([]gl.GitlabAccessToken{t_helper.SampleRepoAccessToken}, nil)
+				g.EXPECT().ListGroupAccessToken(t_helper.SampleGroupPath).Return([]gl.GitlabAccessToken{groupToken}, nil)
+				g.EXPECT().RevokeRepoToken(t_helper.SampleRepoPath, 123).Return(fmt.Errorf("403 Forbidden"))
+			},
+			expectedStatuses: []string{app.TokenStatusFailed},
+			expectedErrMsg:   "403 Forbidden",
+		},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			g := gm.NewMockGitlabAPI(ctrl)
+			tc.mockGitlab(g)
+
+			selector, err := app.NewSelector(tc.selectPaths, nil, nil, nil, nil)
+			assert.NoError(t, err)
+			updater := app.NewGitlabTokenUpdater(genConfig(), g, nil).
+				WithCustomCurrentTime(t_helper.GenTime("2024-04-05")).
+				WithDryRun(tc.dryRun).
+				WithStrictMode(tc.strict).
+				WithSelector(selector)
+
+			targets, err := updater.RevokeTargets()
+			assert.NoError(t, err)
+
+			err = updater.Revoke(targets, tc.rotate)
+			if tc.expectedErrMsg != "" {
+				assert.EqualError(t, err, tc.expectedErrMsg)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			var statuses []string
+			for _, mr := range updater.Report().Managed {
+				for _, tr := range mr.Tokens {
+					statuses = append(statuses, tr.Status)
+				}
+			}
+			assert.Equal(t, tc.expectedStatuses, statuses)
+		})
+	}
+}
+
+func TestGitlabTokenUpdater_RevokeTargets(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	config := t_helper.GenConfig(nil, nil, nil)
+	assert.NoError(t, config.InitValues())
+	g := gm.NewMockGitlabAPI(ctrl)
+	g.EXPECT().ListRepoAccessToken(t_helper.SampleRepoPath).Return(nil, fmt.Errorf("404 Not Found"))
+
+	_, err := app.NewGitlabTokenUpdater(config, g, nil).RevokeTargets()
+	assert.ErrorIs(t, err, app.ErrRevokeNoTarget)
+	assert.ErrorIs(t, err, app.ErrDuringExecution)
+}
@@ -0,0 +1,145 @@
+package app
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	cfg "github.com/iomarmochtar/gitlab-token-updater/pkg/config"
+)
+
+const (
+	// SelectorRegexPrefix prefix of the selector pattern that is evaluated as regular expression, otherwise it's a glob
+	SelectorRegexPrefix = "re:"
+
+	skipReasonNotSelected = "not selected"
+)
+
+// pattern glob or regular expression in matching a value
+type pattern struct {
+	glob string
+	re   *regexp.Regexp
+}
+
+func newPattern(p string) (pattern, error) {
+	if expr, isRegex := strings.CutPrefix(p, SelectorRegexPrefix); isRegex {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return pattern{}, fmt.Errorf("invalid selector regex %s: %w", expr, err)
+		}
+		return pattern{re: re}, nil
+	}
+
+	if _, err := path.Match(p, ""); err != nil {
+		return pattern{}, fmt.Errorf("invalid selector glob %s: %w", p, err)
+	}
+	return pattern{glob: p}, nil
+}
+
+func (p pattern) match(value string) bool {
+	if p.re != nil {
+		return p.re.MatchString(value)
+	}
+	matched, _ := path.Match(p.glob, value)
+	return matched
+}
+
+// patterns list of pattern, empty one is matching everything
+type patterns []pattern
+
+func newPatterns(values []string) (ps patterns, err error) {
+	for _, v := range values {
+		p, err := newPattern(v)
+		if err != nil {
+			return nil, err
+		}
+		ps = append(ps, p)
+	}
+	return ps, nil
+}
+
+func (ps patterns) match(values ...string) bool {
+	if len(ps) == 0 {
+		return true
+	}
+	for _, p := range ps {
+		for _, v := range values {
+			if p.match(v) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Selector select the managed tokens to be processed, each of the criteria are combined with AND and their values with OR
+type Selector struct {
+	paths  patterns
+	tokens patterns
+	types  patterns
+	files  patterns
+	tags   []string
+}
+
+// NewSelector create Selector from the patterns (glob or regex that is prefixed by `re:`) of path, token name, type, file and the tags
+func NewSelector(paths, tokens, types, files, tags []string) (s *Selector, err error) {
+	s = &Selector{tags: tags}
+	if s.paths, err = newPatterns(paths); err != nil {
+		return nil, err
+	}
+	if s.tokens, err = newPatterns(tokens); err != nil {
+		return nil, err
+	}
+	if s.types, err = newPatterns(types); err != nil {
+		return nil, err
+	}
+	if s.files, err = newPatterns(files); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// selectManaged whether the managed token is selected, the file is matched to the source (main config or included) file path or it's name
+func (s *Selector) selectManaged(mg cfg.ManagedToken) bool {
+	return s.paths.match(mg.Path) &&
+		s.types.match(mg.Type) &&
+		s.files.match(mg.Ref, filepath.Base(mg.Ref))
+}
+
+// selectToken whether the access token is selected, the tags of managed token are inherited
+func (s *Selector) selectToken(mg cfg.ManagedToken, at cfg.AccessToken) bool {
+	if !s.tokens.match(at.Name) {
+		return false
+	}
+	if len(s.tags) == 0 {
+		return true
+	}
+	for _, tag := range s.tags {
+		if slices.Contains(at.Tags, tag) || slices.Contains(mg.Tags, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// filter return the managed token with the selected access tokens only, false returned if there is none of them
+func (s *Selector) filter(mg cfg.ManagedToken) (cfg.ManagedToken, bool) {
+	if s == nil {
+		return mg, true
+	}
+	if !s.selectManaged(mg) {
+		return mg, false
+	}
+
+	selected := mg
+	selected.Tokens = nil
+	for _, at := range mg.Tokens {
+		if s.selectToken(mg, at) {
+			selected.Tokens = append(selected.Tokens, at)
+		}
+	}
+	return selected, len(selected.Tokens) > 0
+}
@@ -0,0 +1,146 @@
+package app_test
+
+import (
+	"testing"
+
+	"github.com/iomarmochtar/gitlab-token-updater/app"
+	cfg "github.com/iomarmochtar/gitlab-token-updater/pkg/config"
+	gl "github.com/iomarmochtar/gitlab-token-updater/pkg/gitlab"
+	t_helper "github.com/iomarmochtar/gitlab-token-updater/test"
+	gm "github.com/iomarmochtar/gitlab-token-updater/test/mocks/gitlab"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestNewSelector(t *testing.T) {
+	testCases := map[string]struct {
+		paths, tokens, types, files []string
+		expectedErrMsg              string
+	}{
+		"ok: glob and regex": {
+			paths:  []string{"group/*", "re:^group/x/.+$"},
+			tokens: []string{"deploy-?"},
+		},
+		"err: invalid regex": {
+			tokens:         []string{"re:deploy-("},
+			expectedErrMsg: "invalid selector regex deploy-(",
+		},
+		"err: invalid glob": {
+			files:          []string{"[abc"},
+			expectedErrMsg: "invalid selector glob [abc",
+		},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			_, err := app.NewSelector(tc.paths, tc.tokens, tc.types, tc.files, nil)
+			if tc.expectedErrMsg != "" {
+				assert.ErrorContains(t, err, tc.expectedErrMsg)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestGitlabTokenUpdater_Do_Selector(t *testing.T) {
+	// 1st: repository with tokens "deploy" (tagged) and "MR Handler", 2nd: group that is tagged,
+	// 3rd: repository that is defined in included file
+	genConfig := func() *cfg.Config {
+		config := t_helper.GenConfig(t_helper.GenManageTokens(t_helper.GenManageTokens(nil, nil, nil), nil, nil), nil, nil)
+		config.Managed[0].Tokens = append([]cfg.AccessToken{{Name: "deploy", Tags: []string{"payments"}}}, config.Managed[0].Tokens...)
+		config.Managed[0].Ref = "/etc/gltu/config.yml"
+		config.Managed[1].Type = cfg.ManagedTypeGroup
+		config.Managed[1].Path = t_helper.SampleGroupPath
+		config.Managed[1].Tags = []string{"payments"}
+		config.Managed[1].Ref = "/etc/gltu/config.yml"
+		config.Managed[2].Path = "/path/to/included/repo"
+		config.Managed[2].Ref = "/etc/gltu/included.yml"
+		assert.NoError(t, config.InitValues())
+		return config
+	}
+
+	testCases := map[string]struct {
+		paths, tokens, types, files, tags []string
+		mockGitlab                        func(g *gm.MockGitlabAPI)
+		expectedStatuses                  map[string][]string
+	}{
+		"by token name regex": {
+			tokens: []string{"re:^MR "},
+			mockGitlab: func(g *gm.MockGitlabAPI) {
+				g.EXPECT().ListRepoAccessToken(t_helper.SampleRepoPath).Return([]gl.GitlabAccessToken{t_helper.SampleRepoAccessToken}, nil)
+				g.EXPECT().ListGroupAccessToken(t_helper.SampleGroupPath).Return(nil, nil)
+				g.EXPECT().ListRepoAccessToken("/path/to/included/repo").Return(nil, nil)
+			},
+			expectedStatuses: map[string][]string{
+				t_helper.SampleRepoPath:  {"not selected", app.TokenStatusSkipped},
+				t_helper.SampleGroupPath: {app.TokenStatusNotFound},
+				"/path/to/included/repo": {app.TokenStatusNotFound},
+			},
+		},
+		"by tags that are inherited from managed token": {
+			tags: []string{"payments"},
+			mockGitlab: func(g *gm.MockGitlabAPI) {
+				g.EXPECT().ListRepoAccessToken(t_helper.SampleRepoPath).Return(nil, nil)
+				g.EXPECT().ListGroupAccessToken(t_helper.SampleGroupPath).Return(nil, nil)
+			},
+			expectedStatuses: map[string][]string{
+				t_helper.SampleRepoPath:  {app.TokenStatusNotFound, "not selected"},
+				t_helper.SampleGroupPath: {app.TokenStatusNotFound},
+				"/path/to/included/repo": {"not selected"},
+			},
+		},
+		"by path glob and type": {
+			paths: []string{"/path/to/*"},
+			types: []string{cfg.ManagedTypeRepository},
+			mockGitlab: func(g *gm.MockGitlabAPI) {
+				g.EXPECT().ListRepoAccessToken(t_helper.SampleRepoPath).Return(nil, nil)
+			},
+			expectedStatuses: map[string][]string{
+				t_helper.SampleRepoPath:  {app.TokenStatusNotFound, app.TokenStatusNotFound},
+				t_helper.SampleGroupPath: {"not selected"},
+				"/path/to/included/repo": {"not selected"},
+			},
+		},
+		"by included file name": {
+			files: []string{"included.yml"},
+			mockGitlab: func(g *gm.MockGitlabAPI) {
+				g.EXPECT().ListRepoAccessToken("/path/to/included/repo").Return(nil, nil)
+			},
+			expectedStatuses: map[string][]string{
+				t_helper.SampleRepoPath:  {"not selected", "not selected"},
+				t_helper.SampleGroupPath: {"not selected"},
+				"/path/to/included/repo": {app.TokenStatusNotFound},
+			},
+		},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			g := gm.NewMockGitlabAPI(ctrl)
+			tc.mockGitlab(g)
+
+			selector, err := app.NewSelector(tc.paths, tc.tokens, tc.types, tc.files, tc.tags)
+			assert.NoError(t, err)
+			updater := app.NewGitlabTokenUpdater(genConfig(), g, nil).
+				WithCustomCurrentTime(t_helper.GenTime("2024-01-01")).
+				WithSelector(selector)
+			_ = updater.Do()
+
+			// status of the access tokens, the reason is used for the skipped one if it's exists
+			statuses := map[string][]string{}
+			for _, mr := range updater.Report().Managed {
+				for _, tr := range mr.Tokens {
+					status := tr.Status
+					if tr.Reason != "" {
+						status = tr.Reason
+					}
+					statuses[mr.Path] = append(statuses[mr.Path], status)
+				}
+			}
+			assert.Equal(t, tc.expectedStatuses, statuses)
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package app
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	cfg "github.com/iomarmochtar/gitlab-token-updater/pkg/config"
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	StatusOutputTable = "table"
+	StatusOutputJSON  = "json"
+	StatusOutputYAML  = "yaml"
+	StatusOutputCSV   = "csv"
+	statusDateLayout  = "2006-01-02"
+	statusEmptyValue  = "-"
+	hoursInDay        = 24
+)
+
+var (
+	StatusOutputList = []string{
+		StatusOutputTable,
+		StatusOutputJSON,
+		StatusOutputYAML,
+		StatusOutputCSV,
+	}
+	ErrStatusInvalidOutput = fmt.Errorf("invalid output format, the valid one are %s", strings.Join(StatusOutputList, ","))
+	statusHeaders          = []string{"path", "type", "name", "id", "expires_at", "renew_at", "days_left", "rotate"}
+)
+
+// TokenStatus expiry state of a managed access token
+type TokenStatus struct {
+	Path      string     `json:"path" yaml:"path"`
+	Type      string     `json:"type" yaml:"type"`
+	Name      string     `json:"name" yaml:"name"`
+	ID        int        `json:"id" yaml:"id"`
+	ExpiresAt *time.Time `json:"expires_at" yaml:"expires_at"`
+	RenewAt   *time.Time `json:"renew_at" yaml:"renew_at"`
+	DaysLeft  *int       `json:"days_left" yaml:"days_left"`
+	Rotate    bool       `json:"rotate" yaml:"rotate"`
+}
+
+func (s TokenStatus) row() []string {
+	daysLeft := statusEmptyValue
+	if s.DaysLeft != nil {
+		daysLeft = strconv.Itoa(*s.DaysLeft)
+	}
+	return []string{
+		s.Path,
+		s.Type,
+		s.Name,
+		strconv.Itoa(s.ID),
+		fmtStatusDate(s.ExpiresAt),
+		fmtStatusDate(s.RenewAt),
+		daysLeft,
+		strconv.FormatBool(s.Rotate),
+	}
+}
+
+func fmtStatusDate(tm *time.Time) string {
+	if tm == nil {
+		return statusEmptyValue
+	}
+	return tm.Format(statusDateLayout)
+}
+
+// listSelected list the access tokens of each selected managed token including the missing one that is going to be created,
+// the error in listing is collected in non strict mode
+func (g *GitlabTokenUpdater) listSelected(fn func(mg cfg.ManagedToken, ats []accessTokenPair)) error {
+	for _, mg := range g.config.Managed {
+		logPath := log.With().Str("path", mg.Path).Str("m_type", mg.Type).Logger()
+		selected, isSelected := g.selector.filter(mg)
+		if !isSelected {
+			logPath.Debug().Msg("not selected, skip it")
+			continue
+		}
+		logPath.Debug().Msg("listing access tokens")
+
+		ats, err := g.listAccessTokens(selected)
+		if err != nil {
+			logPath.Error().Err(err).Msg("error while listing access token")
+			if err = g.errAppender(err); err != nil {
+				return err
+			}
+			continue
+		}
+		fn(selected, ats)
+	}
+	return nil
+}
+
+// tokenStatus expiry state of the access token
+func (g *GitlabTokenUpdater) tokenStatus(mg cfg.ManagedToken, at accessTokenPair) TokenStatus {
+	renewAt, validToRenew := g.renewInfo(at)
+	st := TokenStatus{
+		Path:      mg.Path,
+		Type:      mg.Type,
+		Name:      at.cfgAccessToken.Name,
+		ID:        at.glAccessToken.ID,
+		ExpiresAt: at.glAccessToken.ExpiresAt,
+		RenewAt:   renewAt,
+		Rotate:    g.forceRenew || validToRenew,
+	}
+	if st.ExpiresAt != nil {
+		// floored instead of truncated, so the token that is expired within a day is negative instead of 0
+		daysLeft := int(math.Floor(st.ExpiresAt.Sub(*g.now).Hours() / hoursInDay))
+		st.DaysLeft = &daysLeft
+	}
+	return st
+}
+
+// Status list all of managed access token with their expiry, no any write execution will be made
+func (g *GitlabTokenUpdater) Status() (results []TokenStatus, err error) {
+	err = g.listSelected(func(mg cfg.ManagedToken, ats []accessTokenPair) {
+		for _, at := range ats {
+			// the missing one that is going to be created is not listed
+			if !at.missing {
+				results = append(results, g.tokenStatus(mg, at))
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return results, g.collectedErrors()
+}
+
+// RenderStatus write the token status in the given output format
+func RenderStatus(w io.Writer, format string, statuses []TokenStatus) error {
+	switch format {
+	case StatusOutputTable:
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(tw, strings.ToUpper(strings.Join(statusHeaders, "\t")))
+		for _, st := range statuses {
+			_, _ = fmt.Fprintln(tw, strings.Join(st.row(), "\t"))
+		}
+		return tw.Flush()
+	case StatusOutputCSV:
+		cw := csv.NewWriter(w)
+		records := [][]string{statusHeaders}
+		for _, st := range statuses {
+			records = append(records, st.row())
+		}
+		return cw.WriteAll(records)
+	case StatusOutputJSON:
+		if statuses == nil {
+			statuses = []TokenStatus{}
+		}
+		return writeJSON(w, statuses)
+	case StatusOutputYAML:
+		content, err := yaml.Marshal(statuses)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(content)
+		return err
+	}
+
+	return ErrStatusInvalidOutput
+}
@@ -0,0 +1,324 @@
+package app_test
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/iomarmochtar/gitlab-token-updater/app"
+	cfg "github.com/iomarmochtar/gitlab-token-updater/pkg/config"
+	gl "github.com/iomarmochtar/gitlab-token-updater/pkg/gitlab"
+	t_helper "github.com/iomarmochtar/gitlab-token-updater/test"
+	gm "github.com/iomarmochtar/gitlab-token-updater/test/mocks/gitlab"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func TestGitlabTokenUpdater_Status(t *testing.T) {
+	testCases := map[string]struct {
+		config         func() *cfg.Config
+		mockGitlab     func(*gomock.Controller) *gm.MockGitlabAPI
+		forceNew       bool
+		strict         bool
+		currentTime    *time.Time
+		expected       []app.TokenStatus
+		expectedErrMsg string
+	}{
+		"ok: token will be rotated and the other is not": {
+			config: func() *cfg.Config {
+				anotherManageTokens := t_helper.GenManageTokens(nil, nil, nil)
+				anotherManageTokens[0].Type = cfg.ManagedTypeGroup
+				anotherManageTokens[0].Path = t_helper.SampleGroupPath
+				anotherManageTokens[0].Tokens[0].Hooks[0] = t_helper.SampleHookUpdateVarGroup
+				return t_helper.GenConfig(anotherManageTokens, nil, nil)
+			},
+			currentTime: t_helper.GenTime("2024-04-05"),
+			mockGitlab: func(ctrl *gomock.Controller) *gm.MockGitlabAPI {
+				g := gm.NewMockGitlabAPI(ctrl)
+				g.EXPECT().ListRepoAccessToken(t_helper.SampleRepoPath).Return([]gl.GitlabAccessToken{t_helper.SampleRepoAccessToken}, nil)
+				groupToken := t_helper.SampleGroupAccessToken
+				groupToken.ID = 456
+				groupToken.ExpiresAt = t_helper.GenTime("2024-06-01")
+				g.EXPECT().ListGroupAccessToken(t_helper.SampleGroupPath).Return([]gl.GitlabAccessToken{groupToken}, nil)
+				return g
+			},
+			expected: []app.TokenStatus{
+				{
+					Path:      t_helper.SampleRepoPath,
+					Type:      cfg.ManagedTypeRepository,
+					Name:      t_helper.SampleAccessTokeName,
+					ID:        123,
+					ExpiresAt: t_helper.GenTime("2024-05-01"),
+					RenewAt:   t_helper.GenTime("2024-04-01"),
+					DaysLeft:  intPtr(26),
+					Rotate:    true,
+				},
+				{
+					Path:      t_helper.SampleGroupPath,
+					Type:      cfg.ManagedTypeGroup,
+					Name:      t_helper.SampleAccessTokeName,
+					ID:        456,
+					ExpiresAt: t_helper.GenTime("2024-06-01"),
+					RenewAt:   t_helper.GenTime("2024-05-02"),
+					DaysLeft:  intPtr(57),
+					Rotate:    false,
+				},
+			},
+		},
+		"ok: access token without expiry": {
+			config: func() *cfg.Config {
+				return t_helper.GenConfig(nil, nil, nil)
+			},
+			currentTime: t_helper.GenTime("2024-04-05"),
+			mockGitlab: func(ctrl *gomock.Controller) *gm.MockGitlabAPI {
+				g := gm.NewMockGitlabAPI(ctrl)
+				token := t_helper.SampleRepoAccessToken
+				token.ExpiresAt = nil
+				g.EXPECT().ListRepoAccessToken(t_helper.SampleRepoPath).Return([]gl.GitlabAccessToken{token}, nil)
+				return g
+			},
+			expected: []app.TokenStatus{
+				{
+					Path: t_helper.SampleRepoPath,
+					Type: cfg.ManagedTypeRepository,
+					Name: t_helper.SampleAccessTokeName,
+					ID:   123,
+				},
+			},
+		},
+		"ok: force mode marks all as rotated": {
+			config: func() *cfg.Config {
+				return t_helper.GenConfig(nil, nil, nil)
+			},
+			currentTime: t_helper.GenTime("2024-01-01"),
+			forceNew:    true,
+			mockGitlab: func(ctrl *gomock.Controller) *gm.MockGitlabAPI {
+				g := gm.NewMockGitlabAPI(ctrl)
+				g.EXPECT().ListRepoAccessToken(t_helper.SampleRepoPath).Return([]gl.GitlabAccessToken{t_helper.SampleRepoAccessToken}, nil)
+				return g
+			},
+			expected: []app.TokenStatus{
+				{
+					Path:      t_helper.SampleRepoPath,
+					Type:      cfg.ManagedTypeRepository,
+					Name:      t_helper.SampleAccessTokeName,
+					ID:        123,
+					ExpiresAt: t_helper.GenTime("2024-05-01"),
+					RenewAt:   t_helper.GenTime("2024-04-01"),
+					DaysLeft:  intPtr(121),
+					Rotate:    true,
+				},
+			},
+		},
+		"err: error in listing is collected then returned in the end": {
+			config: func() *cfg.Config {
+				c := t_helper.GenConfig(t_helper.GenManageTokens(nil, nil, nil), nil, nil)
+				c.Managed[0].Path = "/first"
+				c.Managed[1].Path = "/second"
+				return c
+			},
+			currentTime: t_helper.GenTime("2024-04-05"),
+			mockGitlab: func(ctrl *gomock.Controller) *gm.MockGitlabAPI {
+				g := gm.NewMockGitlabAPI(ctrl)
+				g.EXPECT().ListRepoAccessToken("/first").Return(nil, fmt.Errorf("error in listing access token"))
+				g.EXPECT().ListRepoAccessToken("/second").Return([]gl.GitlabAccessToken{t_helper.SampleRepoAccessToken}, nil)
+				return g
+			},
+			expected: []app.TokenStatus{
+				{
+					Path:      "/second",
+					Type:      cfg.ManagedTypeRepository,
+					Name:      t_helper.SampleAccessTokeName,
+					ID:        123,
+					ExpiresAt: t_helper.GenTime("2024-05-01"),
+					RenewAt:   t_helper.GenTime("2024-04-01"),
+					DaysLeft:  intPtr(26),
+					Rotate:    true,
+				},
+			},
+			expectedErrMsg: "some error(s) occured during execution",
+		},
+		"strict: error in listing is returned immediately": {
+			config: func() *cfg.Config {
+				return t_helper.GenConfig(nil, nil, nil)
+			},
+			strict: true,
+			mockGitlab: func(ctrl *gomock.Controller) *gm.MockGitlabAPI {
+				g := gm.NewMockGitlabAPI(ctrl)
+				g.EXPECT().ListRepoAccessToken(t_helper.SampleRepoPath).Return(nil, fmt.Errorf("error in listing access token"))
+				return g
+			},
+			expectedErrMsg: "error in listing access token",
+		},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			config := tc.config()
+			assert.NoError(t, config.InitValues())
+
+			updater := app.NewGitlabTokenUpdater(config, tc.mockGitlab(ctrl), nil).
+				WithForceRenew(tc.forceNew).
+				WithStrictMode(tc.strict)
+			if tc.currentTime != nil {
+				updater.WithCustomCurrentTime(tc.currentTime)
+			}
+
+			results, err := updater.Status()
+			if tc.expectedErrMsg != "" {
+				assert.EqualError(t, err, tc.expectedErrMsg)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.expected, results)
+		})
+	}
+}
+
+func TestGitlabTokenUpdater_Status_DaysLeft(t *testing.T) {
+	now := t_helper.GenTime("2024-04-05")
+	testCases := map[string]struct {
+		expiresIn time.Duration
+		expected  int
+	}{
+		"expires in 23 hours":  {expiresIn: 23 * time.Hour, expected: 0},
+		"expires in a day":     {expiresIn: 24 * time.Hour, expected: 1},
+		"expires right now":    {expiresIn: 0, expected: 0},
+		"expired 23 hours ago": {expiresIn: -23 * time.Hour, expected: -1},
+		"expired a day ago":    {expiresIn: -24 * time.Hour, expected: -1},
+		"expired 25 hours ago": {expiresIn: -25 * time.Hour, expected: -2},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			config := t_helper.GenConfig(nil, nil, nil)
+			assert.NoError(t, config.InitValues())
+
+			token := t_helper.SampleRepoAccessToken
+			expiresAt := now.Add(tc.expiresIn)
+			token.ExpiresAt = &expiresAt
+			g := gm.NewMockGitlabAPI(ctrl)
+			g.EXPECT().ListRepoAccessToken(t_helper.SampleRepoPath).Return([]gl.GitlabAccessToken{token}, nil)
+
+			results, err := app.NewGitlabTokenUpdater(config, g, nil).WithCustomCurrentTime(now).Status()
+			assert.NoError(t, err)
+			if assert.Len(t, results, 1) {
+				assert.Equal(t, intPtr(tc.expected), results[0].DaysLeft)
+			}
+		})
+	}
+}
+
+func TestRenderStatus(t *testing.T) {
+	statuses := []app.TokenStatus{
+		{
+			Path:      t_helper.SampleRepoPath,
+			Type:      cfg.ManagedTypeRepository,
+			Name:      t_helper.SampleAccessTokeName,
+			ID:        123,
+			ExpiresAt: t_helper.GenTime("2024-05-01"),
+			RenewAt:   t_helper.GenTime("2024-04-01"),
+			DaysLeft:  intPtr(26),
+			Rotate:    true,
+		},
+		{
+			Path: t_helper.SampleGroupPath,
+			Type: cfg.ManagedTypeGroup,
+			Name: "no expiry",
+			ID:   456,
+		},
+	}
+
+	testCases := map[string]struct {
+		format         string
+		expected       string
+		expectedErrMsg string
+	}{
+		"table": {
+			format: app.StatusOutputTable,
+			expected: "PATH            TYPE        NAME        ID   EXPIRES_AT  RENEW_AT    DAYS_LEFT  ROTATE\n" +
+				"/path/to/repo   repository  MR Handler  123  2024-05-01  2024-04-01  26         true\n" +
+				"/path/to/group  group       no expiry   456  -           -           -          false\n",
+		},
+		"csv": {
+			format: app.StatusOutputCSV,
+			expected: "path,type,name,id,expires_at,renew_at,days_left,rotate\n" +
+				"/path/to/repo,repository,MR Handler,123,2024-05-01,2024-04-01,26,true\n" +
+				"/path/to/group,group,no expiry,456,-,-,-,false\n",
+		},
+		"json": {
+			format: app.StatusOutputJSON,
+			expected: `[
+  {
+    "path": "/path/to/repo",
+    "type": "repository",
+    "name": "MR Handler",
+    "id": 123,
+    "expires_at": "2024-05-01T00:00:00Z",
+    "renew_at": "2024-04-01T00:00:00Z",
+    "days_left": 26,
+    "rotate": true
+  },
+  {
+    "path": "/path/to/group",
+    "type": "group",
+    "name": "no expiry",
+    "id": 456,
+    "expires_at": null,
+    "renew_at": null,
+    "days_left": null,
+    "rotate": false
+  }
+]
+`,
+		},
+		"yaml": {
+			format: app.StatusOutputYAML,
+			expected: `- path: /path/to/repo
+  type: repository
+  name: MR Handler
+  id: 123
+  expires_at: 2024-05-01T00:00:00Z
+  renew_at: 2024-04-01T00:00:00Z
+  days_left: 26
+  rotate: true
+- path: /path/to/group
+  type: group
+  name: no expiry
+  id: 456
+  expires_at: null
+  renew_at: null
+  days_left: null
+  rotate: false
+`,
+		},
+		"err: unknown format": {
+			format:         "xml",
+			expectedErrMsg: app.ErrStatusInvalidOutput.Error(),
+		},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			err := app.RenderStatus(buf, tc.format, statuses)
+			if tc.expectedErrMsg != "" {
+				assert.EqualError(t, err, tc.expectedErrMsg)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, buf.String())
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package app
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	cfg "github.com/iomarmochtar/gitlab-token-updater/pkg/config"
+)
+
+const (
+	ValidateOutputText        = "text"
+	ValidateOutputJSON        = "json"
+	ValidateOutputCodeQuality = "codequality"
+	codeQualityCheckName      = "gitlab-token-updater-config"
+	codeQualitySeverity       = "major"
+)
+
+var (
+	ValidateOutputList = []string{
+		ValidateOutputText,
+		ValidateOutputJSON,
+		ValidateOutputCodeQuality,
+	}
+	ErrValidateInvalidOutput = fmt.Errorf("invalid output format, the valid one are %s", strings.Join(ValidateOutputList, ","))
+	ErrInvalidConfig         = errors.New("invalid configuration")
+)
+
+// ValidationIssue machine readable form of config validation error
+type ValidationIssue struct {
+	File       string   `json:"file"`
+	Line       int      `json:"line"`
+	Message    string   `json:"message"`
+	References []string `json:"references"`
+}
+
+// codeQualityIssue issue in Gitlab code quality report format
+type codeQualityIssue struct {
+	Description string              `json:"description"`
+	CheckName   string              `json:"check_name"`
+	Fingerprint string              `json:"fingerprint"`
+	Severity    string              `json:"severity"`
+	Location    codeQualityLocation `json:"location"`
+}
+
+type codeQualityLocation struct {
+	Path  string `json:"path"`
+	Lines struct {
+		Begin int `json:"begin"`
+	} `json:"lines"`
+}
+
+func newValidationIssue(vErr cfg.ValidationError) ValidationIssue {
+	refs := vErr.References
+	if refs == nil {
+		refs = []string{}
+	}
+	return ValidationIssue{
+		File:       vErr.File,
+		Line:       vErr.Line,
+		Message:    strings.ReplaceAll(vErr.Err.Error(), "\n", "; "),
+		References: refs,
+	}
+}
+
+func (i ValidationIssue) codeQuality() codeQualityIssue {
+	description := i.Message
+	if len(i.References) > 0 {
+		description = fmt.Sprintf("%s (%s)", description, strings.Join(i.References, ", "))
+	}
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s:%d:%s", i.File, i.Line, description)))
+
+	issue := codeQualityIssue{
+		Description: description,
+		CheckName:   codeQualityCheckName,
+		Fingerprint: hex.EncodeToString(sum[:]),
+		Severity:    codeQualitySeverity,
+		Location:    codeQualityLocation{Path: i.File},
+	}
+	// line number is mandatory in code quality report
+	issue.Location.Lines.Begin = max(i.Line, 1)
+	return issue
+}
+
+func (i ValidationIssue) String() string {
+	location := i.File
+	if i.Line > 0 {
+		location = fmt.Sprintf("%s:%d", location, i.Line)
+	}
+	msg := i.Message
+	if len(i.References) > 0 {
+		msg = fmt.Sprintf("%s [%s]", msg, strings.Join(i.References, "; "))
+	}
+	if location == "" {
+		return msg
+	}
+	return fmt.Sprintf("%s: %s", location, msg)
+}
+
+// RenderValidationErrors write the config validation errors in the given output format
+func RenderValidationErrors(w io.Writer, format string, vErrs cfg.ValidationErrors) (err error) {
+	issues := make([]ValidationIssue, len(vErrs))
+	for idx := range vErrs {
+		issues[idx] = newValidationIssue(vErrs[idx])
+	}
+
+	switch format {
+	case ValidateOutputText:
+		if len(issues) == 0 {
+			_, err = fmt.Fprintln(w, "configuration is valid")
+			return err
+		}
+		for _, issue := range issues {
+			if _, err = fmt.Fprintln(w, issue.String()); err != nil {
+				return err
+			}
+		}
+		_, err = fmt.Fprintf(w, "found %d error(s)\n", len(issues))
+		return err
+	case ValidateOutputJSON:
+		return writeJSON(w, issues)
+	case ValidateOutputCodeQuality:
+		cqIssues := make([]codeQualityIssue, len(issues))
+		for idx := range issues {
+			cqIssues[idx] = issues[idx].codeQuality()
+		}
+		return writeJSON(w, cqIssues)
+	}
+
+	return ErrValidateInvalidOutput
+}
+
+func writeJSON(w io.Writer, obj any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(obj)
+}
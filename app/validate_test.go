@@ -0,0 +1,127 @@
+package app_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/iomarmochtar/gitlab-token-updater/app"
+	cfg "github.com/iomarmochtar/gitlab-token-updater/pkg/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderValidationErrors(t *testing.T) {
+	vErrs := cfg.ValidationErrors{
+		{
+			Err:  cfg.ErrValidationEmptyHost,
+			File: "config.yml",
+			Line: 1,
+		},
+		{
+			Err:  cfg.ErrValidationHookExecCMDMissingPath,
+			File: "include.yml",
+			Line: 12,
+			References: []string{
+				"reference: include.yml",
+				"managed_token seq num: 1 (type: repository, path: path/to/repo)",
+			},
+		},
+		{
+			Err: errors.Join(cfg.ErrValidationInvalidDefaultRenewBefore, errors.New("1m is not match with duration pattern")),
+		},
+	}
+
+	testCases := map[string]struct {
+		format         string
+		vErrs          cfg.ValidationErrors
+		expected       string
+		expectedErrMsg string
+	}{
+		"text": {
+			format: app.ValidateOutputText,
+			vErrs:  vErrs,
+			expected: "config.yml:1: empty host\n" +
+				"include.yml:12: missing arg path or script in exec_cmd hook [reference: include.yml; managed_token seq num: 1 (type: repository, path: path/to/repo)]\n" +
+				"invalid default renew before value; 1m is not match with duration pattern\n" +
+				"found 3 error(s)\n",
+		},
+		"text: no error found": {
+			format:   app.ValidateOutputText,
+			expected: "configuration is valid\n",
+		},
+		"json": {
+			format: app.ValidateOutputJSON,
+			vErrs:  vErrs[:2],
+			expected: `[
+  {
+    "file": "config.yml",
+    "line": 1,
+    "message": "empty host",
+    "references": []
+  },
+  {
+    "file": "include.yml",
+    "line": 12,
+    "message": "missing arg path or script in exec_cmd hook",
+    "references": [
+      "reference: include.yml",
+      "managed_token seq num: 1 (type: repository, path: path/to/repo)"
+    ]
+  }
+]
+`,
+		},
+		"json: no error found": {
+			format:   app.ValidateOutputJSON,
+			expected: "[]\n",
+		},
+		"codequality": {
+			format: app.ValidateOutputCodeQuality,
+			vErrs:  vErrs[1:],
+			expected: `[
+  {
+    "description": "missing arg path or script in exec_cmd hook (reference: include.yml, managed_token seq num: 1 (type: repository, path: path/to/repo))",
+    "check_name": "gitlab-token-updater-config",
+    "fingerprint": "d55763e8f6202b452a6cee6fa39469e002c8c06b7be9f761ee9152da23cd9811",
+    "severity": "major",
+    "location": {
+      "path": "include.yml",
+      "lines": {
+        "begin": 12
+      }
+    }
+  },
+  {
+    "description": "invalid default renew before value; 1m is not match with duration pattern",
+    "check_name": "gitlab-token-updater-config",
+    "fingerprint": "3a1182b4e1981dfd7ff3e16491dc8cad7f5eda9db6d298eee296fbfbdfb8221f",
+    "severity": "major",
+    "location": {
+      "path": "",
+      "lines": {
+        "begin": 1
+      }
+    }
+  }
+]
+`,
+		},
+		"err: unknown format": {
+			format:         "xml",
+			expectedErrMsg: app.ErrValidateInvalidOutput.Error(),
+		},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			err := app.RenderValidationErrors(buf, tc.format, tc.vErrs)
+			if tc.expectedErrMsg != "" {
+				assert.EqualError(t, err, tc.expectedErrMsg)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, buf.String())
+		})
+	}
+}
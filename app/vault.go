@@ -0,0 +1,87 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+
+	cfg "github.com/iomarmochtar/gitlab-token-updater/pkg/config"
+	"github.com/iomarmochtar/gitlab-token-updater/pkg/vault"
+	"github.com/rs/zerolog"
+)
+
+// vaultCASAttempts the maximum read-modify-write attempts when the secret is modified concurrently
+const vaultCASAttempts = 3
+
+// VaultInitFunc initiate the authenticated Vault API by it's client config
+type VaultInitFunc func(vault.ClientConfig) (vault.VaultAPI, error)
+
+// writeVaultKV read-modify-write the key of the secret with check-and-set, so the other keys are preserved
+func writeVaultKV(vaultAPI vault.VaultAPI, args cfg.HookVaultKV, value string, logSecret zerolog.Logger) error {
+	data, version, err := vaultAPI.ReadKV(args.Mount, args.Path)
+	if errors.Is(err, vault.ErrNotFound) {
+		if !args.CreateIfMissing {
+			return fmt.Errorf("secret %s in mount %s: %w", args.Path, args.Mount, err)
+		}
+		// the latest version may be deleted while it's metadata still exists, the check-and-set must be it's version
+		if version, err = vaultAPI.ReadMetadata(args.Mount, args.Path); errors.Is(err, vault.ErrNotFound) {
+			version, err = 0, nil
+		}
+		if err != nil {
+			return err
+		}
+		logSecret.Warn().Msg("creating the missing secret")
+	} else if err != nil {
+		return err
+	}
+
+	if data == nil {
+		data = map[string]any{}
+	}
+	data[args.Key] = value
+	return vaultAPI.WriteKV(args.Mount, args.Path, data, version)
+}
+
+// updateVaultKV update the key of Vault KV v2 secret, it's retried if the secret is modified concurrently by the same login.
+// The issued client token is revoked afterwards. In dry run mode, only reading the secret metadata for confirming the access
+func (g GitlabTokenUpdater) updateVaultKV(logHook zerolog.Logger, args cfg.HookVaultKV, value string) error {
+	vaultAPI, err := g.vaultInit(vault.ClientConfig{
+		Address:    args.Address,
+		Namespace:  args.Namespace,
+		CACert:     args.CACert,
+		AuthMethod: args.AuthMethod,
+		AuthMount:  args.AuthMount,
+		Token:      args.Token,
+		RoleID:     args.RoleID,
+		SecretID:   args.SecretID,
+		Role:       args.Role,
+		JWT:        args.JWT,
+	})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if errClose := vaultAPI.Close(); errClose != nil {
+			logHook.Warn().Err(errClose).Msg("the vault client token is left until it's TTL")
+		}
+	}()
+
+	logSecret := logHook.With().Str("mount", args.Mount).Str("secret", args.Path).Str("key", args.Key).Logger()
+	if g.dryRun {
+		_, err = vaultAPI.ReadMetadata(args.Mount, args.Path)
+		if errors.Is(err, vault.ErrNotFound) && args.CreateIfMissing {
+			logSecret.Warn().Msg("would create the missing secret")
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("secret %s in mount %s: %w", args.Path, args.Mount, err)
+		}
+		return nil
+	}
+
+	for attempt := 1; ; attempt++ {
+		err = writeVaultKV(vaultAPI, args, value, logSecret)
+		if !errors.Is(err, vault.ErrCASMismatch) || attempt >= vaultCASAttempts {
+			return err
+		}
+		logSecret.Warn().Int("attempt", attempt).Msg("the secret is modified concurrently, retrying")
+	}
+}
@@ -0,0 +1,139 @@
+package app_test
+
+import (
+	"testing"
+
+	"github.com/iomarmochtar/gitlab-token-updater/app"
+	cfg "github.com/iomarmochtar/gitlab-token-updater/pkg/config"
+	gl "github.com/iomarmochtar/gitlab-token-updater/pkg/gitlab"
+	"github.com/iomarmochtar/gitlab-token-updater/pkg/vault"
+	t_helper "github.com/iomarmochtar/gitlab-token-updater/test"
+	gm "github.com/iomarmochtar/gitlab-token-updater/test/mocks/gitlab"
+	vm "github.com/iomarmochtar/gitlab-token-updater/test/mocks/vault"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestGitlabTokenUpdater_Do_VaultKV(t *testing.T) {
+	newToken := "glpat-newnew"
+
+	testCases := map[string]struct {
+		args           map[string]any
+		envVars        map[string]string
+		dryRun         bool
+		mockVault      func(v *vm.MockVaultAPI)
+		expectedClient vault.ClientConfig
+		expectedErrMsg string
+	}{
+		"ok: read-modify-write preserving the other keys": {
+			args: map[string]any{"address": "https://vault.example.com", "path": "app/gitlab", "key": "token"},
+			mockVault: func(v *vm.MockVaultAPI) {
+				v.EXPECT().ReadKV("secret", "app/gitlab").Return(map[string]any{"token": "old", "user": "bot"}, 3, nil)
+				v.EXPECT().WriteKV("secret", "app/gitlab", map[string]any{"token": newToken, "user": "bot"}, 3).Return(nil)
+			},
+			expectedClient: vault.ClientConfig{Address: "https://vault.example.com", AuthMethod: cfg.VaultAuthMethodToken},
+		},
+		"ok: jwt login and retry on check-and-set mismatch": {
+			envVars: map[string]string{"VAULT_ID_TOKEN": "eyJ"},
+			args: map[string]any{
+				"mount": "kv", "path": "app/gitlab", "key": "token",
+				"auth_method": cfg.VaultAuthMethodJWT, "auth_mount": "gitlab", "role": "rotator", "jwt": "${VAULT_ID_TOKEN}",
+			},
+			mockVault: func(v *vm.MockVaultAPI) {
+				gomock.InOrder(
+					v.EXPECT().ReadKV("kv", "app/gitlab").Return(map[string]any{"token": "old"}, 3, nil),
+					v.EXPECT().WriteKV("kv", "app/gitlab", map[string]any{"token": newToken}, 3).Return(vault.ErrCASMismatch),
+					v.EXPECT().ReadKV("kv", "app/gitlab").Return(map[string]any{"token": "old", "user": "bot"}, 4, nil),
+					v.EXPECT().WriteKV("kv", "app/gitlab", map[string]any{"token": newToken, "user": "bot"}, 4).Return(nil),
+				)
+			},
+			expectedClient: vault.ClientConfig{AuthMethod: cfg.VaultAuthMethodJWT, AuthMount: "gitlab", Role: "rotator", JWT: "eyJ"},
+		},
+		"ok: create the missing secret with the rendered template": {
+			args: map[string]any{
+				"path": "app/gitlab", "key": "url", "create_if_missing": true,
+				"auth_method": cfg.VaultAuthMethodAppRole, "role_id": "role-id", "secret_id": "secret-id",
+				"template": "https://oauth2:{{ .Token }}@{{ .Hostname }}",
+			},
+			mockVault: func(v *vm.MockVaultAPI) {
+				v.EXPECT().ReadKV("secret", "app/gitlab").Return(nil, 0, vault.ErrNotFound)
+				v.EXPECT().ReadMetadata("secret", "app/gitlab").Return(0, vault.ErrNotFound)
+				v.EXPECT().WriteKV("secret", "app/gitlab", map[string]any{"url": "https://oauth2:" + newToken + "@gitlab.com"}, 0).Return(nil)
+			},
+			expectedClient: vault.ClientConfig{AuthMethod: cfg.VaultAuthMethodAppRole, RoleID: "role-id", SecretID: "secret-id"},
+		},
+		"fail: the missing secret is not created": {
+			args: map[string]any{"path": "app/gitlab", "key": "token"},
+			mockVault: func(v *vm.MockVaultAPI) {
+				v.EXPECT().ReadKV("secret", "app/gitlab").Return(nil, 0, vault.ErrNotFound)
+			},
+			expectedClient: vault.ClientConfig{AuthMethod: cfg.VaultAuthMethodToken},
+			expectedErrMsg: "some error(s) occured during execution",
+		},
+		"fail: check-and-set mismatch in all attempts": {
+			args: map[string]any{"path": "app/gitlab", "key": "token"},
+			mockVault: func(v *vm.MockVaultAPI) {
+				v.EXPECT().ReadKV("secret", "app/gitlab").Return(map[string]any{}, 3, nil).Times(3)
+				v.EXPECT().WriteKV("secret", "app/gitlab", gomock.Any(), 3).Return(vault.ErrCASMismatch).Times(3)
+			},
+			expectedClient: vault.ClientConfig{AuthMethod: cfg.VaultAuthMethodToken},
+			expectedErrMsg: "some error(s) occured during execution",
+		},
+		"ok: dry run read the metadata": {
+			dryRun: true,
+			args:   map[string]any{"path": "app/gitlab", "key": "token"},
+			mockVault: func(v *vm.MockVaultAPI) {
+				v.EXPECT().ReadMetadata("secret", "app/gitlab").Return(3, nil)
+			},
+			expectedClient: vault.ClientConfig{AuthMethod: cfg.VaultAuthMethodToken},
+		},
+		"fail: dry run with the missing secret": {
+			dryRun: true,
+			args:   map[string]any{"path": "app/gitlab", "key": "token"},
+			mockVault: func(v *vm.MockVaultAPI) {
+				v.EXPECT().ReadMetadata("secret", "app/gitlab").Return(0, vault.ErrNotFound)
+			},
+			expectedClient: vault.ClientConfig{AuthMethod: cfg.VaultAuthMethodToken},
+			expectedErrMsg: "some error(s) occured during execution",
+		},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			for k, v := range tc.envVars {
+				t.Setenv(k, v)
+			}
+
+			config := t_helper.GenConfig(nil, nil, nil)
+			config.Managed[0].Tokens[0].Hooks = []cfg.Hook{{Type: cfg.HookTypeVaultKV, Args: tc.args}}
+			assert.NoError(t, config.InitValues())
+
+			g := gm.NewMockGitlabAPI(ctrl)
+			g.EXPECT().ListRepoAccessToken(t_helper.SampleRepoPath).Return([]gl.GitlabAccessToken{t_helper.SampleRepoAccessToken}, nil)
+			if !tc.dryRun {
+				g.EXPECT().RotateRepoToken(t_helper.SampleRepoPath, 123, *t_helper.GenTime("2024-07-04")).Return(newToken, nil)
+			}
+
+			v := vm.NewMockVaultAPI(ctrl)
+			tc.mockVault(v)
+			v.EXPECT().Close().Return(nil)
+			vaultInit := func(clientCfg vault.ClientConfig) (vault.VaultAPI, error) {
+				assert.Equal(t, tc.expectedClient, clientCfg)
+				return v, nil
+			}
+
+			err := app.NewGitlabTokenUpdater(config, g, nil).
+				WithCustomCurrentTime(t_helper.GenTime("2024-04-05")).
+				WithDryRun(tc.dryRun).
+				WithVaultInit(vaultInit).
+				Do()
+			if tc.expectedErrMsg != "" {
+				assert.EqualError(t, err, tc.expectedErrMsg)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,476 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+
+	"filippo.io/age"
+	"github.com/rs/zerolog/log"
+	"github.com/urfave/cli/v2"
+
+	"github.com/iomarmochtar/gitlab-token-updater/app"
+	cfg "github.com/iomarmochtar/gitlab-token-updater/pkg/config"
+	gl "github.com/iomarmochtar/gitlab-token-updater/pkg/gitlab"
+	"github.com/iomarmochtar/gitlab-token-updater/pkg/recovery"
+)
+
+var (
+	reloadModeCycle      = "cycle"
+	reloadModeSignal     = "signal"
+	reloadModeList       = []string{reloadModeCycle, reloadModeSignal}
+	errInvalidReloadMode = fmt.Errorf("invalid reload mode, the valid one are %s", strings.Join(reloadModeList, ","))
+
+	errRevokeWithoutSelector = errors.New("at least one of selector (--path, --token, --type, --file or --tags) is required")
+	errRevokeAborted         = errors.New("revocation is aborted")
+	errApplyWithoutPlan      = errors.New("path of the plan file is required")
+)
+
+// selectorFlags flags for selecting the managed and access tokens to be processed
+func selectorFlags() []cli.Flag {
+	patternUsage := fmt.Sprintf("glob or regex (prefixed by `%s`), can be set multiple times", app.SelectorRegexPrefix)
+	return []cli.Flag{
+		&cli.StringSliceFlag{
+			Name:  "path",
+			Usage: "only process the managed tokens by path, " + patternUsage,
+		},
+		&cli.StringSliceFlag{
+			Name:  "token",
+			Usage: "only process the access tokens by name, " + patternUsage,
+		},
+		&cli.StringSliceFlag{
+			Name:  "type",
+			Usage: "only process the managed tokens by type, " + patternUsage,
+		},
+		&cli.StringSliceFlag{
+			Name:  "file",
+			Usage: "only process the managed tokens defined in the config or included file (path or name), " + patternUsage,
+		},
+		&cli.StringSliceFlag{
+			Name:  "tags",
+			Usage: "only process the access tokens or their managed tokens that have any of the tags",
+		},
+	}
+}
+
+// newSelector create selector from the selector flags, nil returned if none of them is set
+func newSelector(ctx *cli.Context) (*app.Selector, error) {
+	paths, tokens, types := ctx.StringSlice("path"), ctx.StringSlice("token"), ctx.StringSlice("type")
+	files, tags := ctx.StringSlice("file"), ctx.StringSlice("tags")
+	if len(paths)+len(tokens)+len(types)+len(files)+len(tags) == 0 {
+		return nil, nil
+	}
+	return app.NewSelector(paths, tokens, types, files, tags)
+}
+
+// statusCommand sub command for reporting the expiry of managed access tokens
+func statusCommand() *cli.Command {
+	return &cli.Command{
+		Name:    "status",
+		Aliases: []string{"list"},
+		Usage:   "report the expiry of each managed access token without rotating anything",
+		Flags: []cli.Flag{
+			outputFlag(app.StatusOutputList, app.StatusOutputTable, app.ErrStatusInvalidOutput),
+		},
+		Action: func(ctx *cli.Context) error {
+			updater, err := newUpdater(ctx)
+			if err != nil {
+				return err
+			}
+
+			statuses, errStatus := updater.Status()
+			if err = app.RenderStatus(ctx.App.Writer, ctx.String("output"), statuses); err != nil {
+				return err
+			}
+			return errStatus
+		},
+	}
+}
+
+// validateCommand sub command for validating the configuration offline
+func validateCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "validate",
+		Usage: "validate the configuration file offline and report all of the found errors, no gitlab token is required",
+		Flags: []cli.Flag{
+			outputFlag(app.ValidateOutputList, app.ValidateOutputText, app.ErrValidateInvalidOutput),
+		},
+		Action: func(ctx *cli.Context) error {
+			configPath, err := requiredConfigPath(ctx)
+			if err != nil {
+				return err
+			}
+
+			var vErrs cfg.ValidationErrors
+			err = cfg.ValidateYAMLConfigFile(configPath)
+			if err != nil && !errors.As(err, &vErrs) {
+				return err
+			}
+
+			if err = app.RenderValidationErrors(ctx.App.Writer, ctx.String("output"), vErrs); err != nil {
+				return err
+			}
+
+			if len(vErrs) > 0 {
+				return app.ErrInvalidConfig
+			}
+			return nil
+		},
+	}
+}
+
+// revokeCommand sub command for revoking the leaked access tokens immediately
+func revokeCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "revoke",
+		Usage: "revoke the selected access tokens immediately, at least one of selector flags is required",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "rotate",
+				Usage: "rotate the access tokens instead, the current ones are revoked and the hooks are executed for the replacements",
+			},
+			&cli.BoolFlag{
+				Name:    "yes",
+				Aliases: []string{"y"},
+				Usage:   "skip the confirmation prompt",
+			},
+		},
+		Action: func(ctx *cli.Context) error {
+			selector, err := newSelector(ctx)
+			if err != nil {
+				return err
+			}
+			if selector == nil {
+				return errRevokeWithoutSelector
+			}
+
+			updater, err := newUpdater(ctx)
+			if err != nil {
+				return err
+			}
+
+			targets, err := updater.RevokeTargets()
+			if err != nil {
+				return err
+			}
+			if err = app.RenderStatus(ctx.App.Writer, app.StatusOutputTable, app.RevokeTargetStatuses(targets)); err != nil {
+				return err
+			}
+
+			if !ctx.Bool("dry-run") && !ctx.Bool("yes") {
+				action := "revoke"
+				if ctx.Bool("rotate") {
+					action = "revoke and rotate"
+				}
+				if !confirm(ctx, fmt.Sprintf("%s %d access token(s)?", action, len(targets))) {
+					return errRevokeAborted
+				}
+			}
+
+			err = updater.Revoke(targets, ctx.Bool("rotate"))
+			if reportPath := ctx.String("report"); reportPath != "" {
+				if errReport := writeReport(reportPath, ctx.String("report-format"), updater.Report()); errReport != nil {
+					return errors.Join(err, errReport)
+				}
+			}
+			return err
+		},
+	}
+}
+
+// planCommand sub command for writing the reviewable plan of the execution
+func planCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "plan",
+		Usage: "evaluate the selected access tokens as the main execution does then write the plan of the ones that are going to be rotated or created, nothing is changed",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "out",
+				Aliases: []string{"o"},
+				Usage:   "path of the written plan file, it's printed to stdout if it's not set",
+			},
+		},
+		Action: func(ctx *cli.Context) error {
+			updater, err := newUpdater(ctx)
+			if err != nil {
+				return err
+			}
+
+			plan, errPlan := updater.Plan()
+			if plan == nil {
+				return errPlan
+			}
+
+			planPath := ctx.String("out")
+			if planPath == "" {
+				return errors.Join(app.WritePlan(ctx.App.Writer, plan), errPlan)
+			}
+			if err = writePlan(planPath, plan); err != nil {
+				return errors.Join(err, errPlan)
+			}
+			log.Info().Str("path", planPath).Int("tokens", len(plan.Tokens)).Msg("plan written")
+			return errPlan
+		},
+	}
+}
+
+// applyCommand sub command for executing the plan file
+func applyCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "apply",
+		Usage:     "execute only the planned access tokens in the plan file, nothing is executed if any of them is drifted since planning",
+		ArgsUsage: "PLAN_FILE",
+		Action: func(ctx *cli.Context) error {
+			planPath := ctx.Args().First()
+			if planPath == "" {
+				return errApplyWithoutPlan
+			}
+			plan, err := readPlan(planPath)
+			if err != nil {
+				return err
+			}
+
+			updater, err := newUpdater(ctx)
+			if err != nil {
+				return err
+			}
+
+			err = updater.Apply(plan)
+			if reportPath := ctx.String("report"); reportPath != "" {
+				if errReport := writeReport(reportPath, ctx.String("report-format"), updater.Report()); errReport != nil {
+					return errors.Join(err, errReport)
+				}
+			}
+			return err
+		},
+	}
+}
+
+// identityFlag the flag of age identity file for decrypting the kept tokens in recovery store
+func identityFlag() cli.Flag {
+	return &cli.StringFlag{
+		Name:     "identity",
+		Aliases:  []string{"i"},
+		Usage:    "path of age identity file (as generated by age-keygen) for decrypting the kept tokens",
+		EnvVars:  []string{"GL_RECOVERY_IDENTITY"},
+		Required: true,
+	}
+}
+
+// identityAction the action of sub command that requires the decrypted kept tokens, the report is written even it's failed
+func identityAction(exec func(updater *app.GitlabTokenUpdater, identities []age.Identity) error) cli.ActionFunc {
+	return func(ctx *cli.Context) error {
+		identities, err := recovery.ReadIdentities(ctx.String("identity"))
+		if err != nil {
+			return err
+		}
+
+		updater, err := newUpdater(ctx)
+		if err != nil {
+			return err
+		}
+
+		err = exec(updater, identities)
+		if reportPath := ctx.String("report"); reportPath != "" {
+			if errReport := writeReport(reportPath, ctx.String("report-format"), updater.Report()); errReport != nil {
+				return errors.Join(err, errReport)
+			}
+		}
+		return err
+	}
+}
+
+// recoverCommand sub command for replaying the hooks by the kept token in recovery store
+func recoverCommand() *cli.Command {
+	return &cli.Command{
+		Name:   "recover",
+		Usage:  "replay the hooks of the selected access tokens by the kept token in recovery store, the entry is cleared once all of the hooks are succeeded",
+		Flags:  []cli.Flag{identityFlag()},
+		Action: identityAction((*app.GitlabTokenUpdater).Recover),
+	}
+}
+
+// resumeCommand sub command for executing the outstanding hooks that are recorded in the journal
+func resumeCommand() *cli.Command {
+	return &cli.Command{
+		Name:   "resume",
+		Usage:  "execute only the outstanding hooks of the interrupted or failed execution as recorded in the journal, the new token is taken from recovery store",
+		Flags:  []cli.Flag{identityFlag()},
+		Action: identityAction((*app.GitlabTokenUpdater).Resume),
+	}
+}
+
+// confirm prompt the question then wait for the answer, only `y` or `yes` are treated as confirmed
+func confirm(ctx *cli.Context, question string) bool {
+	_, _ = fmt.Fprintf(ctx.App.Writer, "%s [y/N]: ", question)
+	answer, _ := bufio.NewReader(ctx.App.Reader).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// serveCommand sub command for running as daemon
+func serveCommand() *cli.Command {
+	return &cli.Command{
+		Name:    "serve",
+		Aliases: []string{"daemon"},
+		Usage:   "keep running and execute the token renewal based on the schedule",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "schedule",
+				Usage: "cron expression of the execution schedule, e.g. '0 3 * * *' or '@daily'",
+			},
+			&cli.DurationFlag{
+				Name:  "interval",
+				Usage: "interval between executions, e.g. 6h. Can't be combined with --schedule",
+			},
+			&cli.StringFlag{
+				Name:  "listen",
+				Usage: fmt.Sprintf("listen address of health (%s) and metrics (%s) endpoints, set it empty to disable", app.DaemonHealthPath, app.MetricsPath),
+				Value: ":8080",
+			},
+			&cli.BoolFlag{
+				Name:  "run-on-start",
+				Usage: "execute immediately once it's started rather than waiting for the first schedule",
+			},
+			&cli.StringFlag{
+				Name:  "reload",
+				Usage: fmt.Sprintf("when the configuration is re-read (%s)", strings.Join(reloadModeList, ", ")),
+				Value: reloadModeCycle,
+				Action: func(_ *cli.Context, v string) error {
+					if !slices.Contains(reloadModeList, v) {
+						return errInvalidReloadMode
+					}
+					return nil
+				},
+			},
+		},
+		Action: serve,
+	}
+}
+
+// discoverCommand sub command for generating the configuration from the existing access tokens
+func discoverCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "discover",
+		Usage: "generate the configuration from the existing active access tokens in a group, it's subgroups and projects",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     "group",
+				Aliases:  []string{"g"},
+				Usage:    "path of the group to discover",
+				Required: true,
+			},
+			&cli.BoolFlag{
+				Name:    "recursive",
+				Aliases: []string{"r"},
+				Usage:   "also discover all of the subgroups and their projects",
+			},
+			&cli.BoolFlag{
+				Name:  "include",
+				Usage: "generate the content for include file (manage_tokens list only) instead of full configuration",
+			},
+			&cli.StringFlag{
+				Name:  "host",
+				Usage: "gitlab host",
+				Value: cfg.NewConfig().Host,
+			},
+			&cli.StringFlag{
+				Name:     "token",
+				Usage:    "gitlab token that has permission to list the access tokens",
+				EnvVars:  []string{"GL_RENEWER_TOKEN"},
+				Required: true,
+			},
+		},
+		Action: func(ctx *cli.Context) error {
+			host := ctx.String("host")
+			glAPI, err := gl.NewGitlabAPI(host, ctx.String("token"))
+			if err != nil {
+				return err
+			}
+
+			discovered, err := app.Discover(glAPI, ctx.String("group"), ctx.Bool("recursive"))
+			if err != nil {
+				return err
+			}
+			return app.RenderDiscovered(ctx.App.Writer, host, discovered, ctx.Bool("include"))
+		},
+	}
+}
+
+// serve run the daemon mode until SIGTERM/SIGINT received, the configuration is re-read in each cycle or only as SIGHUP received
+func serve(ctx *cli.Context) error {
+	configPath, err := requiredConfigPath(ctx)
+	if err != nil {
+		return err
+	}
+
+	schedule, err := app.ParseSchedule(ctx.String("schedule"), ctx.Duration("interval"))
+	if err != nil {
+		return err
+	}
+
+	// make sure the configuration is valid before start serving
+	config, err := cfg.ReadYAMLConfigFile(configPath)
+	if err != nil {
+		return err
+	}
+
+	var mu sync.Mutex
+	loadConfig := func() (*cfg.Config, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		return config, nil
+	}
+	if ctx.String("reload") == reloadModeCycle {
+		loadConfig = func() (*cfg.Config, error) {
+			return cfg.ReadYAMLConfigFile(configPath)
+		}
+	}
+
+	metrics := app.NewMetrics()
+	daemon := app.NewDaemon(schedule, func(runCtx context.Context) (*app.Report, error) {
+		current, err := loadConfig()
+		if err != nil {
+			return nil, err
+		}
+
+		updater, err := newUpdaterWithConfig(ctx, current)
+		if err != nil {
+			return nil, err
+		}
+		updater = updater.WithContext(runCtx)
+		err = execute(ctx, updater, metrics)
+		return updater.Report(), err
+	}).
+		WithListenAddr(ctx.String("listen")).
+		WithRunOnStart(ctx.Bool("run-on-start")).
+		WithHandler(app.MetricsPath, metrics)
+
+	if ctx.String("reload") == reloadModeSignal {
+		daemon = daemon.WithReload(func() error {
+			reloaded, err := cfg.ReadYAMLConfigFile(configPath)
+			if err != nil {
+				return err
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			config = reloaded
+			return nil
+		})
+	}
+
+	sigCtx, stop := signal.NotifyContext(ctx.Context, syscall.SIGTERM, os.Interrupt)
+	defer stop()
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	defer signal.Stop(reload)
+
+	return daemon.Start(sigCtx, reload)
+}
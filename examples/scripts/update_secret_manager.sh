@@ -1,3 +1,11 @@
 #!/bin/bash
 
-echo -n ${GL_NEW_TOKEN} | gcloud secrets --project=${GCP_PROJECT} version add "${SECRET_ID}" --dafa-file=-
\ No newline at end of file
+# the secret id is defaulted to the access token name when it's not set
+SECRET_ID=${SECRET_ID:-${GL_TOKEN_NAME}}
+
+if [ "${GL_DRY_RUN}" == "1" ]; then
+    gcloud secrets --project=${GCP_PROJECT} describe "${SECRET_ID}" > /dev/null
+    exit $?
+fi
+
+echo -n ${GL_NEW_TOKEN} | gcloud secrets --project=${GCP_PROJECT} version add "${SECRET_ID}" --dafa-file=-
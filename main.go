@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
@@ -25,6 +27,9 @@ var (
 	Version = "0.0.0"
 	// BuildHash git commit hash during build process
 	BuildHash = "0000000000000000000000000000000000000000"
+
+	errConfigNotSet       = errors.New(`required flag "config" not set`)
+	errInvalidConcurrency = errors.New("concurrency must be at least 1")
 )
 
 // New return command line instance in parsing and executing main instance
@@ -43,7 +48,7 @@ func New() *cli.App {
 		},
 		Usage:   "Gitlab repository and group access token updater/renewal",
 		Version: Version,
-		Flags: []cli.Flag{
+		Flags: append([]cli.Flag{
 			&cli.BoolFlag{
 				Name:    "debug",
 				Aliases: []string{"d"},
@@ -64,17 +69,46 @@ func New() *cli.App {
 				Aliases: []string{"s"},
 				Usage:   "enable strict mode, if any of error found the it will raise the errors",
 			},
+			&cli.IntFlag{
+				Name:  "concurrency",
+				Usage: "number of managed tokens that are processed in parallel, the personal ones are processed sequentially first",
+				Value: 1,
+				Action: func(_ *cli.Context, v int) error {
+					if v < 1 {
+						return errInvalidConcurrency
+					}
+					return nil
+				},
+			},
 			&cli.BoolFlag{
 				Name:  "dry-run",
 				Usage: "dry run mode, skip any write execution",
 			},
 			&cli.StringFlag{
-				Name:     "config",
-				Aliases:  []string{"c"},
-				Usage:    "path of yaml config path",
-				Required: true,
+				Name:  "report",
+				Usage: "write the execution results report to the given file path",
 			},
-		},
+			&cli.StringFlag{
+				Name:  "report-format",
+				Usage: fmt.Sprintf("format of the execution report (%s)", strings.Join(app.ReportFormatList, ", ")),
+				Value: app.ReportFormatJSON,
+				Action: func(_ *cli.Context, v string) error {
+					if !slices.Contains(app.ReportFormatList, v) {
+						return app.ErrReportInvalidFormat
+					}
+					return nil
+				},
+			},
+			&cli.StringFlag{
+				Name:  "metrics-textfile",
+				Usage: "write the prometheus metrics to the given file path for node exporter textfile collector",
+			},
+			&cli.StringFlag{
+				Name:    "config",
+				Aliases: []string{"c"},
+				Usage:   "path of yaml config path, required by all commands except discover",
+			},
+		}, selectorFlags()...),
 		Before: func(ctx *cli.Context) error {
 			zerolog.SetGlobalLevel(zerolog.InfoLevel)
 			if ctx.Bool("debug") {
@@ -88,42 +122,198 @@ func New() *cli.App {
 			return nil
 		},
 		Action: func(ctx *cli.Context) error {
-			configPath := ctx.String("config")
-			forceRenew := ctx.Bool("force")
-			dryRun := ctx.Bool("dry-run")
-			strictMode := ctx.Bool("strict")
-
-			config, err := cfg.ReadYAMLConfigFile(filepath.Clean(configPath))
+			updater, err := newUpdater(ctx)
 			if err != nil {
 				return err
 			}
+			return execute(ctx, updater, app.NewMetrics())
+		},
+		Commands: []*cli.Command{
+			statusCommand(),
+			validateCommand(),
+			revokeCommand(),
+			recoverCommand(),
+			resumeCommand(),
+			planCommand(),
+			applyCommand(),
+			serveCommand(),
+			discoverCommand(),
+		},
+	}
+	return cmd
+}
 
-			glAPI, err := gl.NewGitlabAPI(config.Host, config.Token)
-			if err != nil {
-				return err
-			}
+// writeReport write the execution report into a file
+func writeReport(path, format string, report *app.Report) (err error) {
+	f, err := os.Create(filepath.Clean(path))
+	if err != nil {
+		return fmt.Errorf("error while create report file: %w", err)
+	}
+	defer func() {
+		err = errors.Join(err, f.Close())
+	}()
 
-			if forceRenew {
-				log.Warn().Msg("force renew enabled")
-			}
+	return app.WriteReport(f, format, report)
+}
 
-			if dryRun {
-				log.Warn().Msg("dry run mode enabled")
-			}
+// writePlan write the plan into a file
+func writePlan(path string, plan *app.Plan) (err error) {
+	f, err := os.Create(filepath.Clean(path))
+	if err != nil {
+		return fmt.Errorf("error while create plan file: %w", err)
+	}
+	defer func() {
+		err = errors.Join(err, f.Close())
+	}()
 
-			if strictMode {
-				log.Warn().Msg("strict mode enabled")
-			}
+	return app.WritePlan(f, plan)
+}
+
+// readPlan read the plan from a file
+func readPlan(path string) (*app.Plan, error) {
+	f, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return nil, fmt.Errorf("error while open plan file: %w", err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
 
-			return app.
-				NewGitlabTokenUpdater(config, glAPI, &shell.SHExecutor{}).
-				WithDryRun(dryRun).
-				WithForceRenew(forceRenew).
-				WithStrictMode(strictMode).
-				Do()
+	return app.ReadPlan(f)
+}
+
+// outputFlag flag for choosing the output format of sub command
+func outputFlag(validFormats []string, defaultFormat string, errInvalid error) *cli.StringFlag {
+	return &cli.StringFlag{
+		Name:    "output",
+		Aliases: []string{"o"},
+		Usage:   fmt.Sprintf("output format (%s)", strings.Join(validFormats, ", ")),
+		Value:   defaultFormat,
+		Action: func(_ *cli.Context, v string) error {
+			if !slices.Contains(validFormats, v) {
+				return errInvalid
+			}
+			return nil
 		},
 	}
-	return cmd
+}
+
+// requiredConfigPath return the config flag value, it's not set as required flag as not all of the commands need it
+func requiredConfigPath(ctx *cli.Context) (string, error) {
+	configPath := ctx.String("config")
+	if configPath == "" {
+		return "", errConfigNotSet
+	}
+	return filepath.Clean(configPath), nil
+}
+
+// newUpdater read the configuration then initiate GitlabTokenUpdater based on the given flags
+func newUpdater(ctx *cli.Context) (*app.GitlabTokenUpdater, error) {
+	configPath, err := requiredConfigPath(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := cfg.ReadYAMLConfigFile(configPath)
+	if err != nil {
+		return nil, err
+	}
+	return newUpdaterWithConfig(ctx, config)
+}
+
+// clientPolicy the Gitlab client policy of the configured one, the default is used for the unset values
+func clientPolicy(p cfg.APIPolicy) gl.ClientPolicy {
+	policy := gl.DefaultClientPolicy()
+	if p.RateLimit != nil {
+		policy.RateLimit = *p.RateLimit
+	}
+	if p.Retry != nil {
+		policy.Retry = *p.Retry
+	}
+	if p.RetryWaitMin != "" {
+		policy.RetryWaitMin = p.RetryWaitMinDuration()
+	}
+	if p.RetryWaitMax != "" {
+		policy.RetryWaitMax = p.RetryWaitMaxDuration()
+	}
+	policy.Timeout = p.TimeoutDuration()
+	return policy
+}
+
+// gitlabOptions the options of Gitlab API based on the api config
+func gitlabOptions(config *cfg.Config) []gl.Option {
+	if config.API == nil {
+		return nil
+	}
+
+	instances := map[string]gl.ClientPolicy{}
+	for instanceURL, policy := range config.API.InstancePolicies() {
+		instances[instanceURL] = clientPolicy(policy)
+	}
+	return []gl.Option{gl.WithClientPolicy(clientPolicy(config.API.APIPolicy)), gl.WithInstancePolicies(instances)}
+}
+
+// newUpdaterWithConfig initiate GitlabTokenUpdater of the given configuration based on the given flags
+func newUpdaterWithConfig(ctx *cli.Context, config *cfg.Config) (*app.GitlabTokenUpdater, error) {
+	forceRenew := ctx.Bool("force")
+	dryRun := ctx.Bool("dry-run")
+	strictMode := ctx.Bool("strict")
+
+	selector, err := newSelector(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	glAPI, err := gl.NewGitlabAPI(config.Host, config.Token, gitlabOptions(config)...)
+	if err != nil {
+		return nil, err
+	}
+
+	if selector != nil {
+		log.Warn().Msg("selector is set, only the selected access tokens are processed")
+	}
+
+	if forceRenew {
+		log.Warn().Msg("force renew enabled")
+	}
+
+	if dryRun {
+		log.Warn().Msg("dry run mode enabled")
+	}
+
+	if strictMode {
+		log.Warn().Msg("strict mode enabled")
+	}
+
+	return app.
+		NewGitlabTokenUpdater(config, glAPI, &shell.SHExecutor{}).
+		WithDryRun(dryRun).
+		WithForceRenew(forceRenew).
+		WithStrictMode(strictMode).
+		WithConcurrency(ctx.Int("concurrency")).
+		WithSelector(selector), nil
+}
+
+// execute run the updater, record it to the metrics then write the execution report and metrics file if they are requested
+func execute(ctx *cli.Context, updater *app.GitlabTokenUpdater, metrics *app.Metrics) error {
+	started := time.Now()
+	err := updater.Do()
+	metrics.Observe(updater.Report(), time.Since(started), err)
+
+	if reportPath := ctx.String("report"); reportPath != "" {
+		if errReport := writeReport(reportPath, ctx.String("report-format"), updater.Report()); errReport != nil {
+			return errors.Join(err, errReport)
+		}
+		log.Info().Str("path", reportPath).Msg("execution report written")
+	}
+
+	if metricsPath := ctx.String("metrics-textfile"); metricsPath != "" {
+		if errMetrics := metrics.WriteTextfile(metricsPath); errMetrics != nil {
+			return errors.Join(err, errMetrics)
+		}
+		log.Info().Str("path", metricsPath).Msg("metrics file written")
+	}
+	return err
 }
 
 // errHandler cleanup new lined character as results in joining some errors then exit with code 1
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"testing"
 
 	m "github.com/iomarmochtar/gitlab-token-updater"
@@ -49,9 +50,57 @@ func TestRun(t *testing.T) {
 				}
 			},
 		},
+		"ok: status subcommand": {
+			cmdArgs: []string{"--config", t_helper.FixturePath("configs", "cmd_test_config.yml"), "status", "--output", "json"},
+			mockGitlabResp: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+				if r.URL.Path == `/api/v4/groups//some/group/path/access_tokens` && r.Method == http.MethodGet {
+					_, _ = w.Write(t_helper.ReadFixture("api_responses/group_access_tokens.json"))
+				}
+			},
+		},
+		"err: status subcommand with unknown output format": {
+			cmdArgs:        []string{"--config", t_helper.FixturePath("configs", "cmd_test_config.yml"), "status", "--output", "xml"},
+			expectedErrMsg: "invalid output format",
+		},
+		"ok: validate subcommand without any gitlab token": {
+			cmdArgs: []string{"--config", t_helper.FixturePath("configs", "cmd_test_config.yml"), "validate"},
+		},
+		"err: validate subcommand found invalid configuration": {
+			cmdArgs:        []string{"--config", t_helper.FixturePath("configs", "invalid_multi_errors.yml"), "validate", "--output", "codequality"},
+			expectedErrMsg: "invalid configuration",
+		},
+		"err: serve subcommand without schedule": {
+			cmdArgs:        []string{"--config", t_helper.FixturePath("configs", "cmd_test_config.yml"), "serve", "--listen", ""},
+			expectedErrMsg: "either schedule or interval must be set",
+		},
+		"err: serve subcommand with unknown reload mode": {
+			cmdArgs:        []string{"--config", t_helper.FixturePath("configs", "cmd_test_config.yml"), "serve", "--interval", "1h", "--reload", "never"},
+			expectedErrMsg: "invalid reload mode",
+		},
+		"err: invalid selector pattern": {
+			cmdArgs:        []string{"--config", t_helper.FixturePath("configs", "cmd_test_config.yml"), "--token", "re:TOKEN(", "status"},
+			expectedErrMsg: "invalid selector regex TOKEN(",
+			mockGitlabResp: func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			},
+		},
+		"ok: status subcommand with concurrency": {
+			cmdArgs: []string{"--config", t_helper.FixturePath("configs", "cmd_test_config.yml"), "--concurrency", "4", "status"},
+			mockGitlabResp: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+				if r.URL.Path == `/api/v4/groups//some/group/path/access_tokens` && r.Method == http.MethodGet {
+					_, _ = w.Write(t_helper.ReadFixture("api_responses/group_access_tokens.json"))
+				}
+			},
+		},
+		"err: invalid concurrency": {
+			cmdArgs:        []string{"--config", t_helper.FixturePath("configs", "cmd_test_config.yml"), "--concurrency", "0"},
+			expectedErrMsg: "concurrency must be at least 1",
+		},
 		"err: not providing required flags": {
 			cmdArgs:        []string{},
-			expectedErrMsg: `Required flag "config" not set`,
+			expectedErrMsg: `required flag "config" not set`,
 		},
 		"err: required config is not set": {
 			cmdArgs:        []string{"--config", t_helper.FixturePath("configs", "cmd_test_config.yml"), "--debug"},
@@ -92,6 +141,192 @@ func TestRun(t *testing.T) {
 	}
 }
 
+func TestRun_Report(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		//nolint:gocritic
+		if r.URL.Path == `/api/v4/groups//some/group/path/access_tokens` && r.Method == http.MethodGet {
+			_, _ = w.Write(t_helper.ReadFixture("api_responses/group_access_tokens.json"))
+		} else if r.URL.Path == `/api/v4/groups//some/group/path/access_tokens/42/rotate` && r.Method == http.MethodPost {
+			_, _ = w.Write(t_helper.ReadFixture("api_responses/group_access_token_rotate.json"))
+		} else if r.URL.Path == `/api/v4/projects//some/repo/path/variables/THIS_IS_VAR` && r.Method == http.MethodPut {
+			_, _ = w.Write(t_helper.ReadFixture("api_responses/project_cicd_var.json"))
+		}
+	}))
+	_ = os.Setenv("HTTP_TEST", ts.URL)
+	t.Cleanup(func() {
+		ts.Close()
+		_ = os.Unsetenv("HTTP_TEST")
+	})
+
+	for _, format := range []string{"json", "junit"} {
+		t.Run(format, func(t *testing.T) {
+			reportPath := filepath.Join(t.TempDir(), "report")
+			metricsPath := filepath.Join(t.TempDir(), "gltu.prom")
+			command := m.New()
+			command.Writer = io.Discard
+			err := command.Run([]string{
+				m.CmdName, "--config", t_helper.FixturePath("configs", "cmd_test_config.yml"),
+				"--force", "--report", reportPath, "--report-format", format, "--metrics-textfile", metricsPath,
+			})
+			assert.NoError(t, err)
+
+			metrics, err := os.ReadFile(metricsPath)
+			assert.NoError(t, err)
+			assert.Contains(t, string(metrics), `gltu_rotations_total{path="/some/group/path",type="group",name="TOKEN1",result="success"} 1`)
+
+			content, err := os.ReadFile(reportPath)
+			assert.NoError(t, err)
+			assert.Contains(t, string(content), "TOKEN1")
+			// the rotated token value must not be written
+			assert.NotContains(t, string(content), "s3cr3t")
+		})
+	}
+}
+
+func TestRun_Discover(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		switch r.URL.Path {
+		case `/api/v4/groups/some/group/path/projects`:
+			_, _ = w.Write([]byte(`[{"id": 1, "path_with_namespace": "some/group/path/repo"}]`))
+		case `/api/v4/groups/some/group/path/access_tokens`:
+			_, _ = w.Write(t_helper.ReadFixture("api_responses/group_access_tokens.json"))
+		case `/api/v4/groups/some/group/path/variables`:
+			_, _ = w.Write([]byte(`[{"key": "TOKEN1_TOKEN", "value": "s3cr3t"}]`))
+		default:
+			_, _ = w.Write([]byte(`[]`))
+		}
+	}))
+	t.Cleanup(ts.Close)
+
+	buf := new(bytes.Buffer)
+	command := m.New()
+	command.Writer = buf
+	err := command.Run([]string{
+		m.CmdName, "discover", "--group", "some/group/path", "--host", ts.URL, "--token", "glpat-renewer",
+	})
+	assert.NoError(t, err)
+	assert.Contains(t, buf.String(), "- name: TOKEN1")
+	assert.Contains(t, buf.String(), "#       name: TOKEN1_TOKEN")
+	// revoked token and variable value are excluded
+	assert.NotContains(t, buf.String(), "token-2")
+	assert.NotContains(t, buf.String(), "s3cr3t")
+}
+
+func TestRun_Revoke(t *testing.T) {
+	var revoked bool
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		//nolint:gocritic
+		if r.URL.Path == `/api/v4/groups//some/group/path/access_tokens` && r.Method == http.MethodGet {
+			_, _ = w.Write(t_helper.ReadFixture("api_responses/group_access_tokens.json"))
+		} else if r.URL.Path == `/api/v4/groups//some/group/path/access_tokens/42` && r.Method == http.MethodDelete {
+			revoked = true
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	_ = os.Setenv("HTTP_TEST", ts.URL)
+	t.Cleanup(func() {
+		ts.Close()
+		_ = os.Unsetenv("HTTP_TEST")
+	})
+
+	testCases := map[string]struct {
+		cmdArgs         []string
+		answer          string
+		expectedRevoked bool
+		expectedErrMsg  string
+	}{
+		"ok: confirmed": {
+			cmdArgs:         []string{"--token", "TOKEN1", "revoke"},
+			answer:          "y\n",
+			expectedRevoked: true,
+		},
+		"ok: skip confirmation": {
+			cmdArgs:         []string{"--token", "TOKEN1", "revoke", "--yes"},
+			expectedRevoked: true,
+		},
+		"ok: dry run": {
+			cmdArgs: []string{"--dry-run", "--token", "TOKEN1", "revoke"},
+		},
+		"err: aborted": {
+			cmdArgs:        []string{"--token", "TOKEN1", "revoke"},
+			answer:         "n\n",
+			expectedErrMsg: "revocation is aborted",
+		},
+		"err: without any selector": {
+			cmdArgs:        []string{"revoke", "--yes"},
+			expectedErrMsg: "at least one of selector",
+		},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			revoked = false
+			buf := new(bytes.Buffer)
+			command := m.New()
+			command.Writer = buf
+			command.Reader = bytes.NewBufferString(tc.answer)
+			cmdArgs := append([]string{m.CmdName, "--config", t_helper.FixturePath("configs", "cmd_test_config.yml")}, tc.cmdArgs...)
+			err := command.Run(cmdArgs)
+
+			if tc.expectedErrMsg != "" {
+				assert.ErrorContains(t, err, tc.expectedErrMsg)
+			} else {
+				assert.NoError(t, err)
+				assert.Contains(t, buf.String(), "TOKEN1")
+			}
+			assert.Equal(t, tc.expectedRevoked, revoked)
+		})
+	}
+}
+
+func TestRun_PlanApply(t *testing.T) {
+	var rotated bool
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		//nolint:gocritic
+		if r.URL.Path == `/api/v4/groups//some/group/path/access_tokens` && r.Method == http.MethodGet {
+			_, _ = w.Write(t_helper.ReadFixture("api_responses/group_access_tokens.json"))
+		} else if r.URL.Path == `/api/v4/groups//some/group/path/access_tokens/42/rotate` && r.Method == http.MethodPost {
+			rotated = true
+			_, _ = w.Write(t_helper.ReadFixture("api_responses/group_access_token_rotate.json"))
+		} else if r.URL.Path == `/api/v4/projects//some/repo/path/variables/THIS_IS_VAR` && r.Method == http.MethodPut {
+			_, _ = w.Write(t_helper.ReadFixture("api_responses/project_cicd_var.json"))
+		}
+	}))
+	_ = os.Setenv("HTTP_TEST", ts.URL)
+	t.Cleanup(func() {
+		ts.Close()
+		_ = os.Unsetenv("HTTP_TEST")
+	})
+
+	configPath := t_helper.FixturePath("configs", "cmd_test_config.yml")
+	planPath := filepath.Join(t.TempDir(), "plan.json")
+	run := func(args ...string) error {
+		command := m.New()
+		command.Writer = io.Discard
+		return command.Run(append([]string{m.CmdName, "--config", configPath}, args...))
+	}
+
+	assert.NoError(t, run("plan", "--out", planPath))
+	assert.False(t, rotated)
+	content, err := os.ReadFile(planPath)
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), `"name": "TOKEN1"`)
+	assert.Contains(t, string(content), `"action": "rotate"`)
+
+	// the plan is refused once the live state is drifted
+	drifted := bytes.ReplaceAll(content, []byte(`"id": 42`), []byte(`"id": 41`))
+	driftedPath := filepath.Join(t.TempDir(), "drifted.json")
+	assert.NoError(t, os.WriteFile(driftedPath, drifted, 0o600))
+	assert.ErrorContains(t, run("apply", driftedPath), "live state is drifted since planning: token TOKEN1 in /some/group/path id is changed from 41 to 42")
+	assert.False(t, rotated)
+
+	assert.ErrorContains(t, run("apply"), "path of the plan file is required")
+	assert.NoError(t, run("apply", planPath))
+	assert.True(t, rotated)
+}
+
 func TestNew(t *testing.T) {
 	// get version
 	buf := new(bytes.Buffer)
@@ -0,0 +1,127 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+var (
+	ErrValidationAPIInvalidRateLimit   = errors.New("invalid rate_limit in api, it must not be negative")
+	ErrValidationAPIInvalidRetry       = errors.New("invalid retry in api, it must not be negative")
+	ErrValidationAPIInvalidDuration    = errors.New("invalid duration in api, it must be a positive duration (e.g. 500ms, 30s)")
+	ErrValidationAPIInvalidRetryWait   = errors.New("retry_wait_min in api must not be greater than retry_wait_max")
+	ErrValidationAPIInvalidInstanceURL = errors.New("invalid instance URL in api, it must be an absolute http(s) URL")
+	apiDurationArgs                    = []string{"retry_wait_min", "retry_wait_max", "timeout"}
+)
+
+// APIPolicy the rate limit, retry and timeout of the Gitlab API calls, the default of the Gitlab client is used for the unset one
+type APIPolicy struct {
+	// RateLimit the maximum requests per second, the rate limit headers of Gitlab are followed if it's not set
+	RateLimit *float64 `yaml:"rate_limit"`
+	// Retry the maximum retries of the rate limited, server error and connection error
+	Retry *int `yaml:"retry"`
+	// RetryWaitMin and RetryWaitMax the bound of the exponential backoff between the retries
+	RetryWaitMin string `yaml:"retry_wait_min"`
+	RetryWaitMax string `yaml:"retry_wait_max"`
+	// Timeout of each of the API call attempts
+	Timeout string `yaml:"timeout"`
+}
+
+// durations the duration arguments by their names
+func (p APIPolicy) durations() map[string]string {
+	return map[string]string{
+		"retry_wait_min": p.RetryWaitMin,
+		"retry_wait_max": p.RetryWaitMax,
+		"timeout":        p.Timeout,
+	}
+}
+
+func (p APIPolicy) RetryWaitMinDuration() time.Duration {
+	duration, _ := time.ParseDuration(p.RetryWaitMin)
+	return duration
+}
+
+func (p APIPolicy) RetryWaitMaxDuration() time.Duration {
+	duration, _ := time.ParseDuration(p.RetryWaitMax)
+	return duration
+}
+
+func (p APIPolicy) TimeoutDuration() time.Duration {
+	duration, _ := time.ParseDuration(p.Timeout)
+	return duration
+}
+
+func (p APIPolicy) validate() (errs []error) {
+	if p.RateLimit != nil && *p.RateLimit < 0 {
+		errs = append(errs, ErrValidationAPIInvalidRateLimit)
+	}
+	if p.Retry != nil && *p.Retry < 0 {
+		errs = append(errs, ErrValidationAPIInvalidRetry)
+	}
+
+	durations := p.durations()
+	for _, key := range apiDurationArgs {
+		if durations[key] == "" {
+			continue
+		}
+		if duration, err := time.ParseDuration(durations[key]); err != nil || duration <= 0 {
+			errs = append(errs, fmt.Errorf("%w: %s", ErrValidationAPIInvalidDuration, key))
+		}
+	}
+
+	if p.RetryWaitMin != "" && p.RetryWaitMax != "" && p.RetryWaitMinDuration() > p.RetryWaitMaxDuration() {
+		errs = append(errs, ErrValidationAPIInvalidRetryWait)
+	}
+	return errs
+}
+
+// inherit fill the unset values by the parent one
+func (p APIPolicy) inherit(parent APIPolicy) APIPolicy {
+	if p.RateLimit == nil {
+		p.RateLimit = parent.RateLimit
+	}
+	if p.Retry == nil {
+		p.Retry = parent.Retry
+	}
+	if p.RetryWaitMin == "" {
+		p.RetryWaitMin = parent.RetryWaitMin
+	}
+	if p.RetryWaitMax == "" {
+		p.RetryWaitMax = parent.RetryWaitMax
+	}
+	if p.Timeout == "" {
+		p.Timeout = parent.Timeout
+	}
+	return p
+}
+
+// API the policy of the Gitlab API calls, the policy of the external instances (used in update_var hook) inherits the unset values from the global one
+type API struct {
+	APIPolicy `yaml:",inline"`
+	// Instances the policy of the external instances by their URL
+	Instances map[string]APIPolicy `yaml:"instances"`
+}
+
+func (a API) validate() (errs []error) {
+	errs = append(errs, a.APIPolicy.validate()...)
+	for instanceURL, policy := range a.Instances {
+		if u, err := url.Parse(instanceURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("%w: %s", ErrValidationAPIInvalidInstanceURL, instanceURL))
+		}
+		for _, err := range policy.validate() {
+			errs = append(errs, fmt.Errorf("%w (instance: %s)", err, instanceURL))
+		}
+	}
+	return errs
+}
+
+// InstancePolicies the policy of the external instances along with the inherited values from the global one
+func (a API) InstancePolicies() map[string]APIPolicy {
+	policies := make(map[string]APIPolicy, len(a.Instances))
+	for instanceURL, policy := range a.Instances {
+		policies[instanceURL] = policy.inherit(a.APIPolicy)
+	}
+	return policies
+}
@@ -4,6 +4,8 @@ package config
 import (
 	"errors"
 	"fmt"
+	"maps"
+	"slices"
 	"strings"
 	"time"
 )
@@ -14,12 +16,32 @@ const (
 	defaultRenewBefore       = "14d"
 	defaultExpiryAfterRotate = "3M"
 	defaultHookRetry         = 0
+	defaultInterpreter       = "/bin/sh"
+	defaultVaultMount        = "secret"
+	defaultHTTPMethod        = "POST"
 	ManagedTypeRepository    = "repository"
 	ManagedTypeGroup         = "group"
 	ManagedTypePersonal      = "personal"
 	HookTypeUpdateVar        = "update_var"
 	HookTypeExecCMD          = "exec_cmd"
 	HookTypeUseToken         = "use_token"
+	HookTypeK8sSecret        = "k8s_secret"
+	HookTypeVaultKV          = "vault_kv"
+	HookTypeHTTP             = "http"
+	AccessLevelGuest         = "guest"
+	AccessLevelReporter      = "reporter"
+	AccessLevelDeveloper     = "developer"
+	AccessLevelMaintainer    = "maintainer"
+	AccessLevelOwner         = "owner"
+	OnExpiredRecreate        = "recreate"
+	VariableTypeEnvVar       = "env_var"
+	VariableTypeFile         = "file"
+	EnvScopeAll              = "*all*"
+	UpdateVarTypeInstance    = "instance"
+	VaultAuthMethodToken     = "token"
+	VaultAuthMethodAppRole   = "approle"
+	VaultAuthMethodJWT       = "jwt"
+	MaskedValue              = "********"
 )
 
 var (
@@ -32,6 +54,67 @@ var (
 		HookTypeUpdateVar,
 		HookTypeExecCMD,
 		HookTypeUseToken,
+		HookTypeK8sSecret,
+		HookTypeVaultKV,
+		HookTypeHTTP,
+	}
+	AccessLevelList = []string{
+		AccessLevelGuest,
+		AccessLevelReporter,
+		AccessLevelDeveloper,
+		AccessLevelMaintainer,
+		AccessLevelOwner,
+	}
+	OnExpiredList = []string{
+		OnExpiredRecreate,
+	}
+	// UpdateVarTypeList the target type of CICD variable in update_var hook
+	UpdateVarTypeList = []string{
+		ManagedTypeRepository,
+		ManagedTypeGroup,
+		UpdateVarTypeInstance,
+	}
+	VariableTypeList = []string{
+		VariableTypeEnvVar,
+		VariableTypeFile,
+	}
+	VaultAuthMethodList = []string{
+		VaultAuthMethodToken,
+		VaultAuthMethodAppRole,
+		VaultAuthMethodJWT,
+	}
+	// updateVarBoolArgs the boolean attributes of CICD variable in update_var hook
+	updateVarBoolArgs = []string{"create_if_missing", "masked", "protected", "raw"}
+	updateVarStrArgs  = []string{"type", "name", "path", "gitlab", "gitlab_token", "variable_type", "description"}
+	// execCMDBoolArgs and execCMDListArgs the boolean and list arguments in exec_cmd hook
+	execCMDBoolArgs = []string{"dry_run", "token_stdin"}
+	execCMDListArgs = []string{"args", "interpreter", "inherit_env"}
+	execCMDStrArgs  = []string{"path", "script", "workdir", "timeout"}
+	// k8sSecretRequiredArgs and k8sSecretBoolArgs the required and boolean arguments in k8s_secret hook
+	k8sSecretRequiredArgs = []string{"namespace", "name", "key"}
+	k8sSecretBoolArgs     = []string{"in_cluster", "create_if_missing"}
+	// vaultKVRequiredArgs the required arguments in vault_kv hook, along with the required one for each of the login auth methods
+	vaultKVRequiredArgs     = []string{"path", "key"}
+	vaultKVAuthRequiredArgs = map[string][]string{
+		VaultAuthMethodAppRole: {"role_id", "secret_id"},
+		VaultAuthMethodJWT:     {"role", "jwt"},
+	}
+	// httpStrArgs the string arguments in http hook
+	httpStrArgs = []string{"url", "method", "bearer_token", "basic_username", "basic_password", "ca_cert", "client_cert", "client_key", "dry_run_method", "timeout"}
+	// secretHookArgs the hook arguments that are masked in the resolved arguments, all of the values in secretMapHookArgs are masked as well
+	secretHookArgs    = []string{"gitlab_token", "token", "secret_id", "jwt", "bearer_token", "basic_password"}
+	secretMapHookArgs = []string{"env", "headers"}
+	// rawHookArgs the hook arguments that are not evaluated for env var pattern, since they are script or Go template
+	rawHookArgs = []string{"script", "template", "body"}
+	// defaultInheritEnv the env var names that are passed to the executable in exec_cmd hook if inherit_env is not set
+	defaultInheritEnv = []string{"PATH"}
+	// accessLevelValues the value of access level in Gitlab API
+	accessLevelValues = map[string]int{
+		AccessLevelGuest:      10,
+		AccessLevelReporter:   20,
+		AccessLevelDeveloper:  30,
+		AccessLevelMaintainer: 40,
+		AccessLevelOwner:      50,
 	}
 )
 
@@ -48,13 +131,44 @@ var (
 	ErrValidationManagedInvalidExpiryAfterRotate = errors.New("invalid expiry after rotate value")
 	ErrValidationManagedDuplicatedDefinition     = errors.New("duplicated manage token found")
 	ErrValidationTokenEmptyName                  = errors.New("empty token name")
+	ErrValidationTokenInvalidAccessLevel         = fmt.Errorf("invalid access level, the valid one are %s", strings.Join(AccessLevelList, ","))
+	ErrValidationTokenAccessLevelByPersonalType  = fmt.Errorf("access level can't be set in manage type %s", ManagedTypePersonal)
+	ErrValidationTokenCreateMissingScopes        = errors.New("scopes is required for creating the missing token")
+	ErrValidationTokenInvalidOnExpired           = fmt.Errorf("invalid on expired policy, the valid one are %s", strings.Join(OnExpiredList, ","))
 	ErrValidationHookInvalidType                 = fmt.Errorf("invalid hook type, the valid one are %s", strings.Join(HookTypeList, ","))
 	ErrValidationHookUpdateVarMissingName        = fmt.Errorf("missing arg name in %s hook", HookTypeUpdateVar)
 	ErrValidationHookUpdateVarMissingPath        = fmt.Errorf("missing arg path in %s hook", HookTypeUpdateVar)
 	ErrValidationHookUpdateVarMissingType        = fmt.Errorf("missing arg type in %s hook", HookTypeUpdateVar)
-	ErrValidationHookUpdateVarInvalidType        = fmt.Errorf("invalid arg type in %s hook, the valid one are %s", HookTypeUpdateVar, strings.Join(ManagedTypeList, ","))
+	ErrValidationHookUpdateVarInvalidType        = fmt.Errorf("invalid arg type in %s hook, the valid one are %s", HookTypeUpdateVar, strings.Join(UpdateVarTypeList, ","))
 	ErrValidationHookUpdateMissingGitlabToken    = fmt.Errorf("external gitlab detected but got empty `gitlab_token` parameter")
-	ErrValidationHookExecCMDMissingPath          = fmt.Errorf("missing arg path in %s hook", HookTypeExecCMD)
+	ErrValidationHookUpdateVarInvalidVarType     = fmt.Errorf("invalid arg variable_type in %s hook, the valid one are %s", HookTypeUpdateVar, strings.Join(VariableTypeList, ","))
+	ErrValidationHookUpdateVarNotBoolArg         = fmt.Errorf("arg must be a boolean in %s hook", HookTypeUpdateVar)
+	ErrValidationHookUpdateVarNotStrArg          = fmt.Errorf("arg must be a string in %s hook", HookTypeUpdateVar)
+	ErrValidationHookUpdateVarInvalidEnvScope    = fmt.Errorf("arg environment_scope in %s hook must be a non empty string or list of them", HookTypeUpdateVar)
+	ErrValidationHookUpdateVarInstanceEnvScope   = fmt.Errorf("arg environment_scope in %s hook can't be set for type %s", HookTypeUpdateVar, UpdateVarTypeInstance)
+	ErrValidationHookUpdateVarEnvScopeAll        = fmt.Errorf("environment scope %s in %s hook can't be combined with other scopes or create_if_missing", EnvScopeAll, HookTypeUpdateVar)
+	ErrValidationHookInvalidTemplate             = fmt.Errorf("invalid template arg, it must be a Go template and can be only set in hook %s", strings.Join(templateHookTypes, ","))
+	ErrValidationHookExecCMDMissingPath          = fmt.Errorf("missing arg path or script in %s hook", HookTypeExecCMD)
+	ErrValidationHookExecCMDPathAndScript        = fmt.Errorf("arg path and script can't be set together in %s hook", HookTypeExecCMD)
+	ErrValidationHookExecCMDNotBoolArg           = fmt.Errorf("arg must be a boolean in %s hook", HookTypeExecCMD)
+	ErrValidationHookExecCMDNotListArg           = fmt.Errorf("arg must be a string or list of string in %s hook", HookTypeExecCMD)
+	ErrValidationHookExecCMDNotStrArg            = fmt.Errorf("arg must be a string in %s hook", HookTypeExecCMD)
+	ErrValidationHookExecCMDInvalidTimeout       = fmt.Errorf("invalid arg timeout in %s hook, it must be a positive duration (e.g. 30s, 5m)", HookTypeExecCMD)
+	ErrValidationHookK8sSecretMissingArg         = fmt.Errorf("missing required arg in %s hook", HookTypeK8sSecret)
+	ErrValidationHookK8sSecretNotBoolArg         = fmt.Errorf("arg must be a boolean in %s hook", HookTypeK8sSecret)
+	ErrValidationHookK8sSecretNotListArg         = fmt.Errorf("arg rollout_restart must be a string or list of string in %s hook", HookTypeK8sSecret)
+	ErrValidationHookK8sSecretInClusterConfig    = fmt.Errorf("arg in_cluster can't be combined with kubeconfig or context in %s hook", HookTypeK8sSecret)
+	ErrValidationHookVaultKVMissingArg           = fmt.Errorf("missing required arg in %s hook", HookTypeVaultKV)
+	ErrValidationHookVaultKVInvalidAuthMethod    = fmt.Errorf("invalid arg auth_method in %s hook, the valid one are %s", HookTypeVaultKV, strings.Join(VaultAuthMethodList, ","))
+	ErrValidationHookVaultKVNotBoolArg           = fmt.Errorf("arg create_if_missing must be a boolean in %s hook", HookTypeVaultKV)
+	ErrValidationHookHTTPMissingURL              = fmt.Errorf("missing arg url in %s hook", HookTypeHTTP)
+	ErrValidationHookHTTPNotStrArg               = fmt.Errorf("arg must be a string in %s hook", HookTypeHTTP)
+	ErrValidationHookHTTPInvalidBody             = fmt.Errorf("invalid arg body in %s hook, it must be a Go template", HookTypeHTTP)
+	ErrValidationHookHTTPInvalidHeaders          = fmt.Errorf("invalid arg headers in %s hook, it must be a map of string", HookTypeHTTP)
+	ErrValidationHookHTTPInvalidStatus           = fmt.Errorf("invalid arg expected_status in %s hook, it must be a status code or list of them", HookTypeHTTP)
+	ErrValidationHookHTTPInvalidTimeout          = fmt.Errorf("invalid arg timeout in %s hook, it must be a positive duration (e.g. 30s, 5m)", HookTypeHTTP)
+	ErrValidationHookHTTPAuthConflict            = fmt.Errorf("arg bearer_token can't be combined with basic_username or basic_password in %s hook", HookTypeHTTP)
+	ErrValidationHookHTTPClientCert              = fmt.Errorf("arg client_cert and client_key must be set together in %s hook", HookTypeHTTP)
 	ErrValidationHookUseTokenNotByPersonalType   = fmt.Errorf("can be only use in manage type %s", ManagedTypePersonal)
 	ErrValidationHookUseTokenAlreadyUse          = fmt.Errorf("hook %s can be only use once", HookTypeUseToken)
 	ErrValidationHookUseTokenNotFirstSeq         = fmt.Errorf("hook %s must be set at the first", HookTypeUseToken)
@@ -66,11 +180,101 @@ type HookUpdateVar struct {
 	Type        string
 	Gitlab      string
 	GitlabToken string
+	// CreateIfMissing create the CICD variable if it's not exists
+	CreateIfMissing bool
+	// the managed attributes of CICD variable, the nil or empty one is left as is
+	Masked       *bool
+	Protected    *bool
+	Raw          *bool
+	VariableType string
+	Description  string
+	// EnvironmentScopes the updated (or created) variable in each of environment scope, EnvScopeAll for all of the existing ones
+	EnvironmentScopes []string
+}
+
+// AllEnvScopes all of the environment scopes that have the variable are updated
+func (u HookUpdateVar) AllEnvScopes() bool {
+	return len(u.EnvironmentScopes) == 1 && u.EnvironmentScopes[0] == EnvScopeAll
 }
 
 type HookExecScript struct {
 	Path   string
 	EnvVar map[string]string
+	// DryRun the script is executed in dry run mode with GL_DRY_RUN=1, instead of only checking it's existence
+	DryRun bool
+	Args   []string
+	// Script the inline script that is executed by the Interpreter, it's exclusive with Path
+	Script      string
+	Interpreter []string
+	WorkDir     string
+	Timeout     time.Duration
+	// InheritEnv the env var names of the current process that are passed to the executable
+	InheritEnv []string
+	// TokenStdin the token is passed through stdin instead of GL_NEW_TOKEN
+	TokenStdin bool
+}
+
+// Executable the executable that must be exists, it's the interpreter for the inline script
+func (e HookExecScript) Executable() string {
+	if e.Script != "" && len(e.Interpreter) > 0 {
+		return e.Interpreter[0]
+	} else if e.Script != "" {
+		return defaultInterpreter
+	}
+	return e.Path
+}
+
+type HookK8sSecret struct {
+	// Kubeconfig and Context the kubeconfig to be used, the default one if they are empty
+	Kubeconfig string
+	Context    string
+	// InCluster use the service account of the running pod instead of kubeconfig
+	InCluster bool
+	Namespace string
+	Name      string
+	Key       string
+	// CreateIfMissing create the secret if it's not exists
+	CreateIfMissing bool
+	// RolloutRestart the deployment names in the same namespace that are restarted after the secret is updated
+	RolloutRestart []string
+}
+
+type HookVaultKV struct {
+	// Address, Namespace, CACert and Token are taken from the env vars as in Vault CLI if they are empty
+	Address   string
+	Namespace string
+	CACert    string
+	Mount     string
+	Path      string
+	Key       string
+	// AuthMethod the login method of Vault, token is used as is while approle and jwt are logged in to the AuthMount
+	AuthMethod string
+	AuthMount  string
+	Token      string
+	RoleID     string
+	SecretID   string
+	Role       string
+	JWT        string
+	// CreateIfMissing create the secret if it's not exists
+	CreateIfMissing bool
+}
+
+type HookHTTP struct {
+	URL     string
+	Method  string
+	Headers map[string]string
+	// BearerToken or BasicUsername and BasicPassword for the request auth, suggested set in env variable
+	BearerToken   string
+	BasicUsername string
+	BasicPassword string
+	// ExpectedStatus the success status codes, any of 2xx if it's empty
+	ExpectedStatus []int
+	CACert         string
+	ClientCert     string
+	ClientKey      string
+	Timeout        time.Duration
+	// DryRunMethod the method of probe request that is sent in dry run mode, nothing is sent if it's empty
+	DryRunMethod string
 }
 
 type Hook struct {
@@ -79,64 +283,293 @@ type Hook struct {
 	Args  map[string]any `yaml:"args"`
 }
 
-func (h Hook) validate() error {
+func (h Hook) validate(eval envEvaluator) (errs []error) {
 	if !contains(HookTypeList, h.Type) {
-		return ErrValidationHookInvalidType
+		return []error{ErrValidationHookInvalidType}
+	}
+
+	if err := h.validateTemplate(); err != nil {
+		errs = append(errs, err)
 	}
 
 	if h.Type == HookTypeUpdateVar {
-		uArgs := h.UpdateVarArgs()
+		uArgs := h.updateVarArgs(eval)
 		if uArgs.Name == "" {
-			return ErrValidationHookUpdateVarMissingName
+			errs = append(errs, ErrValidationHookUpdateVarMissingName)
 		}
 
-		if uArgs.Path == "" {
-			return ErrValidationHookUpdateVarMissingPath
+		if uArgs.Path == "" && uArgs.Type != UpdateVarTypeInstance {
+			errs = append(errs, ErrValidationHookUpdateVarMissingPath)
 		}
 
-		if !contains(ManagedTypeList, uArgs.Type) {
-			return ErrValidationHookUpdateVarInvalidType
+		if !contains(UpdateVarTypeList, uArgs.Type) {
+			errs = append(errs, ErrValidationHookUpdateVarInvalidType)
 		}
 
 		if uArgs.Gitlab != "" && uArgs.GitlabToken == "" {
-			return ErrValidationHookUpdateMissingGitlabToken
+			errs = append(errs, ErrValidationHookUpdateMissingGitlabToken)
+		}
+
+		if uArgs.VariableType != "" && !contains(VariableTypeList, uArgs.VariableType) {
+			errs = append(errs, ErrValidationHookUpdateVarInvalidVarType)
+		}
+
+		for _, key := range updateVarBoolArgs {
+			if _, isBool := h.Args[key].(bool); h.Args[key] != nil && !isBool {
+				errs = append(errs, fmt.Errorf("%w: %s", ErrValidationHookUpdateVarNotBoolArg, key))
+			}
+		}
+
+		errs = append(errs, h.strArgErrs(updateVarStrArgs, ErrValidationHookUpdateVarNotStrArg)...)
+
+		if envScopes, ok := h.getStrListOrNil("environment_scope"); !ok || slices.Contains(envScopes, "") {
+			errs = append(errs, ErrValidationHookUpdateVarInvalidEnvScope)
+		} else if len(envScopes) > 0 && uArgs.Type == UpdateVarTypeInstance {
+			errs = append(errs, ErrValidationHookUpdateVarInstanceEnvScope)
+		} else if slices.Contains(envScopes, EnvScopeAll) && (!uArgs.AllEnvScopes() || uArgs.CreateIfMissing) {
+			errs = append(errs, ErrValidationHookUpdateVarEnvScopeAll)
 		}
 	} else if h.Type == HookTypeExecCMD {
-		if h.Args["path"] == nil {
-			return ErrValidationHookExecCMDMissingPath
+		if h.Args["path"] == nil && h.Args["script"] == nil {
+			errs = append(errs, ErrValidationHookExecCMDMissingPath)
+		} else if h.Args["path"] != nil && h.Args["script"] != nil {
+			errs = append(errs, ErrValidationHookExecCMDPathAndScript)
+		}
+
+		for _, key := range execCMDBoolArgs {
+			if _, isBool := h.Args[key].(bool); h.Args[key] != nil && !isBool {
+				errs = append(errs, fmt.Errorf("%w: %s", ErrValidationHookExecCMDNotBoolArg, key))
+			}
+		}
+
+		for _, key := range execCMDListArgs {
+			if _, ok := h.getStrListOrNil(key); !ok {
+				errs = append(errs, fmt.Errorf("%w: %s", ErrValidationHookExecCMDNotListArg, key))
+			}
+		}
+
+		errs = append(errs, h.strArgErrs(execCMDStrArgs, ErrValidationHookExecCMDNotStrArg)...)
+
+		if timeout := h.getValueOrEmpty("timeout"); timeout != "" {
+			if duration, err := time.ParseDuration(timeout); err != nil || duration <= 0 {
+				errs = append(errs, ErrValidationHookExecCMDInvalidTimeout)
+			}
+		}
+	} else if h.Type == HookTypeK8sSecret {
+		for _, key := range k8sSecretRequiredArgs {
+			if argVal, _ := h.Args[key].(string); argVal == "" {
+				errs = append(errs, fmt.Errorf("%w: %s", ErrValidationHookK8sSecretMissingArg, key))
+			}
+		}
+
+		for _, key := range k8sSecretBoolArgs {
+			if _, isBool := h.Args[key].(bool); h.Args[key] != nil && !isBool {
+				errs = append(errs, fmt.Errorf("%w: %s", ErrValidationHookK8sSecretNotBoolArg, key))
+			}
+		}
+
+		if _, ok := h.getStrListOrNil("rollout_restart"); !ok {
+			errs = append(errs, ErrValidationHookK8sSecretNotListArg)
+		}
+
+		if h.getBoolOrFalse("in_cluster") && (h.Args["kubeconfig"] != nil || h.Args["context"] != nil) {
+			errs = append(errs, ErrValidationHookK8sSecretInClusterConfig)
+		}
+	} else if h.Type == HookTypeVaultKV {
+		authMethod := VaultAuthMethodToken
+		if h.Args["auth_method"] != nil {
+			authMethod, _ = h.Args["auth_method"].(string)
+		}
+		if !contains(VaultAuthMethodList, authMethod) {
+			errs = append(errs, ErrValidationHookVaultKVInvalidAuthMethod)
+		}
+
+		for _, key := range append(slices.Clone(vaultKVRequiredArgs), vaultKVAuthRequiredArgs[authMethod]...) {
+			if argVal, _ := h.Args[key].(string); argVal == "" {
+				errs = append(errs, fmt.Errorf("%w: %s", ErrValidationHookVaultKVMissingArg, key))
+			}
+		}
+
+		if _, isBool := h.Args["create_if_missing"].(bool); h.Args["create_if_missing"] != nil && !isBool {
+			errs = append(errs, ErrValidationHookVaultKVNotBoolArg)
+		}
+	} else if h.Type == HookTypeHTTP {
+		errs = append(errs, h.strArgErrs(httpStrArgs, ErrValidationHookHTTPNotStrArg)...)
+
+		if h.Args["url"] == nil || h.Args["url"] == "" {
+			errs = append(errs, ErrValidationHookHTTPMissingURL)
+		}
+
+		if _, ok := h.getStrMapOrNil("headers"); !ok {
+			errs = append(errs, ErrValidationHookHTTPInvalidHeaders)
+		}
+
+		if statuses, ok := h.getIntListOrNil("expected_status"); !ok || slices.ContainsFunc(statuses, func(code int) bool { return code < 100 || code > 599 }) {
+			errs = append(errs, ErrValidationHookHTTPInvalidStatus)
+		}
+
+		if timeout := h.getValueOrEmpty("timeout"); timeout != "" {
+			if duration, err := time.ParseDuration(timeout); err != nil || duration <= 0 {
+				errs = append(errs, ErrValidationHookHTTPInvalidTimeout)
+			}
+		}
+
+		if h.Args["bearer_token"] != nil && (h.Args["basic_username"] != nil || h.Args["basic_password"] != nil) {
+			errs = append(errs, ErrValidationHookHTTPAuthConflict)
+		}
+
+		if (h.Args["client_cert"] == nil) != (h.Args["client_key"] == nil) {
+			errs = append(errs, ErrValidationHookHTTPClientCert)
 		}
 	}
-	return nil
+	return errs
 }
 
 func (h Hook) UpdateVarArgs() HookUpdateVar {
+	return h.updateVarArgs(evalEnvVar)
+}
+
+func (h Hook) updateVarArgs(eval envEvaluator) HookUpdateVar {
+	envScopes, _ := h.getStrListOrNil("environment_scope")
+	for idx := range envScopes {
+		envScopes[idx] = eval(envScopes[idx])
+	}
 	return HookUpdateVar{
 		Type:        h.getValueOrEmpty("type"),
-		Name:        evalEnvVar(h.getValueOrEmpty("name")),
-		Path:        evalEnvVar(h.getValueOrEmpty("path")),
-		Gitlab:      evalEnvVar(h.getValueOrEmpty("gitlab")),
-		GitlabToken: evalEnvVar(h.getValueOrEmpty("gitlab_token")),
+		Name:        eval(h.getValueOrEmpty("name")),
+		Path:        eval(h.getValueOrEmpty("path")),
+		Gitlab:      eval(h.getValueOrEmpty("gitlab")),
+		GitlabToken: eval(h.getValueOrEmpty("gitlab_token")),
+
+		CreateIfMissing:   h.getBoolOrFalse("create_if_missing"),
+		Masked:            h.getBoolOrNil("masked"),
+		Protected:         h.getBoolOrNil("protected"),
+		Raw:               h.getBoolOrNil("raw"),
+		VariableType:      h.getValueOrEmpty("variable_type"),
+		Description:       eval(h.getValueOrEmpty("description")),
+		EnvironmentScopes: envScopes,
+	}
+}
+
+// getStrListOrNil fetch the content in hook arguments that can be a string or list of string, it's not ok for the other types
+func (h Hook) getStrListOrNil(key string) (results []string, ok bool) {
+	switch argVal := h.Args[key].(type) {
+	case nil:
+		return nil, true
+	case string:
+		return []string{argVal}, true
+	case []string:
+		return slices.Clone(argVal), true
+	case []any:
+		for _, item := range argVal {
+			strItem, isStr := item.(string)
+			if !isStr {
+				return nil, false
+			}
+			results = append(results, strItem)
+		}
+		return results, true
+	}
+	return nil, false
+}
+
+// getIntListOrNil fetch the content in hook arguments that can be an integer or list of integer, it's not ok for the other types
+func (h Hook) getIntListOrNil(key string) (results []int, ok bool) {
+	switch argVal := h.Args[key].(type) {
+	case nil:
+		return nil, true
+	case int:
+		return []int{argVal}, true
+	case []int:
+		return slices.Clone(argVal), true
+	case []any:
+		for _, item := range argVal {
+			intItem, isInt := item.(int)
+			if !isInt {
+				return nil, false
+			}
+			results = append(results, intItem)
+		}
+		return results, true
 	}
+	return nil, false
 }
 
-// getValueOrEmpty fetch content in hook arguments, also evaluating the content for any env var pattern
+// getStrMapOrNil fetch the map of string content in hook arguments, the values are evaluated for any env var pattern
+func (h Hook) getStrMapOrNil(key string) (results map[string]string, ok bool) {
+	switch argVal := h.Args[key].(type) {
+	case nil:
+		return nil, true
+	case map[string]string:
+		return maps.Clone(argVal), true
+	case map[any]any:
+		results = make(map[string]string)
+		for key, value := range argVal {
+			strKey, keyOk := key.(string)
+			strValue, valueOk := value.(string)
+			if !keyOk || !valueOk {
+				return nil, false
+			}
+			results[strKey] = evalEnvVar(strValue)
+		}
+		return results, true
+	}
+	return nil, false
+}
+
+// getBoolOrNil fetch the boolean content in hook arguments, nil if it's not set
+func (h Hook) getBoolOrNil(key string) *bool {
+	argVal, ok := h.Args[key].(bool)
+	if !ok {
+		return nil
+	}
+	return &argVal
+}
+
+// getBoolOrFalse fetch the boolean content in hook arguments, false if it's not set
+func (h Hook) getBoolOrFalse(key string) bool {
+	argVal, _ := h.Args[key].(bool)
+	return argVal
+}
+
+// getValueOrEmpty fetch the string content in hook arguments, empty if it's not set or not a string (reported by strArgErrs in validation)
 func (h Hook) getValueOrEmpty(key string) string {
-	argVal := h.Args[key]
-	if argVal == nil {
-		return ""
+	argVal, _ := h.Args[key].(string)
+	return argVal
+}
+
+// strArgErrs the errors of the hook arguments that are set but not a string
+func (h Hook) strArgErrs(keys []string, errNotStr error) (errs []error) {
+	for _, key := range keys {
+		if _, isStr := h.Args[key].(string); h.Args[key] != nil && !isStr {
+			errs = append(errs, fmt.Errorf("%w: %s", errNotStr, key))
+		}
 	}
-	return argVal.(string)
+	return errs
 }
 
 // ExecCMDArgs return the list of argument in execution hook exec_cmd
 func (h Hook) ExecCMDArgs() HookExecScript {
 	execArgs := HookExecScript{
-		Path: evalEnvVar(h.getValueOrEmpty("path")),
+		Path:       evalEnvVar(h.getValueOrEmpty("path")),
+		DryRun:     h.getBoolOrFalse("dry_run"),
+		Script:     h.getValueOrEmpty("script"),
+		WorkDir:    evalEnvVar(h.getValueOrEmpty("workdir")),
+		TokenStdin: h.getBoolOrFalse("token_stdin"),
 	}
 	execArgs.EnvVar = make(map[string]string)
+	execArgs.Timeout, _ = time.ParseDuration(h.getValueOrEmpty("timeout"))
+	execArgs.Interpreter, _ = h.getStrListOrNil("interpreter")
+	execArgs.Args, _ = h.getStrListOrNil("args")
+	for idx := range execArgs.Args {
+		execArgs.Args[idx] = evalEnvVar(execArgs.Args[idx])
+	}
+	execArgs.InheritEnv, _ = h.getStrListOrNil("inherit_env")
+	if h.Args["inherit_env"] == nil {
+		execArgs.InheritEnv = defaultInheritEnv
+	}
 
-	if envVar := h.Args["env"]; envVar != nil {
-		convEnvVar := envVar.(map[any]any)
+	if convEnvVar, ok := h.Args["env"].(map[any]any); ok {
 		for key, value := range convEnvVar {
 			strKey, keyOk := key.(string)
 			strValue, valueOk := value.(string)
@@ -150,27 +583,177 @@ func (h Hook) ExecCMDArgs() HookExecScript {
 	return execArgs
 }
 
+// K8sSecretArgs return the list of argument in hook k8s_secret
+func (h Hook) K8sSecretArgs() HookK8sSecret {
+	k8sArgs := HookK8sSecret{
+		Kubeconfig:      evalEnvVar(h.getValueOrEmpty("kubeconfig")),
+		Context:         evalEnvVar(h.getValueOrEmpty("context")),
+		InCluster:       h.getBoolOrFalse("in_cluster"),
+		Namespace:       evalEnvVar(h.getValueOrEmpty("namespace")),
+		Name:            evalEnvVar(h.getValueOrEmpty("name")),
+		Key:             evalEnvVar(h.getValueOrEmpty("key")),
+		CreateIfMissing: h.getBoolOrFalse("create_if_missing"),
+	}
+	k8sArgs.RolloutRestart, _ = h.getStrListOrNil("rollout_restart")
+	return k8sArgs
+}
+
+// VaultKVArgs return the list of argument in hook vault_kv
+func (h Hook) VaultKVArgs() HookVaultKV {
+	vaultArgs := HookVaultKV{
+		Address:         evalEnvVar(h.getValueOrEmpty("address")),
+		Namespace:       evalEnvVar(h.getValueOrEmpty("namespace")),
+		CACert:          evalEnvVar(h.getValueOrEmpty("ca_cert")),
+		Mount:           evalEnvVar(h.getValueOrEmpty("mount")),
+		Path:            evalEnvVar(h.getValueOrEmpty("path")),
+		Key:             evalEnvVar(h.getValueOrEmpty("key")),
+		AuthMethod:      h.getValueOrEmpty("auth_method"),
+		AuthMount:       evalEnvVar(h.getValueOrEmpty("auth_mount")),
+		Token:           evalEnvVar(h.getValueOrEmpty("token")),
+		RoleID:          evalEnvVar(h.getValueOrEmpty("role_id")),
+		SecretID:        evalEnvVar(h.getValueOrEmpty("secret_id")),
+		Role:            evalEnvVar(h.getValueOrEmpty("role")),
+		JWT:             evalEnvVar(h.getValueOrEmpty("jwt")),
+		CreateIfMissing: h.getBoolOrFalse("create_if_missing"),
+	}
+	if vaultArgs.Mount == "" {
+		vaultArgs.Mount = defaultVaultMount
+	}
+	if vaultArgs.AuthMethod == "" {
+		vaultArgs.AuthMethod = VaultAuthMethodToken
+	}
+	return vaultArgs
+}
+
+// HTTPArgs return the list of argument in hook http
+func (h Hook) HTTPArgs() HookHTTP {
+	httpArgs := HookHTTP{
+		URL:           evalEnvVar(h.getValueOrEmpty("url")),
+		Method:        strings.ToUpper(h.getValueOrEmpty("method")),
+		BearerToken:   evalEnvVar(h.getValueOrEmpty("bearer_token")),
+		BasicUsername: evalEnvVar(h.getValueOrEmpty("basic_username")),
+		BasicPassword: evalEnvVar(h.getValueOrEmpty("basic_password")),
+		CACert:        evalEnvVar(h.getValueOrEmpty("ca_cert")),
+		ClientCert:    evalEnvVar(h.getValueOrEmpty("client_cert")),
+		ClientKey:     evalEnvVar(h.getValueOrEmpty("client_key")),
+		DryRunMethod:  strings.ToUpper(h.getValueOrEmpty("dry_run_method")),
+	}
+	if httpArgs.Method == "" {
+		httpArgs.Method = defaultHTTPMethod
+	}
+	httpArgs.Headers, _ = h.getStrMapOrNil("headers")
+	httpArgs.ExpectedStatus, _ = h.getIntListOrNil("expected_status")
+	httpArgs.Timeout, _ = time.ParseDuration(h.getValueOrEmpty("timeout"))
+	return httpArgs
+}
+
 func (h Hook) StrArgs() string {
 	switch h.Type {
 	case HookTypeUpdateVar:
 		args := h.UpdateVarArgs()
 		strargs := fmt.Sprintf("type:%s,path:%s,name:%s", args.Type, args.Path, args.Name)
+		if len(args.EnvironmentScopes) > 0 {
+			strargs = fmt.Sprintf("%s,environment_scope:%s", strargs, strings.Join(args.EnvironmentScopes, "|"))
+		}
 		if args.Gitlab != "" {
 			strargs = fmt.Sprintf("%s,gitlab:%s", strargs, args.Gitlab)
 		}
 		return strargs
 	case HookTypeExecCMD:
 		args := h.ExecCMDArgs()
+		if args.Script != "" {
+			return fmt.Sprintf("script:%s", args.Executable())
+		}
 		return fmt.Sprintf("path:%s", args.Path)
+	case HookTypeK8sSecret:
+		args := h.K8sSecretArgs()
+		strargs := fmt.Sprintf("namespace:%s,name:%s,key:%s", args.Namespace, args.Name, args.Key)
+		if args.Context != "" {
+			strargs = fmt.Sprintf("%s,context:%s", strargs, args.Context)
+		}
+		return strargs
+	case HookTypeVaultKV:
+		args := h.VaultKVArgs()
+		strargs := fmt.Sprintf("mount:%s,path:%s,key:%s,auth_method:%s", args.Mount, args.Path, args.Key, args.AuthMethod)
+		if args.Address != "" {
+			strargs = fmt.Sprintf("%s,address:%s", strargs, args.Address)
+		}
+		return strargs
+	case HookTypeHTTP:
+		args := h.HTTPArgs()
+		return fmt.Sprintf("method:%s,url:%s", args.Method, args.URL)
 	}
 	return ""
 }
 
+// ResolvedArgs the hook arguments with their env var patterns evaluated, the secret ones are masked
+func (h Hook) ResolvedArgs() map[string]any {
+	results := make(map[string]any, len(h.Args))
+	for key, value := range h.Args {
+		switch {
+		case slices.Contains(secretHookArgs, key) && value != nil && value != "":
+			results[key] = MaskedValue
+		case slices.Contains(secretMapHookArgs, key):
+			results[key] = maskedArg(value)
+		case slices.Contains(rawHookArgs, key):
+			results[key] = value
+		default:
+			results[key] = resolveArg(value)
+		}
+	}
+	return results
+}
+
+// resolveArg evaluate the env var patterns in the hook argument, the nested map keys are converted to string
+func resolveArg(value any) any {
+	switch argVal := value.(type) {
+	case string:
+		return evalEnvVar(argVal)
+	case []any:
+		results := make([]any, 0, len(argVal))
+		for _, item := range argVal {
+			results = append(results, resolveArg(item))
+		}
+		return results
+	case map[any]any:
+		results := make(map[string]any, len(argVal))
+		for key, item := range argVal {
+			results[fmt.Sprint(key)] = resolveArg(item)
+		}
+		return results
+	case map[string]any:
+		results := make(map[string]any, len(argVal))
+		for key, item := range argVal {
+			results[key] = resolveArg(item)
+		}
+		return results
+	}
+	return value
+}
+
+// maskedArg mask all of the values in the map of hook argument, only the keys are kept
+func maskedArg(value any) any {
+	results, ok := resolveArg(value).(map[string]any)
+	if !ok {
+		return value
+	}
+	for key := range results {
+		results[key] = MaskedValue
+	}
+	return results
+}
+
 type AccessToken struct {
-	Name              string `yaml:"name"`
-	RenewBefore       string `yaml:"renew_before"`
-	ExpiryAfterRotate string `yaml:"expiry_after_rotate"`
-	Hooks             []Hook `yaml:"hooks"`
+	Name              string   `yaml:"name"`
+	RenewBefore       string   `yaml:"renew_before"`
+	ExpiryAfterRotate string   `yaml:"expiry_after_rotate"`
+	CreateIfMissing   bool     `yaml:"create_if_missing"`
+	Scopes            []string `yaml:"scopes"`
+	AccessLevel       string   `yaml:"access_level"`
+	OnExpired         string   `yaml:"on_expired"`
+	Tags              []string `yaml:"tags"`
+	Hooks             []Hook   `yaml:"hooks"`
+	Notify            []Notify `yaml:"notify"`
 }
 
 func (at AccessToken) RenewBeforeDuration() (time.Duration, error) {
@@ -181,43 +764,72 @@ func (at AccessToken) ExpiryAfterRotateDuration() (time.Duration, error) {
 	return durationParse(at.ExpiryAfterRotate)
 }
 
-func (at AccessToken) validate() error {
+// AccessLevelValue the value of access level in Gitlab API, 0 returned if it's not set
+func (at AccessToken) AccessLevelValue() int {
+	return accessLevelValues[at.AccessLevel]
+}
+
+// RecreateOnExpired the expired or revoked access token is recreated with the same attributes
+func (at AccessToken) RecreateOnExpired() bool {
+	return at.OnExpired == OnExpiredRecreate
+}
+
+func (at AccessToken) validate(mType string) (errs []error) {
 	if at.Name == "" {
-		return ErrValidationTokenEmptyName
+		errs = append(errs, ErrValidationTokenEmptyName)
+	}
+
+	if at.AccessLevel != "" {
+		if mType == ManagedTypePersonal {
+			errs = append(errs, ErrValidationTokenAccessLevelByPersonalType)
+		} else if !contains(AccessLevelList, at.AccessLevel) {
+			errs = append(errs, ErrValidationTokenInvalidAccessLevel)
+		}
+	}
+
+	if at.CreateIfMissing && len(at.Scopes) == 0 {
+		errs = append(errs, ErrValidationTokenCreateMissingScopes)
+	}
+
+	if at.OnExpired != "" && !contains(OnExpiredList, at.OnExpired) {
+		errs = append(errs, ErrValidationTokenInvalidOnExpired)
 	}
 
 	if at.RenewBefore != "" && !renewBeforeRe.MatchString(at.RenewBefore) {
-		return ErrValidationManagedInvalidRenewBefore
+		errs = append(errs, ErrValidationManagedInvalidRenewBefore)
 	}
 
 	if at.ExpiryAfterRotate != "" && !renewBeforeRe.MatchString(at.ExpiryAfterRotate) {
-		return ErrValidationManagedInvalidExpiryAfterRotate
+		errs = append(errs, ErrValidationManagedInvalidExpiryAfterRotate)
 	}
-	return nil
+	return errs
 }
 
 type ManagedToken struct {
 	Path   string        `yaml:"path"`
 	Type   string        `yaml:"type"`
 	Ref    string        `yaml:"include"`
+	Tags   []string      `yaml:"tags"`
 	Tokens []AccessToken `yaml:"access_tokens"`
+	// location of the managed token in it's source file, used for locating the validation error
+	location []any
 }
 
-func (m *ManagedToken) validate() error {
+func (m *ManagedToken) validate() (errs []error) {
 	if !contains(ManagedTypeList, m.Type) {
-		return ErrValidationManagedInvalidType
+		errs = append(errs, ErrValidationManagedInvalidType)
 	}
 
 	// except personal_token path property not required
 	if m.Path == "" && m.Type != ManagedTypePersonal {
-		return ErrValidationManagedEmptyPath
+		errs = append(errs, ErrValidationManagedEmptyPath)
 	}
 
 	if len(m.Tokens) == 0 {
-		return ErrValidationManagedEmptyTokenList
+		errs = append(errs, ErrValidationManagedEmptyTokenList)
 	}
 
-	return nil
+	return errs
 }
 
 type Config struct {
@@ -227,6 +839,18 @@ type Config struct {
 	DefaultRenewBefore       string         `yaml:"default_renew_before"`
 	DefaultExpiryAfterRotate string         `yaml:"default_expiry_after_rotate"`
 	Managed                  []ManagedToken `yaml:"manage_tokens"`
+	// Notify the global notify for all of the access tokens
+	Notify []Notify `yaml:"notify"`
+	// Recovery the store of the rotated token for recovering it when the hooks are failed, disabled if it's not set
+	Recovery *Recovery `yaml:"recovery"`
+	// Journal the records of the execution steps for resuming the interrupted one, disabled if it's not set
+	Journal *Journal `yaml:"journal"`
+	// API the rate limit, retry and timeout of the Gitlab API calls, the default of the Gitlab client is used if it's not set
+	API *API `yaml:"api"`
+	// path of the main config file
+	path string
+	// offline skip the env variable evaluation, used in validating config without the secrets
+	offline bool
 }
 
 func (c Config) DefaultRenewBeforeDuration() (time.Duration, error) {
@@ -237,33 +861,50 @@ func (c Config) DefaultExpiryAfterRotateDuration() (time.Duration, error) {
 	return durationParse(c.DefaultExpiryAfterRotate)
 }
 
-// appendReference adding more context to returned error
-func appendErrReferences(err error, references []string) error {
-	for _, errRef := range references {
-		err = errors.Join(err, errors.New(errRef))
+func (c Config) validate() error {
+	var errs ValidationErrors
+	// appender register the validation errors along with it's location and references
+	appender := func(refs []string, location []any, file string, validationErrs ...error) {
+		for _, err := range validationErrs {
+			errs = append(errs, ValidationError{Err: err, File: file, References: slices.Clone(refs), location: location})
+		}
 	}
-	return err
-}
 
-func (c Config) validate() (err error) {
 	if c.Host == "" {
-		return ErrValidationEmptyHost
+		appender(nil, []any{"host"}, c.path, ErrValidationEmptyHost)
 	}
 
 	if len(c.Managed) == 0 {
-		return ErrValidationEmptyManagedList
+		appender(nil, []any{"manage_tokens"}, c.path, ErrValidationEmptyManagedList)
 	}
 
 	if c.Token == "" {
-		return ErrValidationEmptyGitlabToken
+		appender(nil, []any{"token"}, c.path, ErrValidationEmptyGitlabToken)
 	}
 
-	if _, err = c.DefaultRenewBeforeDuration(); err != nil {
-		return errors.Join(ErrValidationInvalidDefaultRenewBefore, err)
+	if _, err := c.DefaultRenewBeforeDuration(); err != nil {
+		appender(nil, []any{"default_renew_before"}, c.path, errors.Join(ErrValidationInvalidDefaultRenewBefore, err))
 	}
 
-	if _, err = c.DefaultExpiryAfterRotateDuration(); err != nil {
-		return errors.Join(ErrValidationInvalidDefaultExpiryAfterRotate, err)
+	if _, err := c.DefaultExpiryAfterRotateDuration(); err != nil {
+		appender(nil, []any{"default_expiry_after_rotate"}, c.path, errors.Join(ErrValidationInvalidDefaultExpiryAfterRotate, err))
+	}
+
+	for idx := range c.Notify {
+		errRefsNotify := []string{fmt.Sprintf("global notify seq num: %d", idx+1)}
+		appender(errRefsNotify, []any{"notify", idx}, c.path, c.Notify[idx].validate()...)
+	}
+
+	if c.Recovery != nil {
+		appender(nil, []any{"recovery"}, c.path, c.Recovery.validate()...)
+	}
+
+	if c.Journal != nil {
+		appender(nil, []any{"journal"}, c.path, c.Journal.validate()...)
+	}
+
+	if c.API != nil {
+		appender(nil, []any{"api"}, c.path, c.API.validate()...)
 	}
 
 	hookUseTokenUsed := false
@@ -296,63 +937,75 @@ func (c Config) validate() (err error) {
 		if !exists {
 			managedTrackUsed[managedID] = managed.Ref
 		} else {
-			err = ErrValidationManagedDuplicatedDefinition
+			err := ErrValidationManagedDuplicatedDefinition
 			if prevManageRef != "" {
 				err = errors.Join(err, fmt.Errorf("previously defined at %s", prevManageRef))
 			}
-
-			return appendErrReferences(err, errRefsManage)
+			appender(errRefsManage, managed.location, managed.Ref, err)
 		}
 
-		if err = managed.validate(); err != nil {
-			return appendErrReferences(err, errRefsManage)
-		}
+		appender(errRefsManage, managed.location, managed.Ref, managed.validate()...)
 
 		for tkIdx := range managed.Tokens {
 			tkn := managed.Tokens[tkIdx]
 			num := tkIdx + 1
 			//nolint
 			errRefTkn := append(errRefsManage, fmt.Sprintf("access_token seq num: %d (name: %s)", num, tkn.Name))
-			if err = tkn.validate(); err != nil {
-				return appendErrReferences(err, errRefTkn)
-			}
+			tknLocation := appendLocation(managed.location, "access_tokens", tkIdx)
+			appender(errRefTkn, tknLocation, managed.Ref, tkn.validate(managed.Type)...)
 
 			for hkIdx := range managed.Tokens[tkIdx].Hooks {
 				hook := managed.Tokens[tkIdx].Hooks[hkIdx]
 				//nolint
 				errRefsHook := append(errRefTkn, fmt.Sprintf("hook seq num: %d", hkIdx+1))
-
-				if err = hook.validate(); err != nil {
-					return appendErrReferences(err, errRefsHook)
-				}
+				hookLocation := appendLocation(tknLocation, "hooks", hkIdx)
+				appender(errRefsHook, hookLocation, managed.Ref, hook.validate(c.envEval())...)
 
 				// use_token hook validations
 				if hook.Type == HookTypeUseToken {
 					if managed.Type != ManagedTypePersonal {
-						return appendErrReferences(ErrValidationHookUseTokenNotByPersonalType, errRefsHook)
+						appender(errRefsHook, hookLocation, managed.Ref, ErrValidationHookUseTokenNotByPersonalType)
 					}
 					if hookUseTokenUsed {
-						return appendErrReferences(ErrValidationHookUseTokenAlreadyUse, errRefsHook)
+						appender(errRefsHook, hookLocation, managed.Ref, ErrValidationHookUseTokenAlreadyUse)
 					}
 
 					if hkIdx != 0 {
-						return appendErrReferences(ErrValidationHookUseTokenNotFirstSeq, errRefsHook)
+						appender(errRefsHook, hookLocation, managed.Ref, ErrValidationHookUseTokenNotFirstSeq)
 					}
 
 					hookUseTokenUsed = true
 				}
 			}
+
+			for ntIdx := range tkn.Notify {
+				//nolint
+				errRefsNotify := append(errRefTkn, fmt.Sprintf("notify seq num: %d", ntIdx+1))
+				appender(errRefsNotify, appendLocation(tknLocation, "notify", ntIdx), managed.Ref, tkn.Notify[ntIdx].validate()...)
+			}
 		}
 	}
 
-	return nil
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// envEval return the function in evaluating env variable, in offline mode the pattern is kept as is
+func (c Config) envEval() envEvaluator {
+	if c.offline {
+		return keepEnvVar
+	}
+	return evalEnvVar
 }
 
 // InitValues filling out the default values and some env variable evaluation
 func (c *Config) InitValues() error {
+	eval := c.envEval()
 	// evaluate contents from environment variable
-	c.Token = evalEnvVar(c.Token)
-	c.Host = evalEnvVar(c.Host)
+	c.Token = eval(c.Token)
+	c.Host = eval(c.Host)
 
 	for idx := range c.Managed {
 		managed := c.Managed[idx]
@@ -377,8 +1030,11 @@ func (c *Config) InitValues() error {
 
 				// set path and type with the same as configured in managed config if both of them is not set
 				if tkn.Hooks[hkIdx].Type == HookTypeUpdateVar && managed.Type != ManagedTypePersonal {
-					hkUpdateVarargs := tkn.Hooks[hkIdx].UpdateVarArgs()
+					hkUpdateVarargs := tkn.Hooks[hkIdx].updateVarArgs(eval)
 					if hkUpdateVarargs.Path == "" && hkUpdateVarargs.Type == "" {
+						if c.Managed[idx].Tokens[tkIdx].Hooks[hkIdx].Args == nil {
+							c.Managed[idx].Tokens[tkIdx].Hooks[hkIdx].Args = map[string]any{}
+						}
 						c.Managed[idx].Tokens[tkIdx].Hooks[hkIdx].Args["path"] = managed.Path
 						c.Managed[idx].Tokens[tkIdx].Hooks[hkIdx].Args["type"] = managed.Type
 					}
@@ -0,0 +1,219 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const (
+	NotifyTypeSlack = "slack"
+	NotifyTypeTeams = "teams"
+	NotifyTypeEmail = "email"
+	NotifyOnRotated = "rotated"
+	NotifyOnFailed  = "failed"
+
+	NotifyEmailTLSStartTLS = "starttls"
+	NotifyEmailTLSImplicit = "implicit"
+	NotifyEmailTLSNone     = "none"
+
+	defaultSMTPPort      = 587
+	implicitTLSSMTPPort  = 465
+	defaultNotifySubject = `[gitlab-token-updater] access token {{ .Name }} is {{ .Event }}`
+	defaultNotifyMessage = `access token {{ .Name }} ({{ .Type }}: {{ .Path }}) is {{ .Event }}` +
+		`{{ if .ExpiresAt }}, the new expiry is {{ .ExpiresAt.Format "2006-01-02" }}{{ end }}` +
+		`{{ range .Errors }}` + "\n" + `- {{ . }}{{ end }}`
+)
+
+var (
+	NotifyTypeList = []string{
+		NotifyTypeSlack,
+		NotifyTypeTeams,
+		NotifyTypeEmail,
+	}
+	NotifyOnList = []string{
+		NotifyOnRotated,
+		NotifyOnFailed,
+	}
+	NotifyEmailTLSList = []string{
+		NotifyEmailTLSStartTLS,
+		NotifyEmailTLSImplicit,
+		NotifyEmailTLSNone,
+	}
+	// notifyStrArgs the string arguments of the notify
+	notifyStrArgs = []string{"webhook_url", "host", "username", "password", "from", "tls"}
+)
+
+var (
+	ErrValidationNotifyInvalidType       = fmt.Errorf("invalid notify type, the valid one are %s", strings.Join(NotifyTypeList, ","))
+	ErrValidationNotifyInvalidOn         = fmt.Errorf("invalid notify on, the valid one are %s", strings.Join(NotifyOnList, ","))
+	ErrValidationNotifyInvalidTemplate   = errors.New("invalid notify subject or message, it must be a Go template without the token")
+	ErrValidationNotifyNotStrArg         = errors.New("arg must be a string in notify")
+	ErrValidationNotifyMissingWebhookURL = errors.New("missing arg webhook_url in slack or teams notify")
+	ErrValidationNotifyEmailMissingArg   = fmt.Errorf("missing required arg in %s notify", NotifyTypeEmail)
+	ErrValidationNotifyEmailInvalidPort  = fmt.Errorf("invalid arg port in %s notify", NotifyTypeEmail)
+	ErrValidationNotifyEmailInvalidTo    = fmt.Errorf("arg to must be a string or list of string in %s notify", NotifyTypeEmail)
+	ErrValidationNotifyEmailInvalidTLS   = fmt.Errorf("invalid arg tls in %s notify, the valid one are %s", NotifyTypeEmail, strings.Join(NotifyEmailTLSList, ","))
+)
+
+// NotifyTemplateData the data that is available in the notify subject and message template, it never contains the token
+type NotifyTemplateData struct {
+	// Event the notified event, rotated or failed
+	Event string
+	Name  string
+	Path  string
+	Type  string
+	// Status the status of access token as in the execution report
+	Status    string
+	ExpiresAt *time.Time
+	Errors    []string
+	Host      string
+}
+
+// NotifyEmail the arguments of email notify
+type NotifyEmail struct {
+	Host string
+	Port int
+	// TLS the TLS mode, implicit for port 465 and starttls for the others if it's not set
+	TLS      string
+	Username string
+	Password string
+	From     string
+	To       []string
+}
+
+// Notify sending the notification of rotated or failed access token, it's attached in access token or globally for all of them
+type Notify struct {
+	Type string `yaml:"type"`
+	// On the notified events, all of them if it's empty
+	On      []string `yaml:"on"`
+	Subject string   `yaml:"subject"`
+	Message string   `yaml:"message"`
+	// NonFatal the notification failure is only logged instead of failing the access token
+	NonFatal bool           `yaml:"non_fatal"`
+	Args     map[string]any `yaml:"args"`
+}
+
+// hook the arguments as hook, so it's helper functions are reusable
+func (n Notify) hook() Hook {
+	return Hook{Type: n.Type, Args: n.Args}
+}
+
+// Notified whether the event is notified
+func (n Notify) Notified(event string) bool {
+	return len(n.On) == 0 || slices.Contains(n.On, event)
+}
+
+// WebhookURL the incoming webhook URL of slack and teams notify
+func (n Notify) WebhookURL() string {
+	return evalEnvVar(n.hook().getValueOrEmpty("webhook_url"))
+}
+
+// EmailArgs the arguments of email notify
+func (n Notify) EmailArgs() NotifyEmail {
+	h := n.hook()
+	args := NotifyEmail{
+		Host:     evalEnvVar(h.getValueOrEmpty("host")),
+		Port:     defaultSMTPPort,
+		Username: evalEnvVar(h.getValueOrEmpty("username")),
+		Password: evalEnvVar(h.getValueOrEmpty("password")),
+		From:     evalEnvVar(h.getValueOrEmpty("from")),
+	}
+	if port, ok := n.Args["port"].(int); ok {
+		args.Port = port
+	}
+	if args.TLS = h.getValueOrEmpty("tls"); args.TLS == "" {
+		args.TLS = NotifyEmailTLSStartTLS
+		if args.Port == implicitTLSSMTPPort {
+			args.TLS = NotifyEmailTLSImplicit
+		}
+	}
+	args.To, _ = h.getStrListOrNil("to")
+	for idx := range args.To {
+		args.To[idx] = evalEnvVar(args.To[idx])
+	}
+	return args
+}
+
+// renderTemplate render the notify template, the default one is used if it's empty
+func renderTemplate(name, tmpl, defaultTmpl string, data NotifyTemplateData) (string, error) {
+	if tmpl == "" {
+		tmpl = defaultTmpl
+	}
+	parsed, err := template.New(name).Funcs(templateFuncs).Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return "", err
+	}
+
+	var result bytes.Buffer
+	if err = parsed.Execute(&result, data); err != nil {
+		return "", fmt.Errorf("error in rendering notify %s: %w", name, err)
+	}
+	return result.String(), nil
+}
+
+// Render render the subject and message of the notification
+func (n Notify) Render(data NotifyTemplateData) (subject, message string, err error) {
+	if subject, err = renderTemplate("subject", n.Subject, defaultNotifySubject, data); err != nil {
+		return "", "", err
+	}
+	message, err = renderTemplate("message", n.Message, defaultNotifyMessage, data)
+	return subject, message, err
+}
+
+func (n Notify) validate() (errs []error) {
+	if !contains(NotifyTypeList, n.Type) {
+		return []error{ErrValidationNotifyInvalidType}
+	}
+
+	for _, on := range n.On {
+		if !contains(NotifyOnList, on) {
+			errs = append(errs, ErrValidationNotifyInvalidOn)
+		}
+	}
+
+	expiresAt := time.Now()
+	sample := NotifyTemplateData{
+		Event: NotifyOnFailed, Name: "sample", Path: "path/to/sample", Type: ManagedTypeRepository,
+		Status: NotifyOnFailed, ExpiresAt: &expiresAt, Errors: []string{"sample error"}, Host: defaultHost,
+	}
+	if _, _, err := n.Render(sample); err != nil {
+		errs = append(errs, fmt.Errorf("%w: %v", ErrValidationNotifyInvalidTemplate, err))
+	}
+
+	for _, key := range notifyStrArgs {
+		if _, isStr := n.Args[key].(string); n.Args[key] != nil && !isStr {
+			errs = append(errs, fmt.Errorf("%w: %s", ErrValidationNotifyNotStrArg, key))
+		}
+	}
+
+	h := n.hook()
+	switch n.Type {
+	case NotifyTypeSlack, NotifyTypeTeams:
+		if argVal, _ := n.Args["webhook_url"].(string); argVal == "" {
+			errs = append(errs, ErrValidationNotifyMissingWebhookURL)
+		}
+	case NotifyTypeEmail:
+		for _, key := range []string{"host", "from"} {
+			if argVal, _ := n.Args[key].(string); argVal == "" {
+				errs = append(errs, fmt.Errorf("%w: %s", ErrValidationNotifyEmailMissingArg, key))
+			}
+		}
+		if to, ok := h.getStrListOrNil("to"); !ok {
+			errs = append(errs, ErrValidationNotifyEmailInvalidTo)
+		} else if len(to) == 0 {
+			errs = append(errs, fmt.Errorf("%w: %s", ErrValidationNotifyEmailMissingArg, "to"))
+		}
+		if port, isInt := n.Args["port"].(int); n.Args["port"] != nil && (!isInt || port < 1 || port > 65535) {
+			errs = append(errs, ErrValidationNotifyEmailInvalidPort)
+		}
+		if tlsMode, _ := n.Args["tls"].(string); tlsMode != "" && !contains(NotifyEmailTLSList, tlsMode) {
+			errs = append(errs, ErrValidationNotifyEmailInvalidTLS)
+		}
+	}
+	return errs
+}
@@ -0,0 +1,82 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+
+	"filippo.io/age"
+)
+
+var (
+	ErrValidationRecoveryMissingRecipient = errors.New("missing recipients in recovery")
+	ErrValidationRecoveryInvalidRecipient = errors.New("invalid age X25519 recipient in recovery")
+	ErrValidationRecoveryMissingBackend   = errors.New("missing file or gitlab_var in recovery")
+	ErrValidationJournalMissingBackend    = errors.New("missing file or gitlab_var in journal")
+	ErrValidationGitlabVarMissingArg      = errors.New("missing required arg of gitlab_var")
+	ErrValidationGitlabVarInvalidType     = fmt.Errorf("type of gitlab_var must be %s or %s", ManagedTypeRepository, ManagedTypeGroup)
+)
+
+// GitlabVarBackend the dedicated CICD variable for keeping the recovery entries or journal, it's created as protected one
+type GitlabVarBackend struct {
+	Type string `yaml:"type"`
+	Path string `yaml:"path"`
+	Name string `yaml:"name"`
+}
+
+func (v GitlabVarBackend) validate() (errs []error) {
+	if v.Type != ManagedTypeRepository && v.Type != ManagedTypeGroup {
+		errs = append(errs, ErrValidationGitlabVarInvalidType)
+	}
+	if v.Path == "" {
+		errs = append(errs, fmt.Errorf("%w: %s", ErrValidationGitlabVarMissingArg, "path"))
+	}
+	if v.Name == "" {
+		errs = append(errs, fmt.Errorf("%w: %s", ErrValidationGitlabVarMissingArg, "name"))
+	}
+	return errs
+}
+
+// Recovery keeping the rotated token encrypted before executing the hooks, so it's recoverable when all of the hooks attempts are failed
+type Recovery struct {
+	// Recipients the age X25519 public keys for encrypting the token
+	Recipients []string `yaml:"recipients"`
+	// File the local file for keeping the recovery entries
+	File      string            `yaml:"file"`
+	GitlabVar *GitlabVarBackend `yaml:"gitlab_var"`
+}
+
+func (r Recovery) validate() (errs []error) {
+	if len(r.Recipients) == 0 {
+		errs = append(errs, ErrValidationRecoveryMissingRecipient)
+	}
+	for _, recipient := range r.Recipients {
+		if _, err := age.ParseX25519Recipient(recipient); err != nil {
+			errs = append(errs, fmt.Errorf("%w: %v", ErrValidationRecoveryInvalidRecipient, err))
+		}
+	}
+
+	if r.File == "" && r.GitlabVar == nil {
+		errs = append(errs, ErrValidationRecoveryMissingBackend)
+	}
+	if r.GitlabVar != nil {
+		errs = append(errs, r.GitlabVar.validate()...)
+	}
+	return errs
+}
+
+// Journal the append-only records of the execution steps, so the outstanding hooks of the interrupted execution are resumable
+type Journal struct {
+	// File the local file of the journal in JSON lines format
+	File      string            `yaml:"file"`
+	GitlabVar *GitlabVarBackend `yaml:"gitlab_var"`
+}
+
+func (j Journal) validate() (errs []error) {
+	if j.File == "" && j.GitlabVar == nil {
+		errs = append(errs, ErrValidationJournalMissingBackend)
+	}
+	if j.GitlabVar != nil {
+		errs = append(errs, j.GitlabVar.validate()...)
+	}
+	return errs
+}
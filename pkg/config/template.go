@@ -0,0 +1,124 @@
+package config
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"text/template"
+	"time"
+)
+
+var (
+	// templateHookTypes the hook types that are writing the token value, so it can be templated
+	templateHookTypes = []string{
+		HookTypeUpdateVar,
+		HookTypeExecCMD,
+		HookTypeK8sSecret,
+		HookTypeVaultKV,
+	}
+	templateFuncs = template.FuncMap{
+		"b64enc": func(input string) string {
+			return base64.StdEncoding.EncodeToString([]byte(input))
+		},
+		"json": func(input any) (string, error) {
+			result, err := json.Marshal(input)
+			return string(result), err
+		},
+		"urlquery": url.QueryEscape,
+	}
+)
+
+// HookTemplateData the data that is available in the hook value template
+type HookTemplateData struct {
+	// Token the new generated token
+	Token     string
+	Name      string
+	Path      string
+	ID        int
+	ExpiresAt time.Time
+	// Host the Gitlab URL as configured, while the Hostname is only the host part of it
+	Host     string
+	Hostname string
+}
+
+// NewHookTemplateData initiate the hook template data, the hostname is parsed from the host
+func NewHookTemplateData(token, name, path string, id int, expiresAt time.Time, host string) HookTemplateData {
+	hostname := host
+	if parsed, err := url.Parse(host); err == nil && parsed.Host != "" {
+		hostname = parsed.Host
+	}
+	return HookTemplateData{
+		Token:     token,
+		Name:      name,
+		Path:      path,
+		ID:        id,
+		ExpiresAt: expiresAt,
+		Host:      strings.TrimSuffix(host, "/"),
+		Hostname:  hostname,
+	}
+}
+
+// argTemplate parse the Go template in the hook argument, nil if it's not set
+func (h Hook) argTemplate(key string) (*template.Template, error) {
+	tmpl := h.getValueOrEmpty(key)
+	if tmpl == "" {
+		return nil, nil
+	}
+	return template.New(h.Type).Funcs(templateFuncs).Option("missingkey=error").Parse(tmpl)
+}
+
+// renderArg render the Go template in the hook argument, ok is false if it's not set
+func (h Hook) renderArg(key string, data HookTemplateData) (rendered string, ok bool, err error) {
+	tmpl, err := h.argTemplate(key)
+	if err != nil || tmpl == nil {
+		return "", false, err
+	}
+
+	var result bytes.Buffer
+	if err := tmpl.Execute(&result, data); err != nil {
+		return "", false, fmt.Errorf("error in rendering %s of %s hook: %w", key, h.Type, err)
+	}
+	return result.String(), true, nil
+}
+
+// RenderValue render the value that is written by hook using it's template argument, the token as is if it's not set
+func (h Hook) RenderValue(data HookTemplateData) (string, error) {
+	rendered, ok, err := h.renderArg("template", data)
+	if err != nil {
+		return "", err
+	} else if !ok {
+		return data.Token, nil
+	}
+	return rendered, nil
+}
+
+// RenderBody render the request body template of http hook, empty if it's not set
+func (h Hook) RenderBody(data HookTemplateData) (string, error) {
+	rendered, _, err := h.renderArg("body", data)
+	return rendered, err
+}
+
+// validateTemplate check the template arguments by rendering them with the sample data
+func (h Hook) validateTemplate() error {
+	sample := NewHookTemplateData("glpat-sample", "sample", "path/to/sample", 1, time.Now(), defaultHost)
+	if h.Args["template"] != nil {
+		if _, isStr := h.Args["template"].(string); !isStr || !contains(templateHookTypes, h.Type) {
+			return ErrValidationHookInvalidTemplate
+		}
+		if _, err := h.RenderValue(sample); err != nil {
+			return fmt.Errorf("%w: %v", ErrValidationHookInvalidTemplate, err)
+		}
+	}
+	if h.Args["body"] != nil && h.Type == HookTypeHTTP {
+		if _, isStr := h.Args["body"].(string); !isStr {
+			return ErrValidationHookHTTPInvalidBody
+		}
+		if _, err := h.RenderBody(sample); err != nil {
+			return fmt.Errorf("%w: %v", ErrValidationHookHTTPInvalidBody, err)
+		}
+	}
+	return nil
+}
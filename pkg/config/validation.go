@@ -0,0 +1,140 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// envEvaluator evaluate env variable pattern in config value
+type envEvaluator func(string) string
+
+// keepEnvVar keep the env variable pattern as is, used in offline validation
+func keepEnvVar(input string) string {
+	return input
+}
+
+// ValidationError an error found during config validation along with it's location
+type ValidationError struct {
+	Err error
+	// File the config file that contains the error, empty if the config is not read from a file
+	File string
+	// Line number in File, 0 means it cannot be determined
+	Line int
+	// References additional context of error location (managed token, access token, hook sequence)
+	References []string
+	// location path of yaml nodes in File
+	location []any
+}
+
+func (v ValidationError) Error() string {
+	err := v.Err
+	for _, errRef := range v.References {
+		err = errors.Join(err, errors.New(errRef))
+	}
+	return err.Error()
+}
+
+func (v ValidationError) Unwrap() error {
+	return v.Err
+}
+
+// ValidationErrors collections of validation error
+type ValidationErrors []ValidationError
+
+func (v ValidationErrors) Error() string {
+	msgs := make([]string, len(v))
+	for idx := range v {
+		msgs[idx] = v[idx].Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+func (v ValidationErrors) Unwrap() []error {
+	errs := make([]error, len(v))
+	for idx := range v {
+		errs[idx] = v[idx]
+	}
+	return errs
+}
+
+// resolveLines fill out the line number of each validation error based on the yaml node location
+func (v ValidationErrors) resolveLines() {
+	docs := make(map[string]*yamlv3.Node)
+	for idx := range v {
+		file := v[idx].File
+		if file == "" || v[idx].location == nil {
+			continue
+		}
+
+		doc, exists := docs[file]
+		if !exists {
+			doc = parseYAMLNode(file)
+			docs[file] = doc
+		}
+		v[idx].Line = nodeLine(doc, v[idx].location)
+	}
+}
+
+// parseYAMLNode read yaml file as node, nil returned if it's failed
+func parseYAMLNode(path string) *yamlv3.Node {
+	content, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil
+	}
+
+	var doc yamlv3.Node
+	if err = yamlv3.Unmarshal(content, &doc); err != nil || len(doc.Content) == 0 {
+		return nil
+	}
+	return doc.Content[0]
+}
+
+// nodeLine find the line of deepest reachable node in the given location
+func nodeLine(node *yamlv3.Node, location []any) int {
+	if node == nil {
+		return 0
+	}
+
+	line := node.Line
+	for _, loc := range location {
+		var next *yamlv3.Node
+		switch key := loc.(type) {
+		case string:
+			if node.Kind != yamlv3.MappingNode {
+				return line
+			}
+			for idx := 0; idx+1 < len(node.Content); idx += 2 {
+				if node.Content[idx].Value == key {
+					line = node.Content[idx].Line
+					next = node.Content[idx+1]
+					break
+				}
+			}
+		case int:
+			if node.Kind == yamlv3.SequenceNode && key < len(node.Content) {
+				next = node.Content[key]
+				line = next.Line
+			}
+		}
+
+		if next == nil {
+			return line
+		}
+		node = next
+	}
+	return line
+}
+
+// appendLocation create a new location by appending the given paths
+func appendLocation(location []any, paths ...any) []any {
+	if location == nil {
+		return nil
+	}
+	newLocation := make([]any, 0, len(location)+len(paths))
+	newLocation = append(newLocation, location...)
+	return append(newLocation, paths...)
+}
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -14,55 +15,83 @@ import (
 func expandConfig(cfg *Config, configPath string) error {
 	configDir := filepath.Dir(configPath)
 	var tmpManagedTokens []ManagedToken
+	var errs ValidationErrors
 	for idx := range cfg.Managed {
 		managed := cfg.Managed[idx]
 		if managed.Ref == "" {
 			managed.Ref = configPath
+			managed.location = []any{"manage_tokens", idx}
 			tmpManagedTokens = append(tmpManagedTokens, managed)
 			continue
 		}
 		log.Debug().Int("sequence", idx).Msg("detected include manage config")
 
 		fpath := filepath.Join(configDir, managed.Ref)
-		if !fileExists(fpath) {
-			return fmt.Errorf("included file %s is not exists", fpath)
-		}
-		includedContent, err := os.ReadFile(filepath.Clean(fpath))
+		manageTokens, err := readIncludeFile(fpath)
 		if err != nil {
-			return fmt.Errorf("error while read include file %s: %v", fpath, err)
-		}
-		var manageTokens []ManagedToken
-		if err = yaml.Unmarshal(includedContent, &manageTokens); err != nil {
-			return fmt.Errorf("error in included file %s as yaml content: %v", fpath, err)
+			errs = append(errs, ValidationError{
+				Err:      err,
+				File:     configPath,
+				location: []any{"manage_tokens", idx, "include"},
+			})
+			continue
 		}
 
-		if len(manageTokens) == 0 {
-			return fmt.Errorf("included file (%s) not contains any of managed token config", fpath)
-		}
 		// loop it in injecting reference
-		for _, mt := range manageTokens {
+		for mtIdx, mt := range manageTokens {
 			mt.Ref = fpath
+			mt.location = []any{mtIdx}
 			tmpManagedTokens = append(tmpManagedTokens, mt)
 		}
 	}
 
 	cfg.Managed = tmpManagedTokens
-	return nil
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
 }
 
-// ReadYAMLConfigFile read configuration in yaml content file
-func ReadYAMLConfigFile(path string) (*Config, error) {
+// readIncludeFile read the list of managed token in the included file
+func readIncludeFile(fpath string) ([]ManagedToken, error) {
+	if !fileExists(fpath) {
+		return nil, fmt.Errorf("included file %s is not exists", fpath)
+	}
+	includedContent, err := os.ReadFile(filepath.Clean(fpath))
+	if err != nil {
+		return nil, fmt.Errorf("error while read include file %s: %v", fpath, err)
+	}
+	var manageTokens []ManagedToken
+	if err = yaml.Unmarshal(includedContent, &manageTokens); err != nil {
+		return nil, fmt.Errorf("error in included file %s as yaml content: %v", fpath, err)
+	}
+
+	if len(manageTokens) == 0 {
+		return nil, fmt.Errorf("included file (%s) not contains any of managed token config", fpath)
+	}
+	return manageTokens, nil
+}
+
+// loadYAMLConfigFile read the yaml content file then expanding the included files
+func loadYAMLConfigFile(path string) (*Config, error) {
 	yamlContent, err := os.ReadFile(filepath.Clean(path))
 	if err != nil {
 		return nil, err
 	}
 
 	cfg := NewConfig()
+	cfg.path = path
 	if err = yaml.Unmarshal(yamlContent, cfg); err != nil {
 		return nil, fmt.Errorf("error in unmarshal YAML object: %w", err)
 	}
 
-	if err = expandConfig(cfg, path); err != nil {
+	return cfg, expandConfig(cfg, path)
+}
+
+// ReadYAMLConfigFile read configuration in yaml content file
+func ReadYAMLConfigFile(path string) (*Config, error) {
+	cfg, err := loadYAMLConfigFile(path)
+	if err != nil {
 		return nil, err
 	}
 
@@ -72,3 +101,25 @@ func ReadYAMLConfigFile(path string) (*Config, error) {
 
 	return cfg, nil
 }
+
+// ValidateYAMLConfigFile validate the configuration file without evaluating the env variables,
+// so it can be done without any secret. All of found errors are returned as ValidationErrors
+func ValidateYAMLConfigFile(path string) error {
+	cfg, err := loadYAMLConfigFile(path)
+	var errs ValidationErrors
+	if err != nil && !errors.As(err, &errs) {
+		return ValidationErrors{{Err: err, File: path}}
+	}
+
+	cfg.offline = true
+	var validationErrs ValidationErrors
+	if errors.As(cfg.InitValues(), &validationErrs) {
+		errs = append(errs, validationErrs...)
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	errs.resolveLines()
+	return errs
+}
@@ -90,3 +90,52 @@ func TestReadYAMLConfigFile(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateYAMLConfigFile(t *testing.T) {
+	t.Run("ok: env variables are not evaluated", func(t *testing.T) {
+		// the token is set from env var GL_RENEWER_TOKEN that is not exists
+		assert.NoError(t, c.ValidateYAMLConfigFile(t_helper.FixturePath("..", "..", "examples", "main_config.yml")))
+	})
+
+	t.Run("err: broken yaml content", func(t *testing.T) {
+		err := c.ValidateYAMLConfigFile(t_helper.FixturePath("configs", "broken_config.yml"))
+		var vErrs c.ValidationErrors
+		assert.ErrorAs(t, err, &vErrs)
+		assert.Len(t, vErrs, 1)
+		assert.Equal(t, t_helper.FixturePath("configs", "broken_config.yml"), vErrs[0].File)
+		assert.ErrorContains(t, vErrs[0], "error in unmarshal YAML object")
+	})
+
+	t.Run("err: all errors are reported with it's location", func(t *testing.T) {
+		mainFile := t_helper.FixturePath("configs", "invalid_multi_errors.yml")
+		includeFile := t_helper.FixturePath("configs", "invalid_multi_errors_include.yml")
+		err := c.ValidateYAMLConfigFile(mainFile)
+		var vErrs c.ValidationErrors
+		assert.ErrorAs(t, err, &vErrs)
+
+		type location struct {
+			File string
+			Line int
+			Err  error
+		}
+		var results []location
+		for _, vErr := range vErrs {
+			results = append(results, location{File: vErr.File, Line: vErr.Line, Err: vErr.Err})
+		}
+
+		assert.Equal(t, []location{
+			{File: mainFile, Line: 16, Err: fmt.Errorf("included file %s is not exists", t_helper.FixturePath("configs", "not_exists.yml"))},
+			{File: mainFile, Line: 3, Err: vErrs[1].Err},
+			{File: mainFile, Line: 8, Err: c.ErrValidationManagedInvalidRenewBefore},
+			{File: mainFile, Line: 11, Err: c.ErrValidationHookUpdateVarMissingName},
+			{File: mainFile, Line: 11, Err: c.ErrValidationHookUpdateVarMissingPath},
+			{File: mainFile, Line: 11, Err: c.ErrValidationHookUpdateVarInvalidType},
+			{File: mainFile, Line: 14, Err: c.ErrValidationHookExecCMDMissingPath},
+			{File: includeFile, Line: 4, Err: c.ErrValidationManagedInvalidRenewBefore},
+			{File: includeFile, Line: 6, Err: c.ErrValidationHookUseTokenNotByPersonalType},
+			{File: includeFile, Line: 7, Err: c.ErrValidationManagedInvalidType},
+			{File: includeFile, Line: 7, Err: c.ErrValidationManagedEmptyTokenList},
+		}, results)
+		assert.ErrorIs(t, vErrs[1], c.ErrValidationInvalidDefaultRenewBefore)
+	})
+}
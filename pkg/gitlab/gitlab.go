@@ -2,9 +2,15 @@
 package gitlab
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
 	"time"
 
+	"github.com/hashicorp/go-retryablehttp"
 	gl "github.com/xanzy/go-gitlab"
+	"golang.org/x/time/rate"
 )
 
 //go:generate mockgen -destination ../../test/mocks/gitlab/gitlab.go -source=gitlab.go
@@ -15,91 +21,280 @@ const (
 	GitlabTargetTypeRepo GitlabTargetType = iota
 	GitlabTargetTypeGroup
 	GitlabTargetTypePersonal
+	GitlabTargetTypeInstance
 )
 
 var (
-	includeRevoked = false
-	fetchPerPage   = 20
+	fetchPerPage = 20
+	// ErrNotFound returned by Gitlab API for the non exists object
+	ErrNotFound = gl.ErrNotFound
+	// ErrInstanceVarForbidden the instance variable API is forbidden for the token without admin privilege
+	ErrInstanceVarForbidden = errors.New("instance variable requires the token of an admin user")
 )
 
 // GitlabCICDVar CICD variable
 type GitlabCICDVar struct {
-	Key   string
-	Value string
-	Type  GitlabTargetType
+	Key              string
+	Value            string
+	Type             GitlabTargetType
+	VariableType     string
+	Protected        bool
+	Masked           bool
+	Raw              bool
+	EnvironmentScope string
+	Description      string
+}
+
+// GitlabCICDVarAttrs the managed attributes of CICD variable, the nil one is left as is in Gitlab
+type GitlabCICDVarAttrs struct {
+	Masked           *bool
+	Protected        *bool
+	Raw              *bool
+	VariableType     *string
+	Description      *string
+	EnvironmentScope *string
+}
+
+// Drift list the differences between the managed attributes and the actual ones in the CICD variable
+func (a GitlabCICDVarAttrs) Drift(v GitlabCICDVar) (drifts []string) {
+	boolAttrs := []struct {
+		name     string
+		expected *bool
+		actual   bool
+	}{
+		{"masked", a.Masked, v.Masked},
+		{"protected", a.Protected, v.Protected},
+		{"raw", a.Raw, v.Raw},
+	}
+	for _, attr := range boolAttrs {
+		if attr.expected != nil && *attr.expected != attr.actual {
+			drifts = append(drifts, fmt.Sprintf("%s: %t -> %t", attr.name, attr.actual, *attr.expected))
+		}
+	}
+
+	strAttrs := []struct {
+		name     string
+		expected *string
+		actual   string
+	}{
+		{"variable_type", a.VariableType, v.VariableType},
+		{"description", a.Description, v.Description},
+		{"environment_scope", a.EnvironmentScope, v.EnvironmentScope},
+	}
+	for _, attr := range strAttrs {
+		if attr.expected != nil && *attr.expected != attr.actual {
+			drifts = append(drifts, fmt.Sprintf("%s: %q -> %q", attr.name, attr.actual, *attr.expected))
+		}
+	}
+	return drifts
+}
+
+// envScopeFilter filter of the CICD variable by environment scope, nil for the empty one
+func envScopeFilter(envScope string) *gl.VariableFilter {
+	if envScope == "" {
+		return nil
+	}
+	return &gl.VariableFilter{EnvironmentScope: envScope}
+}
+
+// withEnvScopeFilter set the environment scope filter as query parameter, for the API options that are not supporting it
+func withEnvScopeFilter(envScope string) gl.RequestOptionFunc {
+	return func(req *retryablehttp.Request) error {
+		if envScope == "" {
+			return nil
+		}
+		query := req.URL.Query()
+		query.Set("filter[environment_scope]", envScope)
+		req.URL.RawQuery = query.Encode()
+		return nil
+	}
+}
+
+// variableType convert the variable type to the Gitlab API one
+func (a GitlabCICDVarAttrs) variableType() *gl.VariableTypeValue {
+	if a.VariableType == nil {
+		return nil
+	}
+	return gl.Ptr(gl.VariableTypeValue(*a.VariableType))
 }
 
 // GitlabAccessToken instance in joining repo and group access token
 type GitlabAccessToken struct {
-	ID        int
-	Name      string
-	Active    bool
-	Revoked   bool
-	ExpiresAt *time.Time
-	Type      GitlabTargetType
-	Path      string
+	ID          int
+	Name        string
+	Active      bool
+	Revoked     bool
+	ExpiresAt   *time.Time
+	Type        GitlabTargetType
+	Path        string
+	Scopes      []string
+	AccessLevel int
 }
 
 // GitlabAPI spec for the used Gitlab API
 type GitlabAPI interface {
 	Auth(token string) error
 	InitGitlab(baseURL, token string) (GitlabAPI, error)
-	GetRepoVar(path string, varName string) (*GitlabCICDVar, error)
-	GetGroupVar(path string, varName string) (*GitlabCICDVar, error)
-	UpdateGroupVar(path string, varName string, value string) error
-	UpdateRepoVar(path string, varName string, value string) error
+	WithContext(ctx context.Context) (GitlabAPI, error)
+	GetRepoVar(path string, varName string, envScope string) (*GitlabCICDVar, error)
+	GetGroupVar(path string, varName string, envScope string) (*GitlabCICDVar, error)
+	UpdateGroupVar(path string, varName string, envScope string, value string, attrs GitlabCICDVarAttrs) error
+	UpdateRepoVar(path string, varName string, envScope string, value string, attrs GitlabCICDVarAttrs) error
+	CreateGroupVar(path string, varName string, value string, attrs GitlabCICDVarAttrs) error
+	CreateRepoVar(path string, varName string, value string, attrs GitlabCICDVarAttrs) error
+	GetInstanceVar(varName string) (*GitlabCICDVar, error)
+	UpdateInstanceVar(varName string, value string, attrs GitlabCICDVarAttrs) error
+	CreateInstanceVar(varName string, value string, attrs GitlabCICDVarAttrs) error
 	RotatePersonalToken(tokenID int, expiredAt time.Time) (string, error)
 	RotateRepoToken(path string, tokenID int, expiredAt time.Time) (string, error)
 	RotateGroupToken(path string, tokenID int, expiredAt time.Time) (string, error)
+	CreatePersonalToken(name string, scopes []string, expiredAt time.Time) (string, error)
+	CreateRepoToken(path string, name string, scopes []string, accessLevel int, expiredAt time.Time) (string, error)
+	CreateGroupToken(path string, name string, scopes []string, accessLevel int, expiredAt time.Time) (string, error)
+	RevokePersonalToken(tokenID int) error
+	RevokeRepoToken(path string, tokenID int) error
+	RevokeGroupToken(path string, tokenID int) error
 	ListPersonalAccessToken() ([]GitlabAccessToken, error)
 	ListRepoAccessToken(path string) ([]GitlabAccessToken, error)
 	ListGroupAccessToken(path string) ([]GitlabAccessToken, error)
+	ListGroupProjects(path string, includeSubGroups bool) ([]string, error)
+	ListSubGroups(path string, recursive bool) ([]string, error)
+	ListRepoVars(path string) ([]GitlabCICDVar, error)
+	ListGroupVars(path string) ([]GitlabCICDVar, error)
 }
 
 // Gitlab implement GitlabAPI interface
 type Gitlab struct {
 	baseURL string
+	token   string
 	client  *gl.Client
+	// ctx the context of all of the API calls, they are cancelled by it
+	ctx       context.Context
+	policy    ClientPolicy
+	instances map[string]ClientPolicy
+	limiters  *limiters
 }
 
 // Auth initiate gitlab API client
 func (g *Gitlab) Auth(token string) error {
-	client, err := gl.NewClient(token, gl.WithBaseURL(g.baseURL))
+	client, err := gl.NewClient(token, g.clientOptions()...)
 	if err != nil {
 		return err
 	}
+	g.token = token
 	g.client = client
 	return nil
 }
 
-// GetRepoVar get repo/project CICD var
-func (g Gitlab) GetRepoVar(path string, varName string) (*GitlabCICDVar, error) {
-	cicdVar, _, err := g.client.ProjectVariables.GetVariable(path, varName, nil)
+// WithContext the copy of Gitlab API that all of it's calls are cancelled by the context
+func (g *Gitlab) WithContext(ctx context.Context) (GitlabAPI, error) {
+	bound := *g
+	bound.ctx = ctx
+	if err := bound.Auth(g.token); err != nil {
+		return nil, err
+	}
+	return &bound, nil
+}
+
+// GetRepoVar get repo/project CICD var, filtered by the environment scope if it's set
+func (g Gitlab) GetRepoVar(path string, varName string, envScope string) (*GitlabCICDVar, error) {
+	cicdVar, _, err := g.client.ProjectVariables.GetVariable(path, varName, &gl.GetProjectVariableOptions{
+		Filter: envScopeFilter(envScope),
+	})
 	if err != nil {
 		return nil, err
 	}
 
 	return &GitlabCICDVar{
-		Key:   cicdVar.Key,
-		Value: cicdVar.Value,
-		Type:  GitlabTargetTypeRepo,
+		Key:              cicdVar.Key,
+		Value:            cicdVar.Value,
+		Type:             GitlabTargetTypeRepo,
+		VariableType:     string(cicdVar.VariableType),
+		Protected:        cicdVar.Protected,
+		Masked:           cicdVar.Masked,
+		Raw:              cicdVar.Raw,
+		EnvironmentScope: cicdVar.EnvironmentScope,
+		Description:      cicdVar.Description,
 	}, nil
 }
 
-// GetGroupVar get group CICD var
-func (g Gitlab) GetGroupVar(path string, varName string) (*GitlabCICDVar, error) {
-	cicdVar, _, err := g.client.GroupVariables.GetVariable(path, varName, nil)
+// GetGroupVar get group CICD var, filtered by the environment scope if it's set
+func (g Gitlab) GetGroupVar(path string, varName string, envScope string) (*GitlabCICDVar, error) {
+	cicdVar, _, err := g.client.GroupVariables.GetVariable(path, varName, &gl.GetGroupVariableOptions{
+		Filter: envScopeFilter(envScope),
+	})
 	if err != nil {
 		return nil, err
 	}
 
 	return &GitlabCICDVar{
-		Key:   cicdVar.Key,
-		Value: cicdVar.Value,
-		Type:  GitlabTargetTypeGroup,
+		Key:              cicdVar.Key,
+		Value:            cicdVar.Value,
+		Type:             GitlabTargetTypeGroup,
+		VariableType:     string(cicdVar.VariableType),
+		Protected:        cicdVar.Protected,
+		Masked:           cicdVar.Masked,
+		Raw:              cicdVar.Raw,
+		EnvironmentScope: cicdVar.EnvironmentScope,
+		Description:      cicdVar.Description,
+	}, nil
+}
+
+// instanceVarErr clarify the forbidden error in instance variable API
+func instanceVarErr(err error) error {
+	var errResp *gl.ErrorResponse
+	if errors.As(err, &errResp) && errResp.Response != nil && errResp.Response.StatusCode == http.StatusForbidden {
+		return fmt.Errorf("%w: %v", ErrInstanceVarForbidden, err)
+	}
+	return err
+}
+
+// GetInstanceVar get instance CICD var
+func (g Gitlab) GetInstanceVar(varName string) (*GitlabCICDVar, error) {
+	cicdVar, _, err := g.client.InstanceVariables.GetVariable(varName)
+	if err != nil {
+		return nil, instanceVarErr(err)
+	}
+
+	return &GitlabCICDVar{
+		Key:          cicdVar.Key,
+		Value:        cicdVar.Value,
+		Type:         GitlabTargetTypeInstance,
+		VariableType: string(cicdVar.VariableType),
+		Protected:    cicdVar.Protected,
+		Masked:       cicdVar.Masked,
+		Raw:          cicdVar.Raw,
+		Description:  cicdVar.Description,
 	}, nil
 }
 
+// UpdateInstanceVar update instance CICD variable, also enforcing the managed attributes
+func (g Gitlab) UpdateInstanceVar(varName string, value string, attrs GitlabCICDVarAttrs) error {
+	_, _, err := g.client.InstanceVariables.UpdateVariable(varName, &gl.UpdateInstanceVariableOptions{
+		Value:        &value,
+		Masked:       attrs.Masked,
+		Protected:    attrs.Protected,
+		Raw:          attrs.Raw,
+		VariableType: attrs.variableType(),
+		Description:  attrs.Description,
+	})
+	return instanceVarErr(err)
+}
+
+// CreateInstanceVar create instance CICD variable with the managed attributes
+func (g Gitlab) CreateInstanceVar(varName string, value string, attrs GitlabCICDVarAttrs) error {
+	_, _, err := g.client.InstanceVariables.CreateVariable(&gl.CreateInstanceVariableOptions{
+		Key:          &varName,
+		Value:        &value,
+		Masked:       attrs.Masked,
+		Protected:    attrs.Protected,
+		Raw:          attrs.Raw,
+		VariableType: attrs.variableType(),
+		Description:  attrs.Description,
+	})
+	return instanceVarErr(err)
+}
+
 // RotatePersonalToken rotate/renew personal access token
 func (g Gitlab) RotatePersonalToken(tokenID int, expiredAt time.Time) (string, error) {
 	convTime := gl.ISOTime(expiredAt)
@@ -140,23 +335,144 @@ func (g Gitlab) RotateGroupToken(path string, tokenID int, expiredAt time.Time)
 	return newToken.Token, nil
 }
 
-// UpdateGroupVar update CICD variable in a group
-func (g Gitlab) UpdateGroupVar(path string, varName string, value string) error {
-	_, _, err := g.client.GroupVariables.UpdateVariable(path, varName, &gl.UpdateGroupVariableOptions{
-		Value: &value,
+// CreatePersonalToken create personal access token for the current user, it requires admin privilege
+func (g Gitlab) CreatePersonalToken(name string, scopes []string, expiredAt time.Time) (string, error) {
+	user, _, err := g.client.Users.CurrentUser()
+	if err != nil {
+		return "", err
+	}
+
+	convTime := gl.ISOTime(expiredAt)
+	newToken, _, err := g.client.Users.CreatePersonalAccessToken(user.ID, &gl.CreatePersonalAccessTokenOptions{
+		Name:      &name,
+		Scopes:    &scopes,
+		ExpiresAt: &convTime,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return newToken.Token, nil
+}
+
+// accessLevelOpt access level option in creating access token, it's omitted (using Gitlab default) if not set
+func accessLevelOpt(accessLevel int) *gl.AccessLevelValue {
+	if accessLevel == 0 {
+		return nil
+	}
+	return gl.Ptr(gl.AccessLevelValue(accessLevel))
+}
+
+// CreateRepoToken create project access token
+func (g Gitlab) CreateRepoToken(path string, name string, scopes []string, accessLevel int, expiredAt time.Time) (string, error) {
+	convTime := gl.ISOTime(expiredAt)
+	newToken, _, err := g.client.ProjectAccessTokens.CreateProjectAccessToken(path, &gl.CreateProjectAccessTokenOptions{
+		Name:        &name,
+		Scopes:      &scopes,
+		AccessLevel: accessLevelOpt(accessLevel),
+		ExpiresAt:   &convTime,
 	})
+	if err != nil {
+		return "", err
+	}
+
+	return newToken.Token, nil
+}
+
+// CreateGroupToken create group access token
+func (g Gitlab) CreateGroupToken(path string, name string, scopes []string, accessLevel int, expiredAt time.Time) (string, error) {
+	convTime := gl.ISOTime(expiredAt)
+	newToken, _, err := g.client.GroupAccessTokens.CreateGroupAccessToken(path, &gl.CreateGroupAccessTokenOptions{
+		Name:        &name,
+		Scopes:      &scopes,
+		AccessLevel: accessLevelOpt(accessLevel),
+		ExpiresAt:   &convTime,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return newToken.Token, nil
+}
+
+// RevokePersonalToken revoke personal access token
+func (g Gitlab) RevokePersonalToken(tokenID int) error {
+	_, err := g.client.PersonalAccessTokens.RevokePersonalAccessTokenByID(tokenID)
+	return err
+}
+
+// RevokeRepoToken revoke project access token
+func (g Gitlab) RevokeRepoToken(path string, tokenID int) error {
+	_, err := g.client.ProjectAccessTokens.RevokeProjectAccessToken(path, tokenID)
+	return err
+}
+
+// RevokeGroupToken revoke group access token
+func (g Gitlab) RevokeGroupToken(path string, tokenID int) error {
+	_, err := g.client.GroupAccessTokens.RevokeGroupAccessToken(path, tokenID)
+	return err
+}
+
+// UpdateGroupVar update CICD variable in a group filtered by the environment scope if it's set, also enforcing the managed attributes
+func (g Gitlab) UpdateGroupVar(path string, varName string, envScope string, value string, attrs GitlabCICDVarAttrs) error {
+	_, _, err := g.client.GroupVariables.UpdateVariable(path, varName, &gl.UpdateGroupVariableOptions{
+		Value:            &value,
+		Masked:           attrs.Masked,
+		Protected:        attrs.Protected,
+		Raw:              attrs.Raw,
+		VariableType:     attrs.variableType(),
+		Description:      attrs.Description,
+		EnvironmentScope: attrs.EnvironmentScope,
+	}, withEnvScopeFilter(envScope))
 	return err
 }
 
-// UpdateRepoVar update CICD variable in a repo/project
-func (g Gitlab) UpdateRepoVar(path string, varName string, value string) error {
+// UpdateRepoVar update CICD variable in a repo/project filtered by the environment scope if it's set, also enforcing the managed attributes
+func (g Gitlab) UpdateRepoVar(path string, varName string, envScope string, value string, attrs GitlabCICDVarAttrs) error {
 	_, _, err := g.client.ProjectVariables.UpdateVariable(path, varName, &gl.UpdateProjectVariableOptions{
-		Value: &value,
+		Value:            &value,
+		Filter:           envScopeFilter(envScope),
+		Masked:           attrs.Masked,
+		Protected:        attrs.Protected,
+		Raw:              attrs.Raw,
+		VariableType:     attrs.variableType(),
+		Description:      attrs.Description,
+		EnvironmentScope: attrs.EnvironmentScope,
+	})
+	return err
+}
+
+// CreateGroupVar create CICD variable in a group with the managed attributes
+func (g Gitlab) CreateGroupVar(path string, varName string, value string, attrs GitlabCICDVarAttrs) error {
+	_, _, err := g.client.GroupVariables.CreateVariable(path, &gl.CreateGroupVariableOptions{
+		Key:              &varName,
+		Value:            &value,
+		Masked:           attrs.Masked,
+		Protected:        attrs.Protected,
+		Raw:              attrs.Raw,
+		VariableType:     attrs.variableType(),
+		Description:      attrs.Description,
+		EnvironmentScope: attrs.EnvironmentScope,
 	})
 	return err
 }
 
-// ListRepoAccessToken get list of repo/project access token
+// CreateRepoVar create CICD variable in a repo/project with the managed attributes
+func (g Gitlab) CreateRepoVar(path string, varName string, value string, attrs GitlabCICDVarAttrs) error {
+	_, _, err := g.client.ProjectVariables.CreateVariable(path, &gl.CreateProjectVariableOptions{
+		Key:              &varName,
+		Value:            &value,
+		Masked:           attrs.Masked,
+		Protected:        attrs.Protected,
+		Raw:              attrs.Raw,
+		VariableType:     attrs.variableType(),
+		Description:      attrs.Description,
+		EnvironmentScope: attrs.EnvironmentScope,
+	})
+	return err
+}
+
+// ListRepoAccessToken get list of repo/project access token, the revoked ones are included for recreating them
 func (g Gitlab) ListRepoAccessToken(path string) (gat []GitlabAccessToken, err error) {
 	listOptions := &gl.ListProjectAccessTokensOptions{
 		Page:    1,
@@ -170,18 +486,16 @@ func (g Gitlab) ListRepoAccessToken(path string) (gat []GitlabAccessToken, err e
 		}
 		for idx := range tokens {
 			tk := tokens[idx]
-			// ignore the revoked one
-			if tk.Revoked && !includeRevoked {
-				continue
-			}
 			gat = append(gat, GitlabAccessToken{
-				ID:        tk.ID,
-				Name:      tk.Name,
-				Active:    tk.Active,
-				Revoked:   tk.Revoked,
-				ExpiresAt: (*time.Time)(tk.ExpiresAt),
-				Type:      GitlabTargetTypeRepo,
-				Path:      path,
+				ID:          tk.ID,
+				Name:        tk.Name,
+				Active:      tk.Active,
+				Revoked:     tk.Revoked,
+				ExpiresAt:   (*time.Time)(tk.ExpiresAt),
+				Type:        GitlabTargetTypeRepo,
+				Path:        path,
+				Scopes:      tk.Scopes,
+				AccessLevel: int(tk.AccessLevel),
 			})
 		}
 
@@ -195,7 +509,7 @@ func (g Gitlab) ListRepoAccessToken(path string) (gat []GitlabAccessToken, err e
 	return gat, nil
 }
 
-// ListGroupAccessToken get list of group access token
+// ListGroupAccessToken get list of group access token, the revoked ones are included for recreating them
 func (g Gitlab) ListGroupAccessToken(path string) (gat []GitlabAccessToken, err error) {
 	listOptions := &gl.ListGroupAccessTokensOptions{
 		Page:    1,
@@ -210,18 +524,16 @@ func (g Gitlab) ListGroupAccessToken(path string) (gat []GitlabAccessToken, err
 
 		for idx := range tokens {
 			tk := tokens[idx]
-			// ignore the revoked one
-			if tk.Revoked && !includeRevoked {
-				continue
-			}
 			gat = append(gat, GitlabAccessToken{
-				ID:        tk.ID,
-				Name:      tk.Name,
-				Active:    tk.Active,
-				Revoked:   tk.Revoked,
-				ExpiresAt: (*time.Time)(tk.ExpiresAt),
-				Type:      GitlabTargetTypeGroup,
-				Path:      path,
+				ID:          tk.ID,
+				Name:        tk.Name,
+				Active:      tk.Active,
+				Revoked:     tk.Revoked,
+				ExpiresAt:   (*time.Time)(tk.ExpiresAt),
+				Type:        GitlabTargetTypeGroup,
+				Path:        path,
+				Scopes:      tk.Scopes,
+				AccessLevel: int(tk.AccessLevel),
 			})
 		}
 
@@ -235,14 +547,13 @@ func (g Gitlab) ListGroupAccessToken(path string) (gat []GitlabAccessToken, err
 	return gat, nil
 }
 
-// ListPersonalAccessToken get list of group access token
+// ListPersonalAccessToken get list of personal access token, the revoked ones are included for recreating them
 func (g Gitlab) ListPersonalAccessToken() (pat []GitlabAccessToken, err error) {
 	listOptions := &gl.ListPersonalAccessTokensOptions{
 		ListOptions: gl.ListOptions{
 			Page:    1,
 			PerPage: fetchPerPage,
 		},
-		Revoked: gl.Ptr(includeRevoked),
 	}
 
 	for {
@@ -254,10 +565,6 @@ func (g Gitlab) ListPersonalAccessToken() (pat []GitlabAccessToken, err error) {
 
 		for idx := range tokens {
 			tk := tokens[idx]
-			// ignore the revoked one
-			if tk.Revoked && !includeRevoked {
-				continue
-			}
 			pat = append(pat, GitlabAccessToken{
 				ID:        tk.ID,
 				Name:      tk.Name,
@@ -266,6 +573,7 @@ func (g Gitlab) ListPersonalAccessToken() (pat []GitlabAccessToken, err error) {
 				Path:      "@personal",
 				ExpiresAt: (*time.Time)(tk.ExpiresAt),
 				Type:      GitlabTargetTypePersonal,
+				Scopes:    tk.Scopes,
 			})
 		}
 
@@ -279,14 +587,167 @@ func (g Gitlab) ListPersonalAccessToken() (pat []GitlabAccessToken, err error) {
 	return pat, nil
 }
 
-// InitGitlab initiating external/another Gitlab instance
+// ListGroupProjects get list of project path in a group, including the ones in it's subgroups if includeSubGroups is set
+func (g Gitlab) ListGroupProjects(path string, includeSubGroups bool) (projects []string, err error) {
+	listOptions := &gl.ListGroupProjectsOptions{
+		ListOptions: gl.ListOptions{
+			Page:    1,
+			PerPage: fetchPerPage,
+		},
+		IncludeSubGroups: gl.Ptr(includeSubGroups),
+		WithShared:       gl.Ptr(false),
+	}
+
+	for {
+		results, resp, err := g.client.Groups.ListGroupProjects(path, listOptions)
+		if err != nil {
+			return nil, err
+		}
+
+		for idx := range results {
+			projects = append(projects, results[idx].PathWithNamespace)
+		}
+
+		// Check if there are more pages to fetch
+		if resp.NextPage == 0 {
+			break
+		}
+		listOptions.Page = resp.NextPage
+	}
+
+	return projects, nil
+}
+
+// ListSubGroups get list of subgroup path in a group, all of descendant groups are returned if recursive is set
+func (g Gitlab) ListSubGroups(path string, recursive bool) (groups []string, err error) {
+	listOptions := gl.ListGroupsOptions{
+		ListOptions: gl.ListOptions{
+			Page:    1,
+			PerPage: fetchPerPage,
+		},
+	}
+
+	for {
+		var results []*gl.Group
+		var resp *gl.Response
+		if recursive {
+			opts := gl.ListDescendantGroupsOptions(listOptions)
+			results, resp, err = g.client.Groups.ListDescendantGroups(path, &opts)
+		} else {
+			opts := gl.ListSubGroupsOptions(listOptions)
+			results, resp, err = g.client.Groups.ListSubGroups(path, &opts)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		for idx := range results {
+			groups = append(groups, results[idx].FullPath)
+		}
+
+		// Check if there are more pages to fetch
+		if resp.NextPage == 0 {
+			break
+		}
+		listOptions.Page = resp.NextPage
+	}
+
+	return groups, nil
+}
+
+// ListRepoVars get list of CICD variable in a repo/project
+func (g Gitlab) ListRepoVars(path string) (vars []GitlabCICDVar, err error) {
+	listOptions := &gl.ListProjectVariablesOptions{
+		Page:    1,
+		PerPage: fetchPerPage,
+	}
+
+	for {
+		results, resp, err := g.client.ProjectVariables.ListVariables(path, listOptions)
+		if err != nil {
+			return nil, err
+		}
+
+		for idx := range results {
+			vars = append(vars, GitlabCICDVar{
+				Key:              results[idx].Key,
+				Value:            results[idx].Value,
+				Type:             GitlabTargetTypeRepo,
+				VariableType:     string(results[idx].VariableType),
+				Protected:        results[idx].Protected,
+				Masked:           results[idx].Masked,
+				Raw:              results[idx].Raw,
+				EnvironmentScope: results[idx].EnvironmentScope,
+				Description:      results[idx].Description,
+			})
+		}
+
+		// Check if there are more pages to fetch
+		if resp.NextPage == 0 {
+			break
+		}
+		listOptions.Page = resp.NextPage
+	}
+
+	return vars, nil
+}
+
+// ListGroupVars get list of CICD variable in a group
+func (g Gitlab) ListGroupVars(path string) (vars []GitlabCICDVar, err error) {
+	listOptions := &gl.ListGroupVariablesOptions{
+		Page:    1,
+		PerPage: fetchPerPage,
+	}
+
+	for {
+		results, resp, err := g.client.GroupVariables.ListVariables(path, listOptions)
+		if err != nil {
+			return nil, err
+		}
+
+		for idx := range results {
+			vars = append(vars, GitlabCICDVar{
+				Key:              results[idx].Key,
+				Value:            results[idx].Value,
+				Type:             GitlabTargetTypeGroup,
+				VariableType:     string(results[idx].VariableType),
+				Protected:        results[idx].Protected,
+				Masked:           results[idx].Masked,
+				Raw:              results[idx].Raw,
+				EnvironmentScope: results[idx].EnvironmentScope,
+				Description:      results[idx].Description,
+			})
+		}
+
+		// Check if there are more pages to fetch
+		if resp.NextPage == 0 {
+			break
+		}
+		listOptions.Page = resp.NextPage
+	}
+
+	return vars, nil
+}
+
+// InitGitlab initiating external/another Gitlab instance, it's using the policy of the instance if it's set
 func (g *Gitlab) InitGitlab(baseURL, token string) (GitlabAPI, error) {
-	return NewGitlabAPI(baseURL, token)
+	policy, exists := g.instances[instanceKey(baseURL)]
+	if !exists {
+		policy = g.policy
+	}
+	external := &Gitlab{baseURL: baseURL, policy: policy, instances: g.instances, limiters: g.limiters}
+	if err := external.Auth(token); err != nil {
+		return nil, err
+	}
+	return external, nil
 }
 
-// NewGitlabAPI returning gitlab API object
-func NewGitlabAPI(baseURL, token string) (GitlabAPI, error) {
-	gl := &Gitlab{baseURL: baseURL}
+// NewGitlabAPI returning gitlab API object, the default client policy is used if it's not set
+func NewGitlabAPI(baseURL, token string, opts ...Option) (GitlabAPI, error) {
+	gl := &Gitlab{baseURL: baseURL, policy: DefaultClientPolicy(), limiters: &limiters{items: map[string]*rate.Limiter{}}}
+	for _, opt := range opts {
+		opt(gl)
+	}
 	if err := gl.Auth(token); err != nil {
 		return nil, err
 	}
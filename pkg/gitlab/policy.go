@@ -0,0 +1,157 @@
+package gitlab
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-cleanhttp"
+	"github.com/hashicorp/go-retryablehttp"
+	gl "github.com/xanzy/go-gitlab"
+	"golang.org/x/time/rate"
+)
+
+const (
+	DefaultRetry         = 5
+	DefaultRetryWaitMin  = 100 * time.Millisecond
+	DefaultRetryWaitMax  = 30 * time.Second
+	headerRateLimitReset = "RateLimit-Reset"
+)
+
+// ClientPolicy the rate limit, retry and timeout of the API calls
+type ClientPolicy struct {
+	// RateLimit the maximum requests per second, the rate limit headers of Gitlab are followed if it's not set
+	RateLimit float64
+	// Retry the maximum retries of the rate limited (429), server error (5xx) and connection error, the other errors are never retried.
+	// The non idempotent request (e.g. rotating or creating token) is only retried for the rate limited and the failure of connecting to the server
+	Retry int
+	// RetryWaitMin and RetryWaitMax bound the exponential backoff, the wait of Retry-After or RateLimit-Reset header is preferred
+	RetryWaitMin time.Duration
+	RetryWaitMax time.Duration
+	// Timeout of each of the API call attempts, no timeout if it's not set
+	Timeout time.Duration
+}
+
+// DefaultClientPolicy the policy if it's not set
+func DefaultClientPolicy() ClientPolicy {
+	return ClientPolicy{Retry: DefaultRetry, RetryWaitMin: DefaultRetryWaitMin, RetryWaitMax: DefaultRetryWaitMax}
+}
+
+// Option the option of Gitlab API
+type Option func(g *Gitlab)
+
+// WithClientPolicy set the policy of the API calls
+func WithClientPolicy(policy ClientPolicy) Option {
+	return func(g *Gitlab) {
+		g.policy = policy
+	}
+}
+
+// WithInstancePolicies set the policy of the external Gitlab instances by their URL, the one of the main instance is used for the unlisted ones
+func WithInstancePolicies(policies map[string]ClientPolicy) Option {
+	return func(g *Gitlab) {
+		g.instances = map[string]ClientPolicy{}
+		for baseURL, policy := range policies {
+			g.instances[instanceKey(baseURL)] = policy
+		}
+	}
+}
+
+// instanceKey the URL of the instance regardless of it's trailing slash
+func instanceKey(baseURL string) string {
+	return strings.TrimSuffix(baseURL, "/")
+}
+
+// limiters the rate limiters of the instances, so the rate limit is shared by all of the clients of the same instance
+type limiters struct {
+	mu    sync.Mutex
+	items map[string]*rate.Limiter
+}
+
+func (l *limiters) get(baseURL string, limit float64) *rate.Limiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	key := instanceKey(baseURL)
+	if l.items[key] == nil {
+		l.items[key] = rate.NewLimiter(rate.Limit(limit), 1)
+	}
+	return l.items[key]
+}
+
+// methodKey the context key of the request method, as it's not known by the retry policy for the connection error
+type methodKey struct{}
+
+// idempotentMethods the request methods that are safe to be replayed, the repeated DELETE is not as it's responded by not found
+var idempotentMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodOptions: true,
+	http.MethodPut:     true,
+}
+
+// withMethod keep the request method in the request context
+func withMethod(req *retryablehttp.Request) error {
+	*req = *req.WithContext(context.WithValue(req.Context(), methodKey{}, req.Method))
+	return nil
+}
+
+// retryPolicy retry the rate limited (429), server error (5xx) and connection error of the idempotent request, the others (e.g. 401, 403, 404) are failed fast.
+// The non idempotent one (e.g. rotating or creating token) might be processed without receiving it's response, so it's only retried as it's surely not processed:
+// rate limited or failed in connecting to the server
+func retryPolicy(ctx context.Context, resp *http.Response, err error) (bool, error) {
+	if method, _ := ctx.Value(methodKey{}).(string); idempotentMethods[method] {
+		return retryablehttp.DefaultRetryPolicy(ctx, resp, err)
+	}
+	if ctx.Err() != nil {
+		return false, ctx.Err()
+	}
+
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return resp.StatusCode == http.StatusTooManyRequests, nil
+}
+
+// backoff the exponential backoff, the wait of Retry-After or RateLimit-Reset header is preferred for the rate limited one
+func backoff(waitMin, waitMax time.Duration, attemptNum int, resp *http.Response) time.Duration {
+	if resp != nil && resp.StatusCode == http.StatusTooManyRequests && resp.Header.Get("Retry-After") == "" {
+		if reset, _ := strconv.ParseInt(resp.Header.Get(headerRateLimitReset), 10, 64); reset > 0 {
+			// bounded as the far future reset (e.g. the bad header) is stalling the execution
+			return max(waitMin, min(time.Until(time.Unix(reset, 0)), waitMax))
+		}
+	}
+	return retryablehttp.DefaultBackoff(waitMin, waitMax, attemptNum, resp)
+}
+
+// clientOptions the client options of the policy
+func (g *Gitlab) clientOptions() []gl.ClientOptionFunc {
+	policy := g.policy
+	httpClient := cleanhttp.DefaultPooledClient()
+	httpClient.Timeout = policy.Timeout
+	options := []gl.ClientOptionFunc{
+		gl.WithBaseURL(g.baseURL),
+		gl.WithHTTPClient(httpClient),
+		gl.WithCustomRetry(retryPolicy),
+		gl.WithRequestOptions(withMethod),
+		gl.WithCustomBackoff(backoff),
+		gl.WithCustomRetryMax(policy.Retry),
+		gl.WithCustomRetryWaitMinMax(policy.RetryWaitMin, policy.RetryWaitMax),
+	}
+	if g.ctx != nil {
+		// it's set before the other request options, so the request method is kept in it's derived context
+		options = append([]gl.ClientOptionFunc{gl.WithRequestOptions(gl.WithContext(g.ctx))}, options...)
+	}
+	if policy.RateLimit > 0 {
+		options = append(options, gl.WithCustomLimiter(g.limiters.get(g.baseURL, policy.RateLimit)))
+	}
+	return options
+}
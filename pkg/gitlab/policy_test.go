@@ -0,0 +1,194 @@
+package gitlab_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	gl "github.com/iomarmochtar/gitlab-token-updater/pkg/gitlab"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGitlab_ClientPolicy(t *testing.T) {
+	fastRetry := gl.ClientPolicy{Retry: 2, RetryWaitMin: time.Millisecond, RetryWaitMax: 5 * time.Millisecond}
+
+	testCases := map[string]struct {
+		policy           gl.ClientPolicy
+		instancePolicy   *gl.ClientPolicy
+		rotate           bool
+		responses        func(hit int32, w http.ResponseWriter)
+		expectedHits     int32
+		expectedErr      bool
+		expectedMinWaits time.Duration
+		expectedMaxWaits time.Duration
+	}{
+		"ok: server error is retried": {
+			policy: fastRetry,
+			responses: func(hit int32, w http.ResponseWriter) {
+				if hit < 3 {
+					w.WriteHeader(http.StatusBadGateway)
+					return
+				}
+				_, _ = w.Write([]byte("[]"))
+			},
+			expectedHits: 3,
+		},
+		"ok: rate limited is retried after the reset time": {
+			policy: gl.ClientPolicy{Retry: 2, RetryWaitMin: time.Millisecond, RetryWaitMax: 5 * time.Second},
+			responses: func(hit int32, w http.ResponseWriter) {
+				if hit == 1 {
+					w.Header().Set("RateLimit-Reset", strconv.FormatInt(time.Now().Add(2*time.Second).Unix(), 10))
+					w.WriteHeader(http.StatusTooManyRequests)
+					return
+				}
+				_, _ = w.Write([]byte("[]"))
+			},
+			expectedHits:     2,
+			expectedMinWaits: 500 * time.Millisecond,
+		},
+		"ok: wait of the far future reset time is bounded": {
+			policy: fastRetry,
+			responses: func(hit int32, w http.ResponseWriter) {
+				if hit == 1 {
+					w.Header().Set("RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+					w.WriteHeader(http.StatusTooManyRequests)
+					return
+				}
+				_, _ = w.Write([]byte("[]"))
+			},
+			expectedHits:     2,
+			expectedMaxWaits: time.Second,
+		},
+		"err: forbidden is failed fast": {
+			policy: fastRetry,
+			responses: func(_ int32, w http.ResponseWriter) {
+				w.WriteHeader(http.StatusForbidden)
+			},
+			expectedHits: 1,
+			expectedErr:  true,
+		},
+		"err: retries are exhausted": {
+			policy: fastRetry,
+			responses: func(_ int32, w http.ResponseWriter) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			},
+			expectedHits: 3,
+			expectedErr:  true,
+		},
+		"err: call is timed out": {
+			policy: gl.ClientPolicy{Timeout: 20 * time.Millisecond},
+			responses: func(_ int32, w http.ResponseWriter) {
+				time.Sleep(200 * time.Millisecond)
+				_, _ = w.Write([]byte("[]"))
+			},
+			expectedHits: 1,
+			expectedErr:  true,
+		},
+		"ok: rate limited rotation is retried": {
+			policy: fastRetry,
+			rotate: true,
+			responses: func(hit int32, w http.ResponseWriter) {
+				if hit == 1 {
+					w.WriteHeader(http.StatusTooManyRequests)
+					return
+				}
+				_, _ = w.Write([]byte(`{"token":"glpat-new"}`))
+			},
+			expectedHits: 2,
+		},
+		"err: server error of rotation is not retried": {
+			policy: fastRetry,
+			rotate: true,
+			responses: func(_ int32, w http.ResponseWriter) {
+				w.WriteHeader(http.StatusBadGateway)
+			},
+			expectedHits: 1,
+			expectedErr:  true,
+		},
+		"err: timed out rotation is not replayed": {
+			policy: gl.ClientPolicy{Retry: 2, RetryWaitMin: time.Millisecond, RetryWaitMax: 5 * time.Millisecond, Timeout: 20 * time.Millisecond},
+			rotate: true,
+			responses: func(_ int32, w http.ResponseWriter) {
+				time.Sleep(200 * time.Millisecond)
+				_, _ = w.Write([]byte(`{"token":"glpat-new"}`))
+			},
+			expectedHits: 1,
+			expectedErr:  true,
+		},
+		"err: policy of the external instance is used": {
+			policy:         fastRetry,
+			instancePolicy: &gl.ClientPolicy{},
+			responses: func(_ int32, w http.ResponseWriter) {
+				w.WriteHeader(http.StatusBadGateway)
+			},
+			expectedHits: 1,
+			expectedErr:  true,
+		},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			var hits atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				tc.responses(hits.Add(1), w)
+			}))
+			defer server.Close()
+
+			opts := []gl.Option{gl.WithClientPolicy(tc.policy)}
+			if tc.instancePolicy != nil {
+				opts = append(opts, gl.WithInstancePolicies(map[string]gl.ClientPolicy{server.URL + "/": *tc.instancePolicy}))
+			}
+			glAPI, err := gl.NewGitlabAPI("https://gitlab.com/", "glpat-abc", opts...)
+			require.NoError(t, err)
+			glAPI, err = glAPI.InitGitlab(server.URL, "glpat-abc")
+			require.NoError(t, err)
+
+			start := time.Now()
+			if tc.rotate {
+				_, err = glAPI.RotateRepoToken("path/to/repo", 123, time.Now().AddDate(0, 1, 0))
+			} else {
+				_, err = glAPI.ListRepoAccessToken("path/to/repo")
+			}
+			if tc.expectedErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.expectedHits, hits.Load())
+			assert.GreaterOrEqual(t, time.Since(start), tc.expectedMinWaits)
+			if tc.expectedMaxWaits > 0 {
+				assert.Less(t, time.Since(start), tc.expectedMaxWaits)
+			}
+		})
+	}
+}
+
+func TestGitlab_WithContext(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	glAPI, err := gl.NewGitlabAPI(server.URL, "glpat-abc")
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	bound, err := glAPI.WithContext(ctx)
+	require.NoError(t, err)
+
+	// the cancelled one is never sent nor retried, while the original one is not affected
+	_, err = bound.ListRepoAccessToken("path/to/repo")
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, int32(0), hits.Load())
+	_, err = glAPI.ListRepoAccessToken("path/to/repo")
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,314 @@
+// Package journal the append-only records of the execution steps, it's used for resuming the outstanding hooks of the interrupted execution
+package journal
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	StepRunStarted    = "run_started"
+	StepListed        = "listed"
+	StepRotated       = "rotated"
+	StepHookSucceeded = "hook_succeeded"
+	StepHookFailed    = "hook_failed"
+	StepRunFinished   = "run_finished"
+)
+
+// ErrNoBackend none of the backends is configured
+var ErrNoBackend = errors.New("no journal backend is configured")
+
+// Record a step of the execution, the token is never recorded
+type Record struct {
+	Time  time.Time `json:"time"`
+	RunID string    `json:"run_id"`
+	Step  string    `json:"step"`
+	Type  string    `json:"type,omitempty"`
+	Path  string    `json:"path,omitempty"`
+	Name  string    `json:"name,omitempty"`
+	// ExpiresAt the new expiry of the rotated token
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// Hooks the configured hook types of the rotated token
+	Hooks []string `json:"hooks,omitempty"`
+	// Hook the sequence number of the hook, starting from 1
+	Hook     int    `json:"hook,omitempty"`
+	HookType string `json:"hook_type,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// Key the unique key of the access token of the record
+func (r Record) Key() string {
+	return fmt.Sprintf("%s:%s:%s", r.Type, r.Path, r.Name)
+}
+
+// Pending the rotated access token that not all of it's hooks are succeeded
+type Pending struct {
+	Rotated   Record
+	Succeeded map[int]bool
+	// LastError the error of the last failed hook
+	LastError string
+}
+
+// Outstanding the sequence numbers of the hooks that are not succeeded yet
+func (p Pending) Outstanding() (seqs []int) {
+	for seq := 1; seq <= len(p.Rotated.Hooks); seq++ {
+		if !p.Succeeded[seq] {
+			seqs = append(seqs, seq)
+		}
+	}
+	return seqs
+}
+
+// Outstanding the rotated access tokens that have outstanding hooks in the order of their rotation, the later rotation is resetting the state
+func Outstanding(records []Record) []Pending {
+	pendings := map[string]*Pending{}
+	var keys []string
+	for _, r := range records {
+		key := r.Key()
+		switch r.Step {
+		case StepRotated:
+			if _, exists := pendings[key]; !exists {
+				keys = append(keys, key)
+			}
+			pendings[key] = &Pending{Rotated: r, Succeeded: map[int]bool{}}
+		case StepHookSucceeded:
+			if p := pendings[key]; p != nil {
+				p.Succeeded[r.Hook] = true
+			}
+		case StepHookFailed:
+			if p := pendings[key]; p != nil {
+				p.LastError = r.Error
+			}
+		}
+	}
+
+	var results []Pending
+	for _, key := range keys {
+		if p := pendings[key]; len(p.Outstanding()) > 0 {
+			results = append(results, *p)
+		}
+	}
+	return results
+}
+
+// Compact keep only the records of the access tokens that have outstanding hooks
+func Compact(records []Record) []Record {
+	outstanding := map[string]bool{}
+	for _, p := range Outstanding(records) {
+		outstanding[p.Rotated.Key()] = true
+	}
+
+	var results []Record
+	for _, r := range records {
+		if r.Name != "" && outstanding[r.Key()] {
+			results = append(results, r)
+		}
+	}
+	return results
+}
+
+// encode the records in JSON lines format
+func encode(records []Record) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, r := range records {
+		if err := enc.Encode(r); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// decode the records of JSON lines, the broken last line (as interrupted in writing it) is ignored
+func decode(content []byte) ([]Record, error) {
+	var records []Record
+	lines := bytes.Split(bytes.TrimSpace(content), []byte("\n"))
+	for idx, line := range lines {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var r Record
+		if err := json.Unmarshal(line, &r); err != nil {
+			if idx == len(lines)-1 {
+				break
+			}
+			return nil, fmt.Errorf("invalid journal record in line %d: %w", idx+1, err)
+		}
+		records = append(records, r)
+	}
+	return records, nil
+}
+
+// Backend appending and reading the records
+type Backend interface {
+	Name() string
+	Append(records []Record) error
+	Read() ([]Record, error)
+}
+
+// File the backend of local file in JSON lines format, it's written with owner only permission.
+// It's compacted as a new run is started the same as Rewritten, so the file is not growing by each run
+type File struct {
+	Path string
+}
+
+func (f File) Name() string {
+	return "file"
+}
+
+func (f File) Append(records []Record) (err error) {
+	if len(records) > 0 && records[0].Step == StepRunStarted {
+		return f.compact(records)
+	}
+
+	content, err := encode(records)
+	if err != nil {
+		return err
+	}
+	w, err := os.OpenFile(filepath.Clean(f.Path), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errors.Join(err, w.Close())
+	}()
+	_, err = w.Write(content)
+	return err
+}
+
+// compact keep only the records of the outstanding hooks along with the new ones, the file is replaced through a temporary file
+// so the partial write is not leaving a broken file
+func (f File) compact(records []Record) error {
+	existing, err := f.Read()
+	if err != nil {
+		return err
+	}
+	content, err := encode(append(Compact(existing), records...))
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Clean(f.Path)
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(content); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (f File) Read() ([]Record, error) {
+	content, err := os.ReadFile(filepath.Clean(f.Path))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return decode(content)
+}
+
+// ContentStore reading and writing the whole content, nil content is returned if it's not exists yet
+type ContentStore interface {
+	Name() string
+	Read() ([]byte, error)
+	Write(content []byte) error
+}
+
+// Rewritten the backend of the content store that is not appendable (e.g. CICD variable), the whole content is rewritten in each append.
+// It's compacted as a new run is started, so only the records of the outstanding hooks are carried over
+type Rewritten struct {
+	Store ContentStore
+}
+
+func (r Rewritten) Name() string {
+	return r.Store.Name()
+}
+
+func (r Rewritten) Append(records []Record) error {
+	existing, err := r.Read()
+	if err != nil {
+		return err
+	}
+	if len(records) > 0 && records[0].Step == StepRunStarted {
+		existing = Compact(existing)
+	}
+
+	content, err := encode(append(existing, records...))
+	if err != nil {
+		return err
+	}
+	return r.Store.Write(content)
+}
+
+func (r Rewritten) Read() ([]Record, error) {
+	content, err := r.Store.Read()
+	if err != nil {
+		return nil, err
+	}
+	return decode(content)
+}
+
+// Journal appending the records of a run to all of the backends, it's safe for concurrent use
+type Journal struct {
+	mu       sync.Mutex
+	runID    string
+	backends []Backend
+}
+
+// Append the records of the run, the errors are joined so all of the backends are attempted
+func (j *Journal) Append(records ...Record) error {
+	if len(j.backends) == 0 {
+		return ErrNoBackend
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	now := time.Now()
+	for idx := range records {
+		records[idx].RunID = j.runID
+		if records[idx].Time.IsZero() {
+			records[idx].Time = now
+		}
+	}
+
+	var errs []error
+	for _, backend := range j.backends {
+		if err := backend.Append(records); err != nil {
+			errs = append(errs, fmt.Errorf("journal backend %s: %w", backend.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Read the records of the first backend
+func (j *Journal) Read() ([]Record, error) {
+	if len(j.backends) == 0 {
+		return nil, ErrNoBackend
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	records, err := j.backends[0].Read()
+	if err != nil {
+		return nil, fmt.Errorf("journal backend %s: %w", j.backends[0].Name(), err)
+	}
+	return records, nil
+}
+
+// New create the journal of the run
+func New(runID string, backends ...Backend) *Journal {
+	return &Journal{runID: runID, backends: backends}
+}
@@ -0,0 +1,142 @@
+package journal_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/iomarmochtar/gitlab-token-updater/pkg/journal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryStore struct {
+	content []byte
+}
+
+func (m *memoryStore) Name() string               { return "memory" }
+func (m *memoryStore) Read() ([]byte, error)      { return m.content, nil }
+func (m *memoryStore) Write(content []byte) error { m.content = content; return nil }
+
+func rotated(name string, hooks ...string) journal.Record {
+	return journal.Record{Step: journal.StepRotated, Type: "repository", Path: "path/to/repo", Name: name, Hooks: hooks}
+}
+
+func hook(step, name string, seq int) journal.Record {
+	return journal.Record{Step: step, Type: "repository", Path: "path/to/repo", Name: name, Hook: seq}
+}
+
+func TestOutstanding(t *testing.T) {
+	records := []journal.Record{
+		{Step: journal.StepRunStarted},
+		rotated("MR Handler", "update_var", "update_var"),
+		hook(journal.StepHookSucceeded, "MR Handler", 1),
+		{Step: journal.StepHookFailed, Type: "repository", Path: "path/to/repo", Name: "MR Handler", Hook: 2, Error: "403 Forbidden"},
+		rotated("deployer", "update_var"),
+		hook(journal.StepHookSucceeded, "deployer", 1),
+		rotated("interrupted", "update_var"),
+	}
+
+	pendings := journal.Outstanding(records)
+	require.Len(t, pendings, 2)
+	assert.Equal(t, "MR Handler", pendings[0].Rotated.Name)
+	assert.Equal(t, []int{2}, pendings[0].Outstanding())
+	assert.Equal(t, "403 Forbidden", pendings[0].LastError)
+	assert.Equal(t, "interrupted", pendings[1].Rotated.Name)
+	assert.Equal(t, []int{1}, pendings[1].Outstanding())
+
+	// the later rotation is resetting the succeeded hooks
+	records = append(records, rotated("MR Handler", "update_var", "update_var"))
+	pendings = journal.Outstanding(records)
+	assert.Equal(t, []int{1, 2}, pendings[0].Outstanding())
+
+	// replaying the outstanding hooks in another run is completing it
+	records = append(records, hook(journal.StepHookSucceeded, "interrupted", 1))
+	assert.Len(t, journal.Outstanding(records), 1)
+}
+
+func TestJournal_File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.jsonl")
+	jr := journal.New("run-1", journal.File{Path: path})
+
+	records, err := jr.Read()
+	require.NoError(t, err)
+	assert.Empty(t, records)
+
+	require.NoError(t, jr.Append(journal.Record{Step: journal.StepRunStarted}, rotated("MR Handler", "update_var")))
+	require.NoError(t, jr.Append(hook(journal.StepHookSucceeded, "MR Handler", 1)))
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	// the broken last line as interrupted in writing it is ignored
+	w, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o600)
+	require.NoError(t, err)
+	_, err = w.WriteString(`{"step":"rot`)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	records, err = jr.Read()
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.Equal(t, "run-1", records[1].RunID)
+	assert.False(t, records[1].Time.IsZero())
+	assert.Empty(t, journal.Outstanding(records))
+
+	require.NoError(t, os.WriteFile(path, []byte("broken\n{}\n"), 0o600))
+	_, err = jr.Read()
+	assert.ErrorContains(t, err, "journal backend file: invalid journal record in line 1")
+
+	_, err = journal.New("run-1").Read()
+	assert.ErrorIs(t, err, journal.ErrNoBackend)
+}
+
+func TestJournal_FileCompacted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.jsonl")
+	previous := journal.New("run-1", journal.File{Path: path})
+	require.NoError(t, previous.Append(journal.Record{Step: journal.StepRunStarted}))
+	require.NoError(t, previous.Append(rotated("MR Handler", "update_var"), rotated("deployer", "update_var")))
+	require.NoError(t, previous.Append(hook(journal.StepHookSucceeded, "deployer", 1)))
+
+	// the file is not growing by each run, only the records of the outstanding hooks are carried over
+	for _, runID := range []string{"run-2", "run-3"} {
+		next := journal.New(runID, journal.File{Path: path})
+		require.NoError(t, next.Append(journal.Record{Step: journal.StepRunStarted}))
+		require.NoError(t, next.Append(journal.Record{Step: journal.StepRunFinished}))
+	}
+	records, err := previous.Read()
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.Equal(t, "MR Handler", records[0].Name)
+	assert.Equal(t, "run-1", records[0].RunID)
+	assert.Equal(t, journal.StepRunStarted, records[1].Step)
+	assert.Equal(t, "run-3", records[1].RunID)
+	assert.Len(t, journal.Outstanding(records), 1)
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestJournal_Rewritten(t *testing.T) {
+	store := &memoryStore{}
+	previous := journal.New("run-1", journal.Rewritten{Store: store})
+	require.NoError(t, previous.Append(journal.Record{Step: journal.StepRunStarted}))
+	require.NoError(t, previous.Append(rotated("MR Handler", "update_var"), rotated("deployer", "update_var")))
+	require.NoError(t, previous.Append(hook(journal.StepHookSucceeded, "deployer", 1)))
+
+	// only the records of the outstanding hooks are carried over to the next run
+	next := journal.New("run-2", journal.Rewritten{Store: store})
+	require.NoError(t, next.Append(journal.Record{Step: journal.StepRunStarted}))
+	records, err := next.Read()
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, "MR Handler", records[0].Name)
+	assert.Equal(t, "run-1", records[0].RunID)
+	assert.Equal(t, journal.StepRunStarted, records[1].Step)
+	assert.Equal(t, "run-2", records[1].RunID)
+	assert.Len(t, journal.Outstanding(records), 1)
+}
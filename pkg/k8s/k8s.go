@@ -0,0 +1,187 @@
+// Package k8s abstracting Kubernetes API execution for updating the secret
+package k8s
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+//go:generate mockgen -destination ../../test/mocks/k8s/k8s.go -source=k8s.go
+
+const (
+	contentTypeJSON       = "application/json"
+	contentTypeMergePatch = "application/merge-patch+json"
+	restartedAtAnnotation = "kubectl.kubernetes.io/restartedAt"
+	defaultTimeout        = 30 * time.Second
+)
+
+// ErrNotFound returned by Kubernetes API for the non exists object
+var ErrNotFound = errors.New("404 Not Found")
+
+// KubernetesAPI spec for the used Kubernetes API
+type KubernetesAPI interface {
+	GetSecret(namespace, name string) (map[string][]byte, error)
+	PatchSecret(namespace, name string, data map[string][]byte) error
+	CreateSecret(namespace, name string, data map[string][]byte) error
+	CanI(verb, resource, namespace, name string) (bool, error)
+	RestartDeployment(namespace, name string, restartedAt time.Time) error
+}
+
+// Kubernetes implement KubernetesAPI interface through it's REST API
+type Kubernetes struct {
+	server string
+	token  string
+	client *http.Client
+}
+
+// statusResponse the error response of Kubernetes API
+type statusResponse struct {
+	Message string `json:"message"`
+}
+
+// request execute the API request, the response is decoded to result if it's set
+func (k Kubernetes) request(method, path, contentType string, body, result any) error {
+	var reqBody io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequest(method, k.server+path, reqBody)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", contentTypeJSON)
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if k.token != "" {
+		req.Header.Set("Authorization", "Bearer "+k.token)
+	}
+
+	resp, err := k.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	} else if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		status := statusResponse{}
+		_ = json.NewDecoder(resp.Body).Decode(&status)
+		return fmt.Errorf("%s %s: %d %s", method, path, resp.StatusCode, status.Message)
+	}
+
+	if result == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+// secretPath the API path of the secret, the name is omitted for the collection one
+func secretPath(namespace, name string) string {
+	path := fmt.Sprintf("/api/v1/namespaces/%s/secrets", url.PathEscape(namespace))
+	if name != "" {
+		path = fmt.Sprintf("%s/%s", path, url.PathEscape(name))
+	}
+	return path
+}
+
+// GetSecret get the data of the secret
+func (k Kubernetes) GetSecret(namespace, name string) (map[string][]byte, error) {
+	secret := struct {
+		Data map[string][]byte `json:"data"`
+	}{}
+	if err := k.request(http.MethodGet, secretPath(namespace, name), "", nil, &secret); err != nil {
+		return nil, err
+	}
+	return secret.Data, nil
+}
+
+// PatchSecret update the keys of the secret, the other keys are kept as is
+func (k Kubernetes) PatchSecret(namespace, name string, data map[string][]byte) error {
+	patch := map[string]any{"data": data}
+	return k.request(http.MethodPatch, secretPath(namespace, name), contentTypeMergePatch, patch, nil)
+}
+
+// CreateSecret create the opaque secret with the data
+func (k Kubernetes) CreateSecret(namespace, name string, data map[string][]byte) error {
+	secret := map[string]any{
+		"apiVersion": "v1",
+		"kind":       "Secret",
+		"metadata":   map[string]string{"name": name, "namespace": namespace},
+		"type":       "Opaque",
+		"data":       data,
+	}
+	return k.request(http.MethodPost, secretPath(namespace, ""), contentTypeJSON, secret, nil)
+}
+
+// CanI check whether the current user is allowed to do the verb in the resource, the empty name is for all of the resources
+func (k Kubernetes) CanI(verb, resource, namespace, name string) (bool, error) {
+	review := map[string]any{
+		"apiVersion": "authorization.k8s.io/v1",
+		"kind":       "SelfSubjectAccessReview",
+		"spec": map[string]any{
+			"resourceAttributes": map[string]string{
+				"namespace": namespace,
+				"verb":      verb,
+				"resource":  resource,
+				"name":      name,
+			},
+		},
+	}
+	result := struct {
+		Status struct {
+			Allowed bool `json:"allowed"`
+		} `json:"status"`
+	}{}
+	err := k.request(http.MethodPost, "/apis/authorization.k8s.io/v1/selfsubjectaccessreviews", contentTypeJSON, review, &result)
+	return result.Status.Allowed, err
+}
+
+// RestartDeployment rollout restart the deployment, the same as `kubectl rollout restart`
+func (k Kubernetes) RestartDeployment(namespace, name string, restartedAt time.Time) error {
+	patch := map[string]any{
+		"spec": map[string]any{
+			"template": map[string]any{
+				"metadata": map[string]any{
+					"annotations": map[string]string{restartedAtAnnotation: restartedAt.Format(time.RFC3339Nano)},
+				},
+			},
+		},
+	}
+	path := fmt.Sprintf("/apis/apps/v1/namespaces/%s/deployments/%s", url.PathEscape(namespace), url.PathEscape(name))
+	return k.request(http.MethodPatch, path, contentTypeMergePatch, patch, nil)
+}
+
+// NewKubernetesAPI returning Kubernetes API object, it's authenticated by the in-cluster service account or the kubeconfig
+func NewKubernetesAPI(cfg ClientConfig) (KubernetesAPI, error) {
+	conn, err := cfg.load()
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig, err := conn.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Kubernetes{
+		server: conn.server,
+		token:  conn.token,
+		client: &http.Client{
+			Timeout:   defaultTimeout,
+			Transport: &http.Transport{TLSClientConfig: tlsConfig, Proxy: http.ProxyFromEnvironment},
+		},
+	}, nil
+}
@@ -0,0 +1,198 @@
+package k8s_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	k "github.com/iomarmochtar/gitlab-token-updater/pkg/k8s"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordedRequest struct {
+	method      string
+	path        string
+	contentType string
+	auth        string
+	body        map[string]any
+}
+
+// fakeAPIServer serving the prepared responses by method and path, all of the requests are recorded
+func fakeAPIServer(t *testing.T, responses map[string]string) (*httptest.Server, *[]recordedRequest) {
+	requests := []recordedRequest{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := recordedRequest{method: r.Method, path: r.URL.Path, contentType: r.Header.Get("Content-Type"), auth: r.Header.Get("Authorization")}
+		if content, _ := io.ReadAll(r.Body); len(content) > 0 {
+			require.NoError(t, json.Unmarshal(content, &req.body))
+		}
+		requests = append(requests, req)
+
+		resp, ok := responses[r.Method+" "+r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"kind":"Status","message":"not found"}`))
+			return
+		}
+		if resp == "forbidden" {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"kind":"Status","message":"secrets is forbidden"}`))
+			return
+		}
+		_, _ = w.Write([]byte(resp))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &requests
+}
+
+func writeKubeconfig(t *testing.T, server string) string {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "token"), []byte("file-token\n"), 0o600))
+	content := fmt.Sprintf(`apiVersion: v1
+kind: Config
+current-context: dev
+clusters:
+- name: dev
+  cluster:
+    server: %[1]s/
+- name: prod
+  cluster:
+    server: %[1]s
+contexts:
+- name: dev
+  context:
+    cluster: dev
+    user: dev
+- name: prod
+  context:
+    cluster: prod
+    user: prod
+- name: eks
+  context:
+    cluster: prod
+    user: eks
+- name: gke
+  context:
+    cluster: prod
+    user: gke
+users:
+- name: dev
+  user:
+    token: dev-token
+- name: prod
+  user:
+    tokenFile: token
+- name: eks
+  user:
+    exec:
+      apiVersion: client.authentication.k8s.io/v1beta1
+      command: aws
+      args: ["eks", "get-token", "--cluster-name", "prod"]
+- name: gke
+  user:
+    auth-provider:
+      name: gcp
+`, server)
+	path := filepath.Join(dir, "config")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestKubernetes_Secret(t *testing.T) {
+	srv, requests := fakeAPIServer(t, map[string]string{
+		"GET /api/v1/namespaces/default/secrets/app":             `{"data":{"token":"b2xk","other":"a2VlcA=="}}`,
+		"PATCH /api/v1/namespaces/default/secrets/app":           `{}`,
+		"POST /api/v1/namespaces/default/secrets":                `{}`,
+		"PATCH /api/v1/namespaces/denied/secrets/app":            "forbidden",
+		"PATCH /apis/apps/v1/namespaces/default/deployments/web": `{}`,
+	})
+	api, err := k.NewKubernetesAPI(k.ClientConfig{Kubeconfig: writeKubeconfig(t, srv.URL)})
+	require.NoError(t, err)
+
+	data, err := api.GetSecret("default", "app")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]byte{"token": []byte("old"), "other": []byte("keep")}, data)
+
+	_, err = api.GetSecret("default", "missing")
+	assert.ErrorIs(t, err, k.ErrNotFound)
+
+	assert.NoError(t, api.PatchSecret("default", "app", map[string][]byte{"token": []byte("new")}))
+	assert.NoError(t, api.CreateSecret("default", "new", map[string][]byte{"token": []byte("new")}))
+	assert.EqualError(t, api.PatchSecret("denied", "app", map[string][]byte{"token": []byte("new")}),
+		"PATCH /api/v1/namespaces/denied/secrets/app: 403 secrets is forbidden")
+
+	restartedAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	assert.NoError(t, api.RestartDeployment("default", "web", restartedAt))
+
+	reqs := *requests
+	require.Len(t, reqs, 6)
+	assert.Equal(t, "Bearer dev-token", reqs[0].auth)
+
+	assert.Equal(t, "application/merge-patch+json", reqs[2].contentType)
+	assert.Equal(t, map[string]any{"data": map[string]any{"token": "bmV3"}}, reqs[2].body)
+
+	assert.Equal(t, "application/json", reqs[3].contentType)
+	assert.Equal(t, map[string]any{
+		"apiVersion": "v1",
+		"kind":       "Secret",
+		"metadata":   map[string]any{"name": "new", "namespace": "default"},
+		"type":       "Opaque",
+		"data":       map[string]any{"token": "bmV3"},
+	}, reqs[3].body)
+
+	assert.Equal(t, map[string]any{"spec": map[string]any{"template": map[string]any{"metadata": map[string]any{
+		"annotations": map[string]any{"kubectl.kubernetes.io/restartedAt": "2024-01-02T03:04:05Z"},
+	}}}}, reqs[5].body)
+}
+
+func TestKubernetes_CanI(t *testing.T) {
+	srv, requests := fakeAPIServer(t, map[string]string{
+		"POST /apis/authorization.k8s.io/v1/selfsubjectaccessreviews": `{"status":{"allowed":true}}`,
+	})
+	api, err := k.NewKubernetesAPI(k.ClientConfig{Kubeconfig: writeKubeconfig(t, srv.URL), Context: "prod"})
+	require.NoError(t, err)
+
+	allowed, err := api.CanI("patch", "secrets", "default", "app")
+	assert.NoError(t, err)
+	assert.True(t, allowed)
+
+	reqs := *requests
+	require.Len(t, reqs, 1)
+	assert.Equal(t, "Bearer file-token", reqs[0].auth)
+	assert.Equal(t, map[string]any{"resourceAttributes": map[string]any{
+		"namespace": "default", "verb": "patch", "resource": "secrets", "name": "app",
+	}}, reqs[0].body["spec"])
+}
+
+func TestNewKubernetesAPI_Errors(t *testing.T) {
+	kubeconfig := writeKubeconfig(t, "https://127.0.0.1:6443")
+
+	_, err := k.NewKubernetesAPI(k.ClientConfig{Kubeconfig: kubeconfig, Context: "unknown"})
+	assert.ErrorIs(t, err, k.ErrContextNotFound)
+
+	_, err = k.NewKubernetesAPI(k.ClientConfig{Kubeconfig: kubeconfig, Context: "eks"})
+	assert.ErrorIs(t, err, k.ErrUnsupportedAuth)
+	assert.ErrorContains(t, err, "exec of user eks")
+
+	_, err = k.NewKubernetesAPI(k.ClientConfig{Kubeconfig: kubeconfig, Context: "gke"})
+	assert.ErrorIs(t, err, k.ErrUnsupportedAuth)
+	assert.ErrorContains(t, err, "auth-provider of user gke")
+
+	_, err = k.NewKubernetesAPI(k.ClientConfig{Kubeconfig: "/file/is/not/found"})
+	assert.True(t, os.IsNotExist(err))
+
+	t.Setenv("KUBERNETES_SERVICE_HOST", "")
+	_, err = k.NewKubernetesAPI(k.ClientConfig{InCluster: true})
+	assert.ErrorIs(t, err, k.ErrNotInCluster)
+
+	t.Setenv("KUBERNETES_SERVICE_HOST", "10.0.0.1")
+	k.ServiceAccountDir = t.TempDir()
+	_, err = k.NewKubernetesAPI(k.ClientConfig{InCluster: true})
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,240 @@
+package k8s
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	envKubeconfig = "KUBECONFIG"
+	envK8sHost    = "KUBERNETES_SERVICE_HOST"
+	envK8sPort    = "KUBERNETES_SERVICE_PORT"
+)
+
+var (
+	// ServiceAccountDir the mounted service account directory of the in-cluster pod
+	ServiceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+	// ErrNotInCluster the in-cluster auth is used outside of the Kubernetes pod
+	ErrNotInCluster = errors.New("not running inside of kubernetes cluster, missing env var KUBERNETES_SERVICE_HOST")
+	// ErrContextNotFound the kubeconfig context is not exists
+	ErrContextNotFound = errors.New("kubeconfig context not found")
+	// ErrUnsupportedAuth the kubeconfig user is authenticated by the external plugin (e.g. EKS, GKE and AKS), only the token and client certificate are supported
+	ErrUnsupportedAuth = errors.New("unsupported kubeconfig auth")
+)
+
+// ClientConfig the authentication source of Kubernetes API
+type ClientConfig struct {
+	// Kubeconfig the path of kubeconfig file, fallback to KUBECONFIG env var then ~/.kube/config
+	Kubeconfig string
+	// Context the kubeconfig context, use the current-context if it's empty
+	Context   string
+	InCluster bool
+}
+
+// connection the resolved server along with it's credentials
+type connection struct {
+	server     string
+	token      string
+	caData     []byte
+	certData   []byte
+	keyData    []byte
+	skipVerify bool
+}
+
+// kubeconfig the used parts of kubeconfig file
+type kubeconfig struct {
+	CurrentContext string `yaml:"current-context"`
+	Clusters       []struct {
+		Name    string `yaml:"name"`
+		Cluster struct {
+			Server                   string `yaml:"server"`
+			CertificateAuthority     string `yaml:"certificate-authority"`
+			CertificateAuthorityData string `yaml:"certificate-authority-data"`
+			InsecureSkipTLSVerify    bool   `yaml:"insecure-skip-tls-verify"`
+		} `yaml:"cluster"`
+	} `yaml:"clusters"`
+	Users []struct {
+		Name string `yaml:"name"`
+		User struct {
+			Token                 string `yaml:"token"`
+			TokenFile             string `yaml:"tokenFile"`
+			ClientCertificate     string `yaml:"client-certificate"`
+			ClientCertificateData string `yaml:"client-certificate-data"`
+			ClientKey             string `yaml:"client-key"`
+			ClientKeyData         string `yaml:"client-key-data"`
+			Exec                  any    `yaml:"exec"`
+			AuthProvider          any    `yaml:"auth-provider"`
+		} `yaml:"user"`
+	} `yaml:"users"`
+	Contexts []struct {
+		Name    string `yaml:"name"`
+		Context struct {
+			Cluster string `yaml:"cluster"`
+			User    string `yaml:"user"`
+		} `yaml:"context"`
+	} `yaml:"contexts"`
+}
+
+// load resolve the connection from the in-cluster service account or the kubeconfig
+func (c ClientConfig) load() (*connection, error) {
+	if c.InCluster {
+		return loadInCluster()
+	}
+	return loadKubeconfig(c.kubeconfigPath(), c.Context)
+}
+
+// kubeconfigPath the path of kubeconfig by it's precedence
+func (c ClientConfig) kubeconfigPath() string {
+	if c.Kubeconfig != "" {
+		return c.Kubeconfig
+	}
+	if fromEnv := os.Getenv(envKubeconfig); fromEnv != "" {
+		// only the first one is used in case of multiple files
+		return filepath.SplitList(fromEnv)[0]
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".kube", "config")
+}
+
+func loadInCluster() (*connection, error) {
+	host, port := os.Getenv(envK8sHost), os.Getenv(envK8sPort)
+	if host == "" {
+		return nil, ErrNotInCluster
+	}
+	if port == "" {
+		port = "443"
+	}
+
+	token, err := os.ReadFile(filepath.Join(ServiceAccountDir, "token"))
+	if err != nil {
+		return nil, err
+	}
+	caData, err := os.ReadFile(filepath.Join(ServiceAccountDir, "ca.crt"))
+	if err != nil {
+		return nil, err
+	}
+
+	return &connection{
+		server: "https://" + net.JoinHostPort(host, port),
+		token:  strings.TrimSpace(string(token)),
+		caData: caData,
+	}, nil
+}
+
+// inlineOrFile the base64 encoded inline data or the content of the file, relative file path is resolved from the kubeconfig directory
+func inlineOrFile(data, path, baseDir string) ([]byte, error) {
+	if data != "" {
+		return base64.StdEncoding.DecodeString(data)
+	}
+	if path == "" {
+		return nil, nil
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(baseDir, path)
+	}
+	return os.ReadFile(path)
+}
+
+func loadKubeconfig(path, context string) (*connection, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	kc := kubeconfig{}
+	if err = yaml.Unmarshal(content, &kc); err != nil {
+		return nil, fmt.Errorf("error in parsing kubeconfig %s: %w", path, err)
+	}
+
+	if context == "" {
+		context = kc.CurrentContext
+	}
+	clusterName, userName := "", ""
+	found := false
+	for _, ctx := range kc.Contexts {
+		if ctx.Name == context {
+			clusterName, userName, found = ctx.Context.Cluster, ctx.Context.User, true
+			break
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("%w: %s", ErrContextNotFound, context)
+	}
+
+	baseDir := filepath.Dir(path)
+	conn := &connection{}
+	for _, cl := range kc.Clusters {
+		if cl.Name != clusterName {
+			continue
+		}
+		conn.server = strings.TrimSuffix(cl.Cluster.Server, "/")
+		conn.skipVerify = cl.Cluster.InsecureSkipTLSVerify
+		if conn.caData, err = inlineOrFile(cl.Cluster.CertificateAuthorityData, cl.Cluster.CertificateAuthority, baseDir); err != nil {
+			return nil, err
+		}
+	}
+	if conn.server == "" {
+		return nil, fmt.Errorf("missing server of cluster %s in kubeconfig", clusterName)
+	}
+
+	for _, u := range kc.Users {
+		if u.Name != userName {
+			continue
+		}
+		// failed early, otherwise the request is sent without credentials and rejected by the vague 401 or 403
+		if u.User.Exec != nil {
+			return nil, fmt.Errorf("%w: exec of user %s", ErrUnsupportedAuth, userName)
+		}
+		if u.User.AuthProvider != nil {
+			return nil, fmt.Errorf("%w: auth-provider of user %s", ErrUnsupportedAuth, userName)
+		}
+		conn.token = u.User.Token
+		if conn.token == "" && u.User.TokenFile != "" {
+			token, err := inlineOrFile("", u.User.TokenFile, baseDir)
+			if err != nil {
+				return nil, err
+			}
+			conn.token = strings.TrimSpace(string(token))
+		}
+		if conn.certData, err = inlineOrFile(u.User.ClientCertificateData, u.User.ClientCertificate, baseDir); err != nil {
+			return nil, err
+		}
+		if conn.keyData, err = inlineOrFile(u.User.ClientKeyData, u.User.ClientKey, baseDir); err != nil {
+			return nil, err
+		}
+	}
+
+	return conn, nil
+}
+
+// tlsConfig the TLS config of the connection by using the CA and client certificate if they are set
+func (c connection) tlsConfig() (*tls.Config, error) {
+	config := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		// #nosec G402 -- only when it's explicitly set in kubeconfig
+		InsecureSkipVerify: c.skipVerify,
+	}
+	if len(c.caData) > 0 {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(c.caData) {
+			return nil, errors.New("invalid certificate authority of kubernetes cluster")
+		}
+		config.RootCAs = pool
+	}
+	if len(c.certData) > 0 {
+		cert, err := tls.X509KeyPair(c.certData, c.keyData)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
@@ -0,0 +1,178 @@
+// Package notify sending the notification through Slack, Microsoft Teams incoming webhook or email
+package notify
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/iomarmochtar/gitlab-token-updater/pkg/webhook"
+)
+
+const (
+	// DefaultTimeout the timeout of sending the notification
+	DefaultTimeout = 30 * time.Second
+	// TLSStartTLS upgrade the plain connection by STARTTLS, it's failed if the server is not offering it
+	TLSStartTLS = "starttls"
+	// TLSImplicit the connection is TLS since it's established, e.g. port 465
+	TLSImplicit = "implicit"
+	// TLSNone the plain connection, e.g. the local relay
+	TLSNone = "none"
+)
+
+// ErrSTARTTLSNotOffered the SMTP server is not offering STARTTLS while it's required
+var ErrSTARTTLSNotOffered = errors.New("SMTP server is not offering STARTTLS, use tls implicit for the implicit TLS port (e.g. 465) or none for the plain connection")
+
+// Message the notification content
+type Message struct {
+	Subject string
+	Text    string
+}
+
+// Notifier sending the notification message
+type Notifier interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// postJSON post the JSON payload to the incoming webhook, the non 2xx status is an error
+func postJSON(ctx context.Context, url string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	resp, err := webhook.Send(ctx, webhook.Request{
+		URL:     url,
+		Method:  http.MethodPost,
+		Headers: map[string]string{"Content-Type": "application/json"},
+		Body:    string(body),
+		Timeout: DefaultTimeout,
+	})
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("incoming webhook responded with status %d: %s", resp.StatusCode, strings.TrimSpace(string(resp.Body)))
+	}
+	return nil
+}
+
+// Slack sending the notification to Slack incoming webhook
+type Slack struct {
+	WebhookURL string
+}
+
+func (s Slack) Send(ctx context.Context, msg Message) error {
+	return postJSON(ctx, s.WebhookURL, map[string]string{"text": fmt.Sprintf("*%s*\n%s", msg.Subject, msg.Text)})
+}
+
+// Teams sending the notification to Microsoft Teams incoming webhook as message card
+type Teams struct {
+	WebhookURL string
+}
+
+func (t Teams) Send(ctx context.Context, msg Message) error {
+	return postJSON(ctx, t.WebhookURL, map[string]string{
+		"@type":    "MessageCard",
+		"@context": "https://schema.org/extensions",
+		"summary":  msg.Subject,
+		"title":    msg.Subject,
+		// the card text is markdown, the line break must be a double one
+		"text": strings.ReplaceAll(msg.Text, "\n", "\n\n"),
+	})
+}
+
+// Email sending the notification through SMTP server
+type Email struct {
+	Host string
+	Port int
+	// TLS the TLS mode, one of TLSStartTLS (default), TLSImplicit or TLSNone
+	TLS      string
+	Username string
+	Password string
+	From     string
+	To       []string
+}
+
+// content the email content along with it's headers
+func (e Email) content(msg Message, now time.Time) string {
+	headers := []string{
+		"From: " + e.From,
+		"To: " + strings.Join(e.To, ", "),
+		"Subject: " + mime.QEncoding.Encode("utf-8", msg.Subject),
+		"Date: " + now.Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=utf-8",
+	}
+	body := strings.ReplaceAll(msg.Text, "\r\n", "\n")
+	return strings.Join(headers, "\r\n") + "\r\n\r\n" + strings.ReplaceAll(body, "\n", "\r\n") + "\r\n"
+}
+
+// dial connect to the SMTP server, the TLS handshake is done in the first place for the implicit TLS
+func (e Email) dial(ctx context.Context, tlsConfig *tls.Config) (net.Conn, error) {
+	addr := net.JoinHostPort(e.Host, strconv.Itoa(e.Port))
+	dialer := &net.Dialer{Timeout: DefaultTimeout}
+	if e.TLS == TLSImplicit {
+		return (&tls.Dialer{NetDialer: dialer, Config: tlsConfig}).DialContext(ctx, "tcp", addr)
+	}
+	return dialer.DialContext(ctx, "tcp", addr)
+}
+
+func (e Email) Send(ctx context.Context, msg Message) error {
+	tlsConfig := &tls.Config{ServerName: e.Host, MinVersion: tls.VersionTLS12}
+	conn, err := e.dial(ctx, tlsConfig)
+	if err != nil {
+		return err
+	}
+	_ = conn.SetDeadline(time.Now().Add(DefaultTimeout))
+
+	client, err := smtp.NewClient(conn, e.Host)
+	if err != nil {
+		_ = conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if e.TLS == "" || e.TLS == TLSStartTLS {
+		// never fallback to the plain connection, the credentials and the message are sent in clear text
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return ErrSTARTTLSNotOffered
+		}
+		if err = client.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	}
+	if e.Username != "" {
+		if err = client.Auth(smtp.PlainAuth("", e.Username, e.Password, e.Host)); err != nil {
+			return err
+		}
+	}
+
+	if err = client.Mail(e.From); err != nil {
+		return err
+	}
+	for _, to := range e.To {
+		if err = client.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err = w.Write([]byte(e.content(msg, time.Now()))); err != nil {
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
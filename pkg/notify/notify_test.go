@@ -0,0 +1,119 @@
+package notify_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	n "github.com/iomarmochtar/gitlab-token-updater/pkg/notify"
+	t_helper "github.com/iomarmochtar/gitlab-token-updater/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIncomingWebhook_Send(t *testing.T) {
+	msg := n.Message{Subject: "access token bot is rotated", Text: "first line\nsecond line"}
+
+	testCases := map[string]struct {
+		notifier        func(url string) n.Notifier
+		status          int
+		expectedPayload map[string]any
+		expectedErr     string
+	}{
+		"slack": {
+			notifier:        func(url string) n.Notifier { return n.Slack{WebhookURL: url} },
+			status:          http.StatusOK,
+			expectedPayload: map[string]any{"text": "*access token bot is rotated*\nfirst line\nsecond line"},
+		},
+		"teams": {
+			notifier: func(url string) n.Notifier { return n.Teams{WebhookURL: url} },
+			status:   http.StatusOK,
+			expectedPayload: map[string]any{
+				"@type": "MessageCard", "@context": "https://schema.org/extensions",
+				"summary": "access token bot is rotated", "title": "access token bot is rotated", "text": "first line\n\nsecond line",
+			},
+		},
+		"non 2xx status": {
+			notifier:        func(url string) n.Notifier { return n.Slack{WebhookURL: url} },
+			status:          http.StatusNotFound,
+			expectedPayload: map[string]any{"text": "*access token bot is rotated*\nfirst line\nsecond line"},
+			expectedErr:     "incoming webhook responded with status 404: no_service",
+		},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			var payload map[string]any
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				require.NoError(t, json.Unmarshal(body, &payload))
+				assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+				w.WriteHeader(tc.status)
+				_, _ = w.Write([]byte("no_service"))
+			}))
+			defer srv.Close()
+
+			err := tc.notifier(srv.URL).Send(context.Background(), msg)
+			assert.Equal(t, tc.expectedPayload, payload)
+			if tc.expectedErr != "" {
+				assert.EqualError(t, err, tc.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestEmail_Send(t *testing.T) {
+	srv := t_helper.NewSMTPServer(t)
+	email := n.Email{Host: srv.Host, Port: srv.Port, TLS: n.TLSNone, From: "bot@example.com", To: []string{"team@example.com", "ops@example.com"}}
+
+	err := email.Send(context.Background(), n.Message{Subject: "access token bot is rotated", Text: "first line\nsecond line"})
+	require.NoError(t, err)
+
+	messages := srv.Messages()
+	require.Len(t, messages, 1)
+	assert.Equal(t, "bot@example.com", messages[0].From)
+	assert.Equal(t, []string{"team@example.com", "ops@example.com"}, messages[0].To)
+	assert.Contains(t, messages[0].Data, "To: team@example.com, ops@example.com\r\n")
+	assert.Contains(t, messages[0].Data, "Subject: access token bot is rotated\r\n")
+	assert.Contains(t, messages[0].Data, "\r\n\r\nfirst line\r\nsecond line\r\n")
+}
+
+func TestEmail_SendTLS(t *testing.T) {
+	msg := n.Message{Subject: "access token bot is rotated", Text: "rotated"}
+
+	t.Run("STARTTLS is not offered", func(t *testing.T) {
+		srv := t_helper.NewSMTPServer(t)
+		email := n.Email{Host: srv.Host, Port: srv.Port, From: "bot@example.com", To: []string{"team@example.com"}}
+		assert.ErrorIs(t, email.Send(context.Background(), msg), n.ErrSTARTTLSNotOffered)
+		assert.Empty(t, srv.Messages())
+	})
+
+	t.Run("implicit TLS handshake in the first place", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer listener.Close()
+		received := make(chan byte, 1)
+		go func() {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			first := make([]byte, 1)
+			_, _ = conn.Read(first)
+			received <- first[0]
+		}()
+
+		addr := listener.Addr().(*net.TCPAddr)
+		email := n.Email{Host: addr.IP.String(), Port: addr.Port, TLS: n.TLSImplicit, From: "bot@example.com", To: []string{"team@example.com"}}
+		assert.Error(t, email.Send(context.Background(), msg))
+		// the handshake record type, instead of waiting for the SMTP greeting
+		assert.Equal(t, byte(0x16), <-received)
+	})
+}
@@ -0,0 +1,271 @@
+// Package recovery keeping the rotated token encrypted by age X25519 recipients, so it's not lost when the hooks are failed
+package recovery
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+)
+
+var (
+	// ErrNotFound the entry is not exists in any of the backends
+	ErrNotFound = errors.New("recovery entry is not found")
+	// ErrNoBackend none of the backends is configured
+	ErrNoBackend = errors.New("no recovery backend is configured")
+)
+
+// Backend reading and writing the encoded entries, nil content is returned if it's not exists yet
+type Backend interface {
+	Name() string
+	Read() ([]byte, error)
+	Write(content []byte) error
+}
+
+// Entry the kept token of an access token, only the token is encrypted so the entries are writable without the identity
+type Entry struct {
+	Type      string    `json:"type"`
+	Path      string    `json:"path"`
+	Name      string    `json:"name"`
+	ID        int       `json:"id,omitempty"`
+	ExpiresAt time.Time `json:"expires_at"`
+	RotatedAt time.Time `json:"rotated_at"`
+	// Token the age encrypted token in armored format
+	Token string `json:"token"`
+}
+
+// Key the unique key of the access token
+func Key(mType, path, name string) string {
+	return fmt.Sprintf("%s:%s:%s", mType, path, name)
+}
+
+// Key the unique key of the entry
+func (e Entry) Key() string {
+	return Key(e.Type, e.Path, e.Name)
+}
+
+// Decrypt the kept token by one of the identities
+func (e Entry) Decrypt(identities ...age.Identity) (string, error) {
+	r, err := age.Decrypt(armor.NewReader(strings.NewReader(e.Token)), identities...)
+	if err != nil {
+		return "", fmt.Errorf("error in decrypting token of %s: %w", e.Key(), err)
+	}
+	token, err := io.ReadAll(r)
+	return string(token), err
+}
+
+// encrypt the token for all of the recipients
+func encrypt(token string, recipients []age.Recipient) (string, error) {
+	var buf bytes.Buffer
+	armored := armor.NewWriter(&buf)
+	w, err := age.Encrypt(armored, recipients...)
+	if err != nil {
+		return "", err
+	}
+	if _, err = io.WriteString(w, token); err != nil {
+		return "", err
+	}
+	if err = w.Close(); err != nil {
+		return "", err
+	}
+	if err = armored.Close(); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// decode the backend content into entries, the empty content is an empty entries
+func decode(content []byte) (map[string]Entry, error) {
+	entries := map[string]Entry{}
+	if len(bytes.TrimSpace(content)) == 0 {
+		return entries, nil
+	}
+	if err := json.Unmarshal(content, &entries); err != nil {
+		return nil, fmt.Errorf("invalid recovery content: %w", err)
+	}
+	return entries, nil
+}
+
+// Store keeping the entries in all of the backends
+type Store struct {
+	backends   []Backend
+	recipients []age.Recipient
+}
+
+// update read-modify-write the entries of each backends, the errors are joined so all of them are attempted
+func (s Store) update(modify func(entries map[string]Entry) bool) error {
+	if len(s.backends) == 0 {
+		return ErrNoBackend
+	}
+
+	var errs []error
+	for _, backend := range s.backends {
+		err := func() error {
+			content, err := backend.Read()
+			if err != nil {
+				return err
+			}
+			entries, err := decode(content)
+			if err != nil {
+				return err
+			}
+			if !modify(entries) {
+				return nil
+			}
+			encoded, err := json.MarshalIndent(entries, "", "  ")
+			if err != nil {
+				return err
+			}
+			return backend.Write(encoded)
+		}()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("recovery backend %s: %w", backend.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Put encrypt the token then keep it as the entry, the existing one of the same access token is replaced
+func (s Store) Put(entry Entry, token string) error {
+	encrypted, err := encrypt(token, s.recipients)
+	if err != nil {
+		return err
+	}
+	entry.Token = encrypted
+	return s.update(func(entries map[string]Entry) bool {
+		entries[entry.Key()] = entry
+		return true
+	})
+}
+
+// Delete remove the entry of the access token from all of the backends
+func (s Store) Delete(key string) error {
+	return s.update(func(entries map[string]Entry) bool {
+		if _, ok := entries[key]; !ok {
+			return false
+		}
+		delete(entries, key)
+		return true
+	})
+}
+
+// List all of the entries sorted by their keys, the entry in the first backend is preferred if it's exists in multiple backends
+func (s Store) List() ([]Entry, error) {
+	if len(s.backends) == 0 {
+		return nil, ErrNoBackend
+	}
+
+	merged := map[string]Entry{}
+	for _, backend := range s.backends {
+		content, err := backend.Read()
+		if err != nil {
+			return nil, fmt.Errorf("recovery backend %s: %w", backend.Name(), err)
+		}
+		entries, err := decode(content)
+		if err != nil {
+			return nil, fmt.Errorf("recovery backend %s: %w", backend.Name(), err)
+		}
+		for key, entry := range entries {
+			if _, exists := merged[key]; !exists {
+				merged[key] = entry
+			}
+		}
+	}
+
+	results := make([]Entry, 0, len(merged))
+	for _, entry := range merged {
+		results = append(results, entry)
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Key() < results[j].Key() })
+	return results, nil
+}
+
+// Get the entry of the access token
+func (s Store) Get(key string) (*Entry, error) {
+	entries, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.Key() == key {
+			return &entry, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// File the backend of local file, it's written with owner only permission
+type File struct {
+	Path string
+}
+
+func (f File) Name() string {
+	return "file"
+}
+
+func (f File) Read() ([]byte, error) {
+	content, err := os.ReadFile(filepath.Clean(f.Path))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	return content, err
+}
+
+// Write replace the file content through a temporary file, so the partial write is not leaving a broken file
+func (f File) Write(content []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(f.Path), "."+filepath.Base(f.Path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(content); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), f.Path)
+}
+
+// ParseRecipients parse the age X25519 public keys (age1...)
+func ParseRecipients(keys []string) ([]age.Recipient, error) {
+	recipients := make([]age.Recipient, 0, len(keys))
+	for _, key := range keys {
+		recipient, err := age.ParseX25519Recipient(key)
+		if err != nil {
+			return nil, err
+		}
+		recipients = append(recipients, recipient)
+	}
+	return recipients, nil
+}
+
+// ReadIdentities read the age identities file as generated by age-keygen
+func ReadIdentities(path string) ([]age.Identity, error) {
+	f, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return age.ParseIdentities(f)
+}
+
+// NewStore create the store of the backends that is encrypting the tokens for the recipients
+func NewStore(recipients []string, backends ...Backend) (*Store, error) {
+	parsed, err := ParseRecipients(recipients)
+	if err != nil {
+		return nil, err
+	}
+	return &Store{backends: backends, recipients: parsed}, nil
+}
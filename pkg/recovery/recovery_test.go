@@ -0,0 +1,80 @@
+package recovery_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"filippo.io/age"
+	"github.com/iomarmochtar/gitlab-token-updater/pkg/recovery"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type failingBackend struct{}
+
+func (failingBackend) Name() string               { return "failing" }
+func (failingBackend) Read() ([]byte, error)      { return nil, errors.New("unavailable") }
+func (failingBackend) Write(content []byte) error { return errors.New("unavailable") }
+
+func TestStore(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "recovery.json")
+	store, err := recovery.NewStore([]string{identity.Recipient().String()}, recovery.File{Path: path})
+	require.NoError(t, err)
+
+	entry := recovery.Entry{
+		Type: "repository", Path: "path/to/repo", Name: "MR Handler", ID: 123,
+		ExpiresAt: time.Date(2024, 7, 4, 0, 0, 0, 0, time.UTC), RotatedAt: time.Date(2024, 4, 5, 0, 0, 0, 0, time.UTC),
+	}
+	require.NoError(t, store.Put(entry, "glpat-newnew"))
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(content), "glpat-newnew")
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	kept, err := store.Get(recovery.Key("repository", "path/to/repo", "MR Handler"))
+	require.NoError(t, err)
+	assert.Equal(t, entry.ExpiresAt, kept.ExpiresAt)
+	token, err := kept.Decrypt(identity)
+	require.NoError(t, err)
+	assert.Equal(t, "glpat-newnew", token)
+
+	other, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	_, err = kept.Decrypt(other)
+	assert.Error(t, err)
+
+	require.NoError(t, store.Delete(entry.Key()))
+	_, err = store.Get(entry.Key())
+	assert.ErrorIs(t, err, recovery.ErrNotFound)
+}
+
+func TestStore_BackendErrors(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "recovery.json")
+	store, err := recovery.NewStore([]string{identity.Recipient().String()}, recovery.File{Path: path}, failingBackend{})
+	require.NoError(t, err)
+
+	// the entry is still kept in the working backend
+	err = store.Put(recovery.Entry{Type: "group", Path: "path/to/group", Name: "deployer"}, "glpat-newnew")
+	assert.EqualError(t, err, "recovery backend failing: unavailable")
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "group:path/to/group:deployer")
+
+	_, err = recovery.NewStore([]string{"age1invalid"})
+	assert.Error(t, err)
+
+	empty, err := recovery.NewStore(nil)
+	require.NoError(t, err)
+	_, err = empty.List()
+	assert.ErrorIs(t, err, recovery.ErrNoBackend)
+}
@@ -2,40 +2,178 @@
 package shell
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
+	"strings"
+	"time"
 )
 
 //go:generate mockgen -destination ../../test/mocks/shell/shell.go -source=shell.go
 
+const (
+	redactedMark = "[REDACTED]"
+	// stderrExcerptLen the maximum length of stderr that is included in the exit error
+	stderrExcerptLen = 512
+	// waitDelay the waiting time of the outputs after the command is killed, since it's children may still hold them
+	waitDelay = 3 * time.Second
+)
+
+var (
+	// DefaultInterpreter the interpreter of inline script if it's not set
+	DefaultInterpreter = []string{"/bin/sh", "-c"}
+	// ErrTimeout the command is killed since it's exceeding the timeout
+	ErrTimeout = errors.New("command execution timeout")
+)
+
 // Shell abstracting shell command execution
 type Shell interface {
 	Exec(command string, envVars map[string]string) (results []byte, err error)
+	Run(ctx context.Context, cmd Command) (*Result, error)
 	FileMustExists(path string) error
 }
 
+// Command the executable or inline script along with it's execution options
+type Command struct {
+	// Path the executable, it's ignored when the Script is set
+	Path string
+	Args []string
+	// Script the inline script, it's executed by the Interpreter followed by the Args
+	Script      string
+	Interpreter []string
+	WorkDir     string
+	// Timeout the command is killed after reaching it, no timeout if it's zero
+	Timeout time.Duration
+	Env     map[string]string
+	// InheritEnv the env var names of the current process that are passed to the command, overridden by Env
+	InheritEnv []string
+	Stdin      string
+	// Redact the secrets that are masked in the results and errors
+	Redact []string
+}
+
+// Result the outputs of the executed command
+type Result struct {
+	Stdout []byte
+	Stderr []byte
+}
+
+// ExitError the command is exited with non zero code
+type ExitError struct {
+	Code int
+	// Stderr the excerpt of the redacted stderr
+	Stderr string
+}
+
+func (e *ExitError) Error() string {
+	if e.Stderr == "" {
+		return fmt.Sprintf("exited with code %d", e.Code)
+	}
+	return fmt.Sprintf("exited with code %d: %s", e.Code, e.Stderr)
+}
+
+// argv the executable and it's arguments of the command
+func (c Command) argv() (string, []string) {
+	if c.Script == "" {
+		return c.Path, c.Args
+	}
+	interpreter := c.Interpreter
+	if len(interpreter) == 0 {
+		interpreter = DefaultInterpreter
+	}
+	args := append(append(append([]string{}, interpreter[1:]...), c.Script), c.Args...)
+	return interpreter[0], args
+}
+
+// environ the env vars of the command, the inherited one are set first
+func (c Command) environ() (env []string) {
+	for _, name := range c.InheritEnv {
+		if value, ok := os.LookupEnv(name); ok {
+			env = append(env, fmt.Sprintf("%s=%s", name, value))
+		}
+	}
+	for k, v := range c.Env {
+		env = append(env, fmt.Sprintf("%s=%s", k, v))
+	}
+	return env
+}
+
+// redact mask all of the secrets in the content
+func (c Command) redact(content []byte) []byte {
+	for _, secret := range c.Redact {
+		if secret != "" {
+			content = bytes.ReplaceAll(content, []byte(secret), []byte(redactedMark))
+		}
+	}
+	return content
+}
+
 // SHExecutor implements shell interface
 type SHExecutor struct{}
 
 // Exec executing shell command, also the passing environment variables to the executor
 func (s SHExecutor) Exec(command string, envVars map[string]string) (results []byte, err error) {
-	cmd := exec.Command(command)
-	for k, v := range envVars {
-		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", k, v))
+	result, err := s.Run(context.Background(), Command{Path: command, Env: envVars})
+	if err != nil {
+		return nil, err
 	}
 
-	output, err := cmd.Output()
+	return result.Stdout, nil
+}
 
-	if err != nil {
-		return nil, err
+// Run executing the command with it's options, the outputs are redacted and the non zero exit code is returned as ExitError
+func (s SHExecutor) Run(ctx context.Context, command Command) (*Result, error) {
+	if command.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, command.Timeout)
+		defer cancel()
 	}
 
-	return output, nil
+	name, args := command.argv()
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Env = command.environ()
+	cmd.Dir = command.WorkDir
+	cmd.WaitDelay = waitDelay
+	if command.Stdin != "" {
+		cmd.Stdin = strings.NewReader(command.Stdin)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	result := &Result{
+		Stdout: command.redact(stdout.Bytes()),
+		Stderr: command.redact(stderr.Bytes()),
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return result, fmt.Errorf("%w after %s", ErrTimeout, command.Timeout)
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		excerpt := strings.TrimSpace(string(result.Stderr))
+		if len(excerpt) > stderrExcerptLen {
+			excerpt = "..." + excerpt[len(excerpt)-stderrExcerptLen:]
+		}
+		return result, &ExitError{Code: exitErr.ExitCode(), Stderr: excerpt}
+	} else if err != nil {
+		return result, err
+	}
+
+	return result, nil
 }
 
-// FileMustExists check whether the file is exists or not
+// FileMustExists check whether the file is exists or not, the one without path separator is looked up in PATH
 func (s SHExecutor) FileMustExists(filePath string) error {
+	if !strings.Contains(filePath, string(os.PathSeparator)) {
+		_, err := exec.LookPath(filePath)
+		return err
+	}
 	_, err := os.Stat(filePath)
 	return err
 }
@@ -1,8 +1,10 @@
 package shell_test
 
 import (
+	"context"
 	"os"
 	"testing"
+	"time"
 
 	s "github.com/iomarmochtar/gitlab-token-updater/pkg/shell"
 	t_helper "github.com/iomarmochtar/gitlab-token-updater/test"
@@ -24,6 +26,75 @@ func TestSHExecutor_Exec(t *testing.T) {
 	assert.True(t, os.IsNotExist(err))
 }
 
+func TestSHExecutor_Run(t *testing.T) {
+	sh := s.SHExecutor{}
+	testCases := map[string]struct {
+		command        s.Command
+		envs           map[string]string
+		expectedStdout string
+		expectedStderr string
+		expectedErr    string
+	}{
+		"executable with args": {
+			command:        s.Command{Path: "echo", Args: []string{"-n", "hello", "world"}, InheritEnv: []string{"PATH"}},
+			expectedStdout: "hello world",
+		},
+		"inline script with the default interpreter and args": {
+			command:        s.Command{Script: `echo -n "$1 ${GL_NEW_TOKEN}"`, Args: []string{"script", "first"}, Env: map[string]string{"GL_NEW_TOKEN": "glpat-abc"}},
+			expectedStdout: "first glpat-abc",
+		},
+		"inline script with the custom interpreter, workdir and stdin": {
+			command:        s.Command{Script: `read token; echo -n "$(pwd) $token"`, Interpreter: []string{"/bin/sh", "-c"}, WorkDir: "/", Stdin: "glpat-abc\n"},
+			expectedStdout: "/ glpat-abc",
+		},
+		"inherit the allowed env vars only": {
+			command:        s.Command{Script: `echo -n "${INHERITED}-${NOT_INHERITED}-${OVERRIDDEN}"`, InheritEnv: []string{"INHERITED", "OVERRIDDEN"}, Env: map[string]string{"OVERRIDDEN": "new"}},
+			envs:           map[string]string{"INHERITED": "yes", "NOT_INHERITED": "no", "OVERRIDDEN": "old"},
+			expectedStdout: "yes--new",
+		},
+		"non zero exit code with the redacted stderr": {
+			command:        s.Command{Script: `echo "invalid token glpat-abc" >&2; exit 3`, Redact: []string{"glpat-abc"}},
+			expectedStderr: "invalid token [REDACTED]\n",
+			expectedErr:    "exited with code 3: invalid token [REDACTED]",
+		},
+		"timeout": {
+			command:     s.Command{Script: "exec sleep 5", Timeout: 50 * time.Millisecond},
+			expectedErr: "command execution timeout after 50ms",
+		},
+	}
+
+	for title, tc := range testCases {
+		helperTestSetEnv(t, title, tc.envs, func(t *testing.T) {
+			result, err := sh.Run(context.Background(), tc.command)
+			if tc.expectedErr != "" {
+				assert.EqualError(t, err, tc.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.expectedStdout, string(result.Stdout))
+			assert.Equal(t, tc.expectedStderr, string(result.Stderr))
+		})
+	}
+
+	t.Run("exit error code", func(t *testing.T) {
+		_, err := sh.Run(context.Background(), s.Command{Script: "exit 2"})
+		var exitErr *s.ExitError
+		assert.ErrorAs(t, err, &exitErr)
+		assert.Equal(t, 2, exitErr.Code)
+		assert.EqualError(t, err, "exited with code 2")
+	})
+}
+
+// helperTestSetEnv set the env vars during the test
+func helperTestSetEnv(t *testing.T, title string, envs map[string]string, assertion func(t *testing.T)) {
+	t.Run(title, func(t *testing.T) {
+		for k, v := range envs {
+			t.Setenv(k, v)
+		}
+		assertion(t)
+	})
+}
+
 func TestSHExecutor_FileMustExists(t *testing.T) {
 	sh := s.SHExecutor{}
 	err := sh.FileMustExists(t_helper.FixturePath("sample_script.sh"))
@@ -31,4 +102,7 @@ func TestSHExecutor_FileMustExists(t *testing.T) {
 
 	err = sh.FileMustExists("/file/is/not/found")
 	assert.True(t, os.IsNotExist(err))
+
+	assert.NoError(t, sh.FileMustExists("sh"), "looked up in PATH")
+	assert.Error(t, sh.FileMustExists("not-found-executable"))
 }
@@ -0,0 +1,282 @@
+// Package vault abstracting HashiCorp Vault KV v2 API execution for updating the secret
+package vault
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+//go:generate mockgen -destination ../../test/mocks/vault/vault.go -source=vault.go
+
+const (
+	AuthMethodToken   = "token"
+	AuthMethodAppRole = "approle"
+	AuthMethodJWT     = "jwt"
+	defaultTimeout    = 30 * time.Second
+	headerToken       = "X-Vault-Token"
+	headerNamespace   = "X-Vault-Namespace"
+	envAddress        = "VAULT_ADDR"
+	envNamespace      = "VAULT_NAMESPACE"
+	envToken          = "VAULT_TOKEN"
+	envCACert         = "VAULT_CACERT"
+)
+
+var (
+	// ErrNotFound returned by Vault API for the non exists (or deleted) secret
+	ErrNotFound = errors.New("404 Not Found")
+	// ErrCASMismatch the secret is modified since it's read, the check-and-set version is not matched
+	ErrCASMismatch = errors.New("check-and-set parameter did not match the current version")
+	// ErrMissingAddress the Vault address is not set in the config nor VAULT_ADDR env var
+	ErrMissingAddress = errors.New("missing vault address")
+)
+
+// VaultAPI spec for the used Vault KV v2 API
+type VaultAPI interface {
+	ReadKV(mount, path string) (data map[string]any, version int, err error)
+	WriteKV(mount, path string, data map[string]any, cas int) error
+	ReadMetadata(mount, path string) (currentVersion int, err error)
+	Close() error
+}
+
+// ClientConfig the address and authentication of Vault API, the empty address, namespace, CA certificate and token are
+// taken from the same env vars as in Vault CLI (VAULT_ADDR, VAULT_NAMESPACE, VAULT_CACERT and VAULT_TOKEN)
+type ClientConfig struct {
+	Address   string
+	Namespace string
+	// CACert the path of CA certificate for verifying the Vault server
+	CACert     string
+	AuthMethod string
+	// AuthMount the mount path of auth method, default as the auth method name
+	AuthMount string
+	Token     string
+	RoleID    string
+	SecretID  string
+	// Role and JWT for the jwt auth method, e.g. Gitlab CI id_tokens
+	Role string
+	JWT  string
+}
+
+// Vault implement VaultAPI interface through it's REST API
+type Vault struct {
+	address   string
+	namespace string
+	token     string
+	// revocable the token is issued by the login, so it's revoked when it's no longer used
+	revocable bool
+	client    *http.Client
+}
+
+// errorResponse the error response of Vault API
+type errorResponse struct {
+	Errors []string `json:"errors"`
+}
+
+// request execute the API request, the response is decoded to result if it's set
+func (v Vault) request(method, path string, body, result any) error {
+	var reqBody io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequest(method, fmt.Sprintf("%s/v1/%s", v.address, path), reqBody)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if v.token != "" {
+		req.Header.Set(headerToken, v.token)
+	}
+	if v.namespace != "" {
+		req.Header.Set(headerNamespace, v.namespace)
+	}
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	} else if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		errResp := errorResponse{}
+		_ = json.NewDecoder(resp.Body).Decode(&errResp)
+		message := strings.Join(errResp.Errors, ", ")
+		if strings.Contains(message, ErrCASMismatch.Error()) {
+			return ErrCASMismatch
+		}
+		return fmt.Errorf("%s /v1/%s: %d %s", method, path, resp.StatusCode, message)
+	}
+
+	if result == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+// kvPath the API path of KV v2 secret, the kind is data or metadata
+func kvPath(mount, kind, path string) string {
+	return fmt.Sprintf("%s/%s/%s", strings.Trim(mount, "/"), kind, strings.Trim(path, "/"))
+}
+
+// ReadKV read the latest version of the secret along with it's version number
+func (v Vault) ReadKV(mount, path string) (map[string]any, int, error) {
+	result := struct {
+		Data struct {
+			Data     map[string]any `json:"data"`
+			Metadata struct {
+				Version int `json:"version"`
+			} `json:"metadata"`
+		} `json:"data"`
+	}{}
+	if err := v.request(http.MethodGet, kvPath(mount, "data", path), nil, &result); err != nil {
+		return nil, 0, err
+	}
+	return result.Data.Data, result.Data.Metadata.Version, nil
+}
+
+// WriteKV write the whole secret as a new version, it's rejected with ErrCASMismatch if the current version is not the cas.
+// The cas 0 is only allowing the write if the secret is not exists
+func (v Vault) WriteKV(mount, path string, data map[string]any, cas int) error {
+	body := map[string]any{
+		"options": map[string]int{"cas": cas},
+		"data":    data,
+	}
+	return v.request(http.MethodPost, kvPath(mount, "data", path), body, nil)
+}
+
+// ReadMetadata read the current version number in the secret metadata
+func (v Vault) ReadMetadata(mount, path string) (int, error) {
+	result := struct {
+		Data struct {
+			CurrentVersion int `json:"current_version"`
+		} `json:"data"`
+	}{}
+	err := v.request(http.MethodGet, kvPath(mount, "metadata", path), nil, &result)
+	return result.Data.CurrentVersion, err
+}
+
+// login authenticate by the auth method then use the issued client token
+func (v *Vault) login(cfg ClientConfig) error {
+	var body map[string]string
+	switch cfg.AuthMethod {
+	case "", AuthMethodToken:
+		v.token = cfg.Token
+		return nil
+	case AuthMethodAppRole:
+		body = map[string]string{"role_id": cfg.RoleID, "secret_id": cfg.SecretID}
+	case AuthMethodJWT:
+		body = map[string]string{"role": cfg.Role, "jwt": cfg.JWT}
+	default:
+		return fmt.Errorf("unsupported vault auth method %s", cfg.AuthMethod)
+	}
+
+	authMount := cfg.AuthMount
+	if authMount == "" {
+		authMount = cfg.AuthMethod
+	}
+	result := struct {
+		Auth struct {
+			ClientToken string `json:"client_token"`
+		} `json:"auth"`
+	}{}
+	if err := v.request(http.MethodPost, fmt.Sprintf("auth/%s/login", strings.Trim(authMount, "/")), body, &result); err != nil {
+		return fmt.Errorf("error in vault %s login: %w", cfg.AuthMethod, err)
+	}
+	v.token = result.Auth.ClientToken
+	v.revocable = true
+	return nil
+}
+
+// Close revoke the client token that is issued by the login, otherwise it's live until it's TTL is expired.
+// The static token of token auth method is never revoked
+func (v *Vault) Close() error {
+	if !v.revocable {
+		return nil
+	}
+	if err := v.request(http.MethodPost, "auth/token/revoke-self", nil, nil); err != nil {
+		return fmt.Errorf("error in revoking vault client token: %w", err)
+	}
+	v.token, v.revocable = "", false
+	return nil
+}
+
+// tlsConfig the TLS config by using the CA certificate if it's set
+func tlsConfig(caCert string) (*tls.Config, error) {
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if caCert == "" {
+		return config, nil
+	}
+	caData, err := os.ReadFile(caCert)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caData) {
+		return nil, fmt.Errorf("invalid vault CA certificate %s", caCert)
+	}
+	config.RootCAs = pool
+	return config, nil
+}
+
+// withEnvDefaults fill the empty config by it's env var
+func (c ClientConfig) withEnvDefaults() ClientConfig {
+	for field, env := range map[*string]string{
+		&c.Address:   envAddress,
+		&c.Namespace: envNamespace,
+		&c.CACert:    envCACert,
+	} {
+		if *field == "" {
+			*field = os.Getenv(env)
+		}
+	}
+	if c.Token == "" && (c.AuthMethod == "" || c.AuthMethod == AuthMethodToken) {
+		c.Token = os.Getenv(envToken)
+	}
+	return c
+}
+
+// NewVaultAPI returning the authenticated Vault API object
+func NewVaultAPI(cfg ClientConfig) (VaultAPI, error) {
+	cfg = cfg.withEnvDefaults()
+	if cfg.Address == "" {
+		return nil, ErrMissingAddress
+	}
+	if _, err := url.Parse(cfg.Address); err != nil {
+		return nil, err
+	}
+
+	tlsCfg, err := tlsConfig(cfg.CACert)
+	if err != nil {
+		return nil, err
+	}
+
+	v := &Vault{
+		address:   strings.TrimSuffix(cfg.Address, "/"),
+		namespace: cfg.Namespace,
+		client: &http.Client{
+			Timeout:   defaultTimeout,
+			Transport: &http.Transport{TLSClientConfig: tlsCfg, Proxy: http.ProxyFromEnvironment},
+		},
+	}
+	if err = v.login(cfg); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
@@ -0,0 +1,177 @@
+package vault_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	v "github.com/iomarmochtar/gitlab-token-updater/pkg/vault"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordedRequest struct {
+	method    string
+	path      string
+	token     string
+	namespace string
+	body      map[string]any
+}
+
+// fakeVaultServer serving the prepared responses (status code and body) by method and path, all of the requests are recorded
+func fakeVaultServer(t *testing.T, responses map[string]struct {
+	code int
+	body string
+},
+) (*httptest.Server, *[]recordedRequest) {
+	requests := []recordedRequest{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := recordedRequest{method: r.Method, path: r.URL.Path, token: r.Header.Get("X-Vault-Token"), namespace: r.Header.Get("X-Vault-Namespace")}
+		if content, _ := io.ReadAll(r.Body); len(content) > 0 {
+			require.NoError(t, json.Unmarshal(content, &req.body))
+		}
+		requests = append(requests, req)
+
+		resp, ok := responses[r.Method+" "+r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"errors":[]}`))
+			return
+		}
+		w.WriteHeader(resp.code)
+		_, _ = w.Write([]byte(resp.body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &requests
+}
+
+func TestVault_KV(t *testing.T) {
+	srv, requests := fakeVaultServer(t, map[string]struct {
+		code int
+		body string
+	}{
+		"GET /v1/kv/data/app/gitlab":     {200, `{"data":{"data":{"token":"old","user":"bot"},"metadata":{"version":3}}}`},
+		"POST /v1/kv/data/app/gitlab":    {200, `{"data":{"version":4}}`},
+		"GET /v1/kv/metadata/app/gitlab": {200, `{"data":{"current_version":3}}`},
+		"POST /v1/kv/data/app/conflict":  {400, `{"errors":["check-and-set parameter did not match the current version"]}`},
+		"POST /v1/kv/data/app/denied":    {403, `{"errors":["permission denied"]}`},
+	})
+	api, err := v.NewVaultAPI(v.ClientConfig{Address: srv.URL + "/", Namespace: "team", Token: "s.token"})
+	require.NoError(t, err)
+
+	data, version, err := api.ReadKV("/kv/", "app/gitlab")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]any{"token": "old", "user": "bot"}, data)
+	assert.Equal(t, 3, version)
+
+	_, _, err = api.ReadKV("kv", "app/missing")
+	assert.ErrorIs(t, err, v.ErrNotFound)
+
+	currentVersion, err := api.ReadMetadata("kv", "app/gitlab")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, currentVersion)
+
+	assert.NoError(t, api.WriteKV("kv", "app/gitlab", map[string]any{"token": "new", "user": "bot"}, 3))
+	assert.ErrorIs(t, api.WriteKV("kv", "app/conflict", map[string]any{}, 1), v.ErrCASMismatch)
+	assert.EqualError(t, api.WriteKV("kv", "app/denied", map[string]any{}, 1), "POST /v1/kv/data/app/denied: 403 permission denied")
+
+	reqs := *requests
+	require.Len(t, reqs, 6)
+	assert.Equal(t, "s.token", reqs[0].token)
+	assert.Equal(t, "team", reqs[0].namespace)
+	assert.Equal(t, map[string]any{
+		"options": map[string]any{"cas": float64(3)},
+		"data":    map[string]any{"token": "new", "user": "bot"},
+	}, reqs[3].body)
+}
+
+func TestNewVaultAPI_EnvDefaults(t *testing.T) {
+	srv, requests := fakeVaultServer(t, map[string]struct {
+		code int
+		body string
+	}{
+		"GET /v1/kv/metadata/app/gitlab": {200, `{"data":{"current_version":1}}`},
+	})
+	t.Setenv("VAULT_ADDR", srv.URL)
+	t.Setenv("VAULT_NAMESPACE", "team")
+	t.Setenv("VAULT_TOKEN", "s.from-env")
+	t.Setenv("VAULT_CACERT", "")
+
+	api, err := v.NewVaultAPI(v.ClientConfig{})
+	require.NoError(t, err)
+	_, err = api.ReadMetadata("kv", "app/gitlab")
+	assert.NoError(t, err)
+	// the static token is not revoked
+	assert.NoError(t, api.Close())
+
+	reqs := *requests
+	require.Len(t, reqs, 1)
+	assert.Equal(t, "s.from-env", reqs[0].token)
+	assert.Equal(t, "team", reqs[0].namespace)
+}
+
+func TestNewVaultAPI_Login(t *testing.T) {
+	testCases := map[string]struct {
+		config       v.ClientConfig
+		loginPath    string
+		expectedBody map[string]any
+	}{
+		"approle": {
+			config:       v.ClientConfig{AuthMethod: v.AuthMethodAppRole, RoleID: "role-id", SecretID: "secret-id"},
+			loginPath:    "/v1/auth/approle/login",
+			expectedBody: map[string]any{"role_id": "role-id", "secret_id": "secret-id"},
+		},
+		"jwt with custom auth mount": {
+			config:       v.ClientConfig{AuthMethod: v.AuthMethodJWT, AuthMount: "gitlab", Role: "rotator", JWT: "eyJ"},
+			loginPath:    "/v1/auth/gitlab/login",
+			expectedBody: map[string]any{"role": "rotator", "jwt": "eyJ"},
+		},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			srv, requests := fakeVaultServer(t, map[string]struct {
+				code int
+				body string
+			}{
+				"POST " + tc.loginPath:            {200, `{"auth":{"client_token":"s.issued"}}`},
+				"GET /v1/kv/metadata/app/gitlab":  {200, `{"data":{"current_version":1}}`},
+				"POST /v1/auth/token/revoke-self": {204, ""},
+			})
+			tc.config.Address = srv.URL
+			api, err := v.NewVaultAPI(tc.config)
+			require.NoError(t, err)
+
+			_, err = api.ReadMetadata("kv", "app/gitlab")
+			assert.NoError(t, err)
+			assert.NoError(t, api.Close())
+			assert.NoError(t, api.Close())
+
+			// the issued token is revoked only once
+			reqs := *requests
+			require.Len(t, reqs, 3)
+			assert.Equal(t, tc.expectedBody, reqs[0].body)
+			assert.Equal(t, "s.issued", reqs[1].token)
+			assert.Equal(t, recordedRequest{method: http.MethodPost, path: "/v1/auth/token/revoke-self", token: "s.issued"}, reqs[2])
+		})
+	}
+
+	t.Run("login failed", func(t *testing.T) {
+		srv, _ := fakeVaultServer(t, map[string]struct {
+			code int
+			body string
+		}{
+			"POST /v1/auth/approle/login": {400, `{"errors":["invalid role or secret ID"]}`},
+		})
+		_, err := v.NewVaultAPI(v.ClientConfig{Address: srv.URL, AuthMethod: v.AuthMethodAppRole})
+		assert.EqualError(t, err, "error in vault approle login: POST /v1/auth/approle/login: 400 invalid role or secret ID")
+	})
+
+	t.Run("missing address", func(t *testing.T) {
+		t.Setenv("VAULT_ADDR", "")
+		_, err := v.NewVaultAPI(v.ClientConfig{})
+		assert.ErrorIs(t, err, v.ErrMissingAddress)
+	})
+}
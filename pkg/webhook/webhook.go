@@ -0,0 +1,118 @@
+// Package webhook sending the HTTP request of the hooks, included with it's TLS and authentication options
+package webhook
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultTimeout the request timeout if it's not set
+	DefaultTimeout = 30 * time.Second
+	// maxResponseBody the maximum read response body, the rest is discarded
+	maxResponseBody = 1 << 20
+)
+
+// TLS the CA certificate for verifying the server and the client certificate, the system one is used if the CA is not set
+type TLS struct {
+	CACert     string
+	ClientCert string
+	ClientKey  string
+}
+
+// Request the HTTP request along with it's options
+type Request struct {
+	URL     string
+	Method  string
+	Headers map[string]string
+	Body    string
+	// BasicUsername and BasicPassword for basic auth, it's exclusive with BearerToken
+	BasicUsername string
+	BasicPassword string
+	BearerToken   string
+	TLS           TLS
+	Timeout       time.Duration
+}
+
+// Response the status code and body of HTTP response
+type Response struct {
+	StatusCode int
+	Body       []byte
+}
+
+// config the TLS config of the request
+func (t TLS) config() (*tls.Config, error) {
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if t.CACert != "" {
+		caData, err := os.ReadFile(t.CACert)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caData) {
+			return nil, fmt.Errorf("invalid CA certificate %s", t.CACert)
+		}
+		config.RootCAs = pool
+	}
+	if t.ClientCert != "" {
+		cert, err := tls.LoadX509KeyPair(t.ClientCert, t.ClientKey)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+// Send sending the request, the non 2xx status code is not an error so the caller can check it by it's own expectation
+func Send(ctx context.Context, r Request) (*Response, error) {
+	tlsConfig, err := r.TLS.config()
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := r.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	client := &http.Client{
+		Timeout:   timeout,
+		Transport: &http.Transport{TLSClientConfig: tlsConfig, Proxy: http.ProxyFromEnvironment},
+	}
+
+	var body io.Reader
+	if r.Body != "" {
+		body = strings.NewReader(r.Body)
+	}
+	req, err := http.NewRequestWithContext(ctx, r.Method, r.URL, body)
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range r.Headers {
+		req.Header.Set(key, value)
+	}
+	if r.BearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+r.BearerToken)
+	} else if r.BasicUsername != "" || r.BasicPassword != "" {
+		req.SetBasicAuth(r.BasicUsername, r.BasicPassword)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+	if err != nil {
+		return nil, err
+	}
+	return &Response{StatusCode: resp.StatusCode, Body: respBody}, nil
+}
@@ -0,0 +1,80 @@
+package webhook_test
+
+import (
+	"context"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	w "github.com/iomarmochtar/gitlab-token-updater/pkg/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSend(t *testing.T) {
+	var received *http.Request
+	var receivedBody string
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		content, _ := io.ReadAll(r.Body)
+		received, receivedBody = r, string(content)
+		rw.WriteHeader(http.StatusAccepted)
+		_, _ = rw.Write([]byte(`{"status":"ok"}`))
+	}))
+	defer srv.Close()
+
+	caCert := filepath.Join(t.TempDir(), "ca.pem")
+	caContent := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	require.NoError(t, os.WriteFile(caCert, caContent, 0o600))
+
+	testCases := map[string]struct {
+		request      w.Request
+		expectedAuth string
+	}{
+		"bearer token": {
+			request: w.Request{
+				URL: srv.URL + "/credentials", Method: http.MethodPut, Body: `{"token":"glpat-abc"}`,
+				Headers: map[string]string{"Content-Type": "application/json"}, BearerToken: "secret",
+				TLS: w.TLS{CACert: caCert},
+			},
+			expectedAuth: "Bearer secret",
+		},
+		"basic auth": {
+			request: w.Request{
+				URL: srv.URL + "/credentials", Method: http.MethodPut, Body: `{"token":"glpat-abc"}`,
+				Headers: map[string]string{"Content-Type": "application/json"}, BasicUsername: "user", BasicPassword: "pass",
+				TLS: w.TLS{CACert: caCert}, Timeout: time.Second,
+			},
+			expectedAuth: "Basic dXNlcjpwYXNz",
+		},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			resp, err := w.Send(context.Background(), tc.request)
+			require.NoError(t, err)
+			assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+			assert.Equal(t, `{"status":"ok"}`, string(resp.Body))
+
+			assert.Equal(t, http.MethodPut, received.Method)
+			assert.Equal(t, "/credentials", received.URL.Path)
+			assert.Equal(t, "application/json", received.Header.Get("Content-Type"))
+			assert.Equal(t, tc.expectedAuth, received.Header.Get("Authorization"))
+			assert.Equal(t, `{"token":"glpat-abc"}`, receivedBody)
+		})
+	}
+
+	t.Run("unknown certificate authority", func(t *testing.T) {
+		_, err := w.Send(context.Background(), w.Request{URL: srv.URL, Method: http.MethodHead})
+		assert.ErrorContains(t, err, "certificate")
+	})
+
+	t.Run("invalid CA certificate", func(t *testing.T) {
+		_, err := w.Send(context.Background(), w.Request{URL: srv.URL, Method: http.MethodHead, TLS: w.TLS{CACert: "/file/is/not/found"}})
+		assert.True(t, os.IsNotExist(err))
+	})
+}
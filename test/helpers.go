@@ -136,3 +136,8 @@ func ReadFixture(locs ...string) []byte {
 	}
 	return data
 }
+
+// Ptr return the pointer of given value
+func Ptr[T any](v T) *T {
+	return &v
+}
@@ -10,6 +10,7 @@
 package mock_gitlab
 
 import (
+	context "context"
 	reflect "reflect"
 	time "time"
 
@@ -55,34 +56,136 @@ func (mr *MockGitlabAPIMockRecorder) Auth(token any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Auth", reflect.TypeOf((*MockGitlabAPI)(nil).Auth), token)
 }
 
+// CreateGroupToken mocks base method.
+func (m *MockGitlabAPI) CreateGroupToken(path, name string, scopes []string, accessLevel int, expiredAt time.Time) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateGroupToken", path, name, scopes, accessLevel, expiredAt)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateGroupToken indicates an expected call of CreateGroupToken.
+func (mr *MockGitlabAPIMockRecorder) CreateGroupToken(path, name, scopes, accessLevel, expiredAt any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateGroupToken", reflect.TypeOf((*MockGitlabAPI)(nil).CreateGroupToken), path, name, scopes, accessLevel, expiredAt)
+}
+
+// CreateGroupVar mocks base method.
+func (m *MockGitlabAPI) CreateGroupVar(path, varName, value string, attrs gitlab.GitlabCICDVarAttrs) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateGroupVar", path, varName, value, attrs)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateGroupVar indicates an expected call of CreateGroupVar.
+func (mr *MockGitlabAPIMockRecorder) CreateGroupVar(path, varName, value, attrs any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateGroupVar", reflect.TypeOf((*MockGitlabAPI)(nil).CreateGroupVar), path, varName, value, attrs)
+}
+
+// CreateInstanceVar mocks base method.
+func (m *MockGitlabAPI) CreateInstanceVar(varName, value string, attrs gitlab.GitlabCICDVarAttrs) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateInstanceVar", varName, value, attrs)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateInstanceVar indicates an expected call of CreateInstanceVar.
+func (mr *MockGitlabAPIMockRecorder) CreateInstanceVar(varName, value, attrs any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateInstanceVar", reflect.TypeOf((*MockGitlabAPI)(nil).CreateInstanceVar), varName, value, attrs)
+}
+
+// CreatePersonalToken mocks base method.
+func (m *MockGitlabAPI) CreatePersonalToken(name string, scopes []string, expiredAt time.Time) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreatePersonalToken", name, scopes, expiredAt)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreatePersonalToken indicates an expected call of CreatePersonalToken.
+func (mr *MockGitlabAPIMockRecorder) CreatePersonalToken(name, scopes, expiredAt any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePersonalToken", reflect.TypeOf((*MockGitlabAPI)(nil).CreatePersonalToken), name, scopes, expiredAt)
+}
+
+// CreateRepoToken mocks base method.
+func (m *MockGitlabAPI) CreateRepoToken(path, name string, scopes []string, accessLevel int, expiredAt time.Time) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateRepoToken", path, name, scopes, accessLevel, expiredAt)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateRepoToken indicates an expected call of CreateRepoToken.
+func (mr *MockGitlabAPIMockRecorder) CreateRepoToken(path, name, scopes, accessLevel, expiredAt any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateRepoToken", reflect.TypeOf((*MockGitlabAPI)(nil).CreateRepoToken), path, name, scopes, accessLevel, expiredAt)
+}
+
+// CreateRepoVar mocks base method.
+func (m *MockGitlabAPI) CreateRepoVar(path, varName, value string, attrs gitlab.GitlabCICDVarAttrs) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateRepoVar", path, varName, value, attrs)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateRepoVar indicates an expected call of CreateRepoVar.
+func (mr *MockGitlabAPIMockRecorder) CreateRepoVar(path, varName, value, attrs any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateRepoVar", reflect.TypeOf((*MockGitlabAPI)(nil).CreateRepoVar), path, varName, value, attrs)
+}
+
 // GetGroupVar mocks base method.
-func (m *MockGitlabAPI) GetGroupVar(path, varName string) (*gitlab.GitlabCICDVar, error) {
+func (m *MockGitlabAPI) GetGroupVar(path, varName, envScope string) (*gitlab.GitlabCICDVar, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetGroupVar", path, varName)
+	ret := m.ctrl.Call(m, "GetGroupVar", path, varName, envScope)
 	ret0, _ := ret[0].(*gitlab.GitlabCICDVar)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetGroupVar indicates an expected call of GetGroupVar.
-func (mr *MockGitlabAPIMockRecorder) GetGroupVar(path, varName any) *gomock.Call {
+func (mr *MockGitlabAPIMockRecorder) GetGroupVar(path, varName, envScope any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetGroupVar", reflect.TypeOf((*MockGitlabAPI)(nil).GetGroupVar), path, varName)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetGroupVar", reflect.TypeOf((*MockGitlabAPI)(nil).GetGroupVar), path, varName, envScope)
+}
+
+// GetInstanceVar mocks base method.
+func (m *MockGitlabAPI) GetInstanceVar(varName string) (*gitlab.GitlabCICDVar, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetInstanceVar", varName)
+	ret0, _ := ret[0].(*gitlab.GitlabCICDVar)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetInstanceVar indicates an expected call of GetInstanceVar.
+func (mr *MockGitlabAPIMockRecorder) GetInstanceVar(varName any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetInstanceVar", reflect.TypeOf((*MockGitlabAPI)(nil).GetInstanceVar), varName)
 }
 
 // GetRepoVar mocks base method.
-func (m *MockGitlabAPI) GetRepoVar(path, varName string) (*gitlab.GitlabCICDVar, error) {
+func (m *MockGitlabAPI) GetRepoVar(path, varName, envScope string) (*gitlab.GitlabCICDVar, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetRepoVar", path, varName)
+	ret := m.ctrl.Call(m, "GetRepoVar", path, varName, envScope)
 	ret0, _ := ret[0].(*gitlab.GitlabCICDVar)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetRepoVar indicates an expected call of GetRepoVar.
-func (mr *MockGitlabAPIMockRecorder) GetRepoVar(path, varName any) *gomock.Call {
+func (mr *MockGitlabAPIMockRecorder) GetRepoVar(path, varName, envScope any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRepoVar", reflect.TypeOf((*MockGitlabAPI)(nil).GetRepoVar), path, varName)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRepoVar", reflect.TypeOf((*MockGitlabAPI)(nil).GetRepoVar), path, varName, envScope)
 }
 
 // InitGitlab mocks base method.
@@ -115,6 +218,36 @@ func (mr *MockGitlabAPIMockRecorder) ListGroupAccessToken(path any) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListGroupAccessToken", reflect.TypeOf((*MockGitlabAPI)(nil).ListGroupAccessToken), path)
 }
 
+// ListGroupProjects mocks base method.
+func (m *MockGitlabAPI) ListGroupProjects(path string, includeSubGroups bool) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListGroupProjects", path, includeSubGroups)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListGroupProjects indicates an expected call of ListGroupProjects.
+func (mr *MockGitlabAPIMockRecorder) ListGroupProjects(path, includeSubGroups any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListGroupProjects", reflect.TypeOf((*MockGitlabAPI)(nil).ListGroupProjects), path, includeSubGroups)
+}
+
+// ListGroupVars mocks base method.
+func (m *MockGitlabAPI) ListGroupVars(path string) ([]gitlab.GitlabCICDVar, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListGroupVars", path)
+	ret0, _ := ret[0].([]gitlab.GitlabCICDVar)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListGroupVars indicates an expected call of ListGroupVars.
+func (mr *MockGitlabAPIMockRecorder) ListGroupVars(path any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListGroupVars", reflect.TypeOf((*MockGitlabAPI)(nil).ListGroupVars), path)
+}
+
 // ListPersonalAccessToken mocks base method.
 func (m *MockGitlabAPI) ListPersonalAccessToken() ([]gitlab.GitlabAccessToken, error) {
 	m.ctrl.T.Helper()
@@ -145,6 +278,78 @@ func (mr *MockGitlabAPIMockRecorder) ListRepoAccessToken(path any) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListRepoAccessToken", reflect.TypeOf((*MockGitlabAPI)(nil).ListRepoAccessToken), path)
 }
 
+// ListRepoVars mocks base method.
+func (m *MockGitlabAPI) ListRepoVars(path string) ([]gitlab.GitlabCICDVar, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListRepoVars", path)
+	ret0, _ := ret[0].([]gitlab.GitlabCICDVar)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListRepoVars indicates an expected call of ListRepoVars.
+func (mr *MockGitlabAPIMockRecorder) ListRepoVars(path any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListRepoVars", reflect.TypeOf((*MockGitlabAPI)(nil).ListRepoVars), path)
+}
+
+// ListSubGroups mocks base method.
+func (m *MockGitlabAPI) ListSubGroups(path string, recursive bool) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListSubGroups", path, recursive)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListSubGroups indicates an expected call of ListSubGroups.
+func (mr *MockGitlabAPIMockRecorder) ListSubGroups(path, recursive any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListSubGroups", reflect.TypeOf((*MockGitlabAPI)(nil).ListSubGroups), path, recursive)
+}
+
+// RevokeGroupToken mocks base method.
+func (m *MockGitlabAPI) RevokeGroupToken(path string, tokenID int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeGroupToken", path, tokenID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeGroupToken indicates an expected call of RevokeGroupToken.
+func (mr *MockGitlabAPIMockRecorder) RevokeGroupToken(path, tokenID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeGroupToken", reflect.TypeOf((*MockGitlabAPI)(nil).RevokeGroupToken), path, tokenID)
+}
+
+// RevokePersonalToken mocks base method.
+func (m *MockGitlabAPI) RevokePersonalToken(tokenID int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokePersonalToken", tokenID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokePersonalToken indicates an expected call of RevokePersonalToken.
+func (mr *MockGitlabAPIMockRecorder) RevokePersonalToken(tokenID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokePersonalToken", reflect.TypeOf((*MockGitlabAPI)(nil).RevokePersonalToken), tokenID)
+}
+
+// RevokeRepoToken mocks base method.
+func (m *MockGitlabAPI) RevokeRepoToken(path string, tokenID int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeRepoToken", path, tokenID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeRepoToken indicates an expected call of RevokeRepoToken.
+func (mr *MockGitlabAPIMockRecorder) RevokeRepoToken(path, tokenID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeRepoToken", reflect.TypeOf((*MockGitlabAPI)(nil).RevokeRepoToken), path, tokenID)
+}
+
 // RotateGroupToken mocks base method.
 func (m *MockGitlabAPI) RotateGroupToken(path string, tokenID int, expiredAt time.Time) (string, error) {
 	m.ctrl.T.Helper()
@@ -191,29 +396,58 @@ func (mr *MockGitlabAPIMockRecorder) RotateRepoToken(path, tokenID, expiredAt an
 }
 
 // UpdateGroupVar mocks base method.
-func (m *MockGitlabAPI) UpdateGroupVar(path, varName, value string) error {
+func (m *MockGitlabAPI) UpdateGroupVar(path, varName, envScope, value string, attrs gitlab.GitlabCICDVarAttrs) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdateGroupVar", path, varName, value)
+	ret := m.ctrl.Call(m, "UpdateGroupVar", path, varName, envScope, value, attrs)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // UpdateGroupVar indicates an expected call of UpdateGroupVar.
-func (mr *MockGitlabAPIMockRecorder) UpdateGroupVar(path, varName, value any) *gomock.Call {
+func (mr *MockGitlabAPIMockRecorder) UpdateGroupVar(path, varName, envScope, value, attrs any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateGroupVar", reflect.TypeOf((*MockGitlabAPI)(nil).UpdateGroupVar), path, varName, value)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateGroupVar", reflect.TypeOf((*MockGitlabAPI)(nil).UpdateGroupVar), path, varName, envScope, value, attrs)
+}
+
+// UpdateInstanceVar mocks base method.
+func (m *MockGitlabAPI) UpdateInstanceVar(varName, value string, attrs gitlab.GitlabCICDVarAttrs) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateInstanceVar", varName, value, attrs)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateInstanceVar indicates an expected call of UpdateInstanceVar.
+func (mr *MockGitlabAPIMockRecorder) UpdateInstanceVar(varName, value, attrs any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateInstanceVar", reflect.TypeOf((*MockGitlabAPI)(nil).UpdateInstanceVar), varName, value, attrs)
 }
 
 // UpdateRepoVar mocks base method.
-func (m *MockGitlabAPI) UpdateRepoVar(path, varName, value string) error {
+func (m *MockGitlabAPI) UpdateRepoVar(path, varName, envScope, value string, attrs gitlab.GitlabCICDVarAttrs) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdateRepoVar", path, varName, value)
+	ret := m.ctrl.Call(m, "UpdateRepoVar", path, varName, envScope, value, attrs)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // UpdateRepoVar indicates an expected call of UpdateRepoVar.
-func (mr *MockGitlabAPIMockRecorder) UpdateRepoVar(path, varName, value any) *gomock.Call {
+func (mr *MockGitlabAPIMockRecorder) UpdateRepoVar(path, varName, envScope, value, attrs any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateRepoVar", reflect.TypeOf((*MockGitlabAPI)(nil).UpdateRepoVar), path, varName, envScope, value, attrs)
+}
+
+// WithContext mocks base method.
+func (m *MockGitlabAPI) WithContext(ctx context.Context) (gitlab.GitlabAPI, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WithContext", ctx)
+	ret0, _ := ret[0].(gitlab.GitlabAPI)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// WithContext indicates an expected call of WithContext.
+func (mr *MockGitlabAPIMockRecorder) WithContext(ctx any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateRepoVar", reflect.TypeOf((*MockGitlabAPI)(nil).UpdateRepoVar), path, varName, value)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WithContext", reflect.TypeOf((*MockGitlabAPI)(nil).WithContext), ctx)
 }
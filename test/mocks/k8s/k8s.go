@@ -0,0 +1,113 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: k8s.go
+//
+// Generated by this command:
+//
+//	mockgen -destination ../../test/mocks/k8s/k8s.go -source=k8s.go
+//
+
+// Package mock_k8s is a generated GoMock package.
+package mock_k8s
+
+import (
+	reflect "reflect"
+	time "time"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockKubernetesAPI is a mock of KubernetesAPI interface.
+type MockKubernetesAPI struct {
+	ctrl     *gomock.Controller
+	recorder *MockKubernetesAPIMockRecorder
+	isgomock struct{}
+}
+
+// MockKubernetesAPIMockRecorder is the mock recorder for MockKubernetesAPI.
+type MockKubernetesAPIMockRecorder struct {
+	mock *MockKubernetesAPI
+}
+
+// NewMockKubernetesAPI creates a new mock instance.
+func NewMockKubernetesAPI(ctrl *gomock.Controller) *MockKubernetesAPI {
+	mock := &MockKubernetesAPI{ctrl: ctrl}
+	mock.recorder = &MockKubernetesAPIMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockKubernetesAPI) EXPECT() *MockKubernetesAPIMockRecorder {
+	return m.recorder
+}
+
+// CanI mocks base method.
+func (m *MockKubernetesAPI) CanI(verb, resource, namespace, name string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CanI", verb, resource, namespace, name)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CanI indicates an expected call of CanI.
+func (mr *MockKubernetesAPIMockRecorder) CanI(verb, resource, namespace, name any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CanI", reflect.TypeOf((*MockKubernetesAPI)(nil).CanI), verb, resource, namespace, name)
+}
+
+// CreateSecret mocks base method.
+func (m *MockKubernetesAPI) CreateSecret(namespace, name string, data map[string][]byte) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateSecret", namespace, name, data)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateSecret indicates an expected call of CreateSecret.
+func (mr *MockKubernetesAPIMockRecorder) CreateSecret(namespace, name, data any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateSecret", reflect.TypeOf((*MockKubernetesAPI)(nil).CreateSecret), namespace, name, data)
+}
+
+// GetSecret mocks base method.
+func (m *MockKubernetesAPI) GetSecret(namespace, name string) (map[string][]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSecret", namespace, name)
+	ret0, _ := ret[0].(map[string][]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSecret indicates an expected call of GetSecret.
+func (mr *MockKubernetesAPIMockRecorder) GetSecret(namespace, name any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSecret", reflect.TypeOf((*MockKubernetesAPI)(nil).GetSecret), namespace, name)
+}
+
+// PatchSecret mocks base method.
+func (m *MockKubernetesAPI) PatchSecret(namespace, name string, data map[string][]byte) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PatchSecret", namespace, name, data)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PatchSecret indicates an expected call of PatchSecret.
+func (mr *MockKubernetesAPIMockRecorder) PatchSecret(namespace, name, data any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PatchSecret", reflect.TypeOf((*MockKubernetesAPI)(nil).PatchSecret), namespace, name, data)
+}
+
+// RestartDeployment mocks base method.
+func (m *MockKubernetesAPI) RestartDeployment(namespace, name string, restartedAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RestartDeployment", namespace, name, restartedAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RestartDeployment indicates an expected call of RestartDeployment.
+func (mr *MockKubernetesAPIMockRecorder) RestartDeployment(namespace, name, restartedAt any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RestartDeployment", reflect.TypeOf((*MockKubernetesAPI)(nil).RestartDeployment), namespace, name, restartedAt)
+}
@@ -10,8 +10,10 @@
 package mock_shell
 
 import (
+	context "context"
 	reflect "reflect"
 
+	shell "github.com/iomarmochtar/gitlab-token-updater/pkg/shell"
 	gomock "go.uber.org/mock/gomock"
 )
 
@@ -67,3 +69,18 @@ func (mr *MockShellMockRecorder) FileMustExists(path any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FileMustExists", reflect.TypeOf((*MockShell)(nil).FileMustExists), path)
 }
+
+// Run mocks base method.
+func (m *MockShell) Run(ctx context.Context, cmd shell.Command) (*shell.Result, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Run", ctx, cmd)
+	ret0, _ := ret[0].(*shell.Result)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Run indicates an expected call of Run.
+func (mr *MockShellMockRecorder) Run(ctx, cmd any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Run", reflect.TypeOf((*MockShell)(nil).Run), ctx, cmd)
+}
@@ -0,0 +1,99 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: vault.go
+//
+// Generated by this command:
+//
+//	mockgen -destination ../../test/mocks/vault/vault.go -source=vault.go
+//
+
+// Package mock_vault is a generated GoMock package.
+package mock_vault
+
+import (
+	reflect "reflect"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockVaultAPI is a mock of VaultAPI interface.
+type MockVaultAPI struct {
+	ctrl     *gomock.Controller
+	recorder *MockVaultAPIMockRecorder
+	isgomock struct{}
+}
+
+// MockVaultAPIMockRecorder is the mock recorder for MockVaultAPI.
+type MockVaultAPIMockRecorder struct {
+	mock *MockVaultAPI
+}
+
+// NewMockVaultAPI creates a new mock instance.
+func NewMockVaultAPI(ctrl *gomock.Controller) *MockVaultAPI {
+	mock := &MockVaultAPI{ctrl: ctrl}
+	mock.recorder = &MockVaultAPIMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockVaultAPI) EXPECT() *MockVaultAPIMockRecorder {
+	return m.recorder
+}
+
+// Close mocks base method.
+func (m *MockVaultAPI) Close() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Close")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Close indicates an expected call of Close.
+func (mr *MockVaultAPIMockRecorder) Close() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockVaultAPI)(nil).Close))
+}
+
+// ReadKV mocks base method.
+func (m *MockVaultAPI) ReadKV(mount, path string) (map[string]any, int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadKV", mount, path)
+	ret0, _ := ret[0].(map[string]any)
+	ret1, _ := ret[1].(int)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ReadKV indicates an expected call of ReadKV.
+func (mr *MockVaultAPIMockRecorder) ReadKV(mount, path any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadKV", reflect.TypeOf((*MockVaultAPI)(nil).ReadKV), mount, path)
+}
+
+// ReadMetadata mocks base method.
+func (m *MockVaultAPI) ReadMetadata(mount, path string) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadMetadata", mount, path)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadMetadata indicates an expected call of ReadMetadata.
+func (mr *MockVaultAPIMockRecorder) ReadMetadata(mount, path any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadMetadata", reflect.TypeOf((*MockVaultAPI)(nil).ReadMetadata), mount, path)
+}
+
+// WriteKV mocks base method.
+func (m *MockVaultAPI) WriteKV(mount, path string, data map[string]any, cas int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WriteKV", mount, path, data, cas)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WriteKV indicates an expected call of WriteKV.
+func (mr *MockVaultAPIMockRecorder) WriteKV(mount, path, data, cas any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteKV", reflect.TypeOf((*MockVaultAPI)(nil).WriteKV), mount, path, data, cas)
+}